@@ -12,6 +12,10 @@ class ClaudeSessions < Formula
   def install
     system "go", "build", *std_go_args(ldflags: "-s -w"), "-o", bin / "claude-sessions", "./cmd/sessions"
     bin.install_symlink "claude-sessions" => "opencode-sessions"
+    bin.install_symlink "claude-sessions" => "codex-sessions"
+    bin.install_symlink "claude-sessions" => "gemini-sessions"
+    bin.install_symlink "claude-sessions" => "aider-sessions"
+    bin.install_symlink "claude-sessions" => "all-sessions"
   end
 
   def caveats
@@ -19,11 +23,17 @@ class ClaudeSessions < Formula
       This package provides session browsers for AI coding assistants:
         claude-sessions   - Browse Claude Code sessions (~/.claude/projects/)
         opencode-sessions - Browse OpenCode sessions (~/.local/share/opencode/storage/)
+        codex-sessions    - Browse Codex CLI sessions (~/.codex/sessions/)
+        gemini-sessions   - Browse Gemini CLI chats (~/.gemini/tmp/)
+        aider-sessions    - Browse aider chat histories (set AIDER_ROOTS to your code dirs)
+        all-sessions      - Browse all of the above together
 
       Usage:
         claude-sessions          # Launch Claude TUI browser
         opencode-sessions        # Launch OpenCode TUI browser
         <cmd> rebuild            # Manually rebuild the session cache
+        claude-sessions --provider gemini   # Pick a provider without a symlink
+        claude-sessions providers           # Show which providers have data
 
       Keyboard shortcuts in the TUI:
         Enter   - Resume selected session
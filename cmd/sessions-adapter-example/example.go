@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+// Adapter reads example sessions. It implements the core interface plus
+// FirstMessageGetter, so the plugin advertises one optional capability.
+type Adapter struct {
+	dataDir string
+}
+
+// New creates an adapter for the given directory, defaulting to
+// $EXAMPLE_SESSIONS_DIR or ~/.example-agent/sessions
+func New(dataDir string) *Adapter {
+	if dataDir == "" {
+		dataDir = os.Getenv("EXAMPLE_SESSIONS_DIR")
+	}
+	if dataDir == "" {
+		home, _ := os.UserHomeDir()
+		dataDir = filepath.Join(home, ".example-agent", "sessions")
+	}
+	return &Adapter{dataDir: dataDir}
+}
+
+func (a *Adapter) Name() string {
+	return "example"
+}
+
+func (a *Adapter) DataDir() string {
+	return a.dataDir
+}
+
+func (a *Adapter) CacheDir() string {
+	return filepath.Join(filepath.Dir(a.dataDir), ".cache")
+}
+
+func (a *Adapter) ResumeCmd(id string) string {
+	return "example-agent --resume " + id
+}
+
+// ListSessions returns all session IDs sorted by modification time (newest first)
+func (a *Adapter) ListSessions(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(a.dataDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	type sessionFile struct {
+		id    string
+		mtime time.Time
+	}
+	var sessions []sessionFile
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".jsonl") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, sessionFile{
+			id:    strings.TrimSuffix(e.Name(), ".jsonl"),
+			mtime: info.ModTime(),
+		})
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].mtime.After(sessions[j].mtime)
+	})
+
+	ids := make([]string, len(sessions))
+	for i, s := range sessions {
+		ids[i] = s.id
+	}
+	return ids, nil
+}
+
+func (a *Adapter) GetSessionFile(id string) string {
+	path := filepath.Join(a.dataDir, id+".jsonl")
+	if filepath.Base(path) != id+".jsonl" {
+		return "" // Reject IDs that would escape the data dir
+	}
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// errNoMessages indicates a session file without any conversation
+var errNoMessages = fmt.Errorf("session has no messages")
+
+func (a *Adapter) ExtractMeta(ctx context.Context, id string) (*adapters.SessionMeta, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s, err := a.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.messages) == 0 {
+		return nil, errNoMessages
+	}
+
+	summary := s.firstPrompt()
+	if len(summary) > 80 {
+		summary = summary[:77] + "..."
+	}
+	return &adapters.SessionMeta{
+		ID:      id,
+		Date:    s.date,
+		Project: s.project(),
+		Summary: summary,
+	}, nil
+}
+
+func (a *Adapter) GetSessionInfo(id string) (*adapters.SessionInfo, error) {
+	s, err := a.load(id)
+	if err != nil {
+		return nil, err
+	}
+	return &adapters.SessionInfo{
+		ID:      id,
+		Project: s.project(),
+		Date:    s.date,
+		WorkDir: s.cwd,
+	}, nil
+}
+
+func (a *Adapter) ExportMessages(ctx context.Context, id string) ([]adapters.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s, err := a.load(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.messages, nil
+}
+
+// GetFirstMessage returns the first user message
+func (a *Adapter) GetFirstMessage(id string) (string, error) {
+	s, err := a.load(id)
+	if err != nil {
+		return "", err
+	}
+	first := s.firstPrompt()
+	if len(first) > 200 {
+		first = first[:197] + "..."
+	}
+	return first, nil
+}
+
+// record is one line of a session file
+type record struct {
+	Cwd     string    `json:"cwd,omitempty"`
+	Role    string    `json:"role,omitempty"`
+	Content string    `json:"content,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// session is a parsed session file
+type session struct {
+	cwd      string
+	date     time.Time // Time of the last message
+	messages []adapters.Message
+}
+
+func (s *session) project() string {
+	if s.cwd == "" {
+		return "unknown"
+	}
+	return filepath.Base(s.cwd)
+}
+
+func (s *session) firstPrompt() string {
+	for _, m := range s.messages {
+		if m.Role == "user" {
+			return m.Content
+		}
+	}
+	return ""
+}
+
+func (a *Adapter) load(id string) (*session, error) {
+	path := a.GetSessionFile(id)
+	if path == "" {
+		return nil, fmt.Errorf("session %s: %w", id, os.ErrNotExist)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := &session{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024) // 10MB max line
+	for scanner.Scan() {
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue // Skip malformed lines
+		}
+		if r.Cwd != "" {
+			s.cwd = r.Cwd
+		}
+		if r.Role != "user" && r.Role != "assistant" {
+			continue
+		}
+		var ts int64
+		if !r.Time.IsZero() {
+			ts = r.Time.Unix()
+			s.date = r.Time
+		}
+		s.messages = append(s.messages, adapters.Message{
+			Role:      r.Role,
+			Content:   r.Content,
+			Timestamp: ts,
+		})
+	}
+	return s, scanner.Err()
+}
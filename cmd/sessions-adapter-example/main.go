@@ -0,0 +1,25 @@
+// Command sessions-adapter-example is the reference session plugin.
+//
+// It serves sessions stored as JSON Lines files, one file per session:
+//
+//	{"cwd":"/home/me/project"}
+//	{"role":"user","content":"Add a test","time":"2025-06-01T10:00:00Z"}
+//	{"role":"assistant","content":"Done.","time":"2025-06-01T10:00:05Z"}
+//
+// Install it on PATH and select it with `sessions --provider example`.
+// Sessions are read from $EXAMPLE_SESSIONS_DIR, or ~/.example-agent/sessions.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters/plugin"
+)
+
+func main() {
+	if err := plugin.Serve(New(""), os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "sessions-adapter-example: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -10,66 +12,188 @@ import (
 	"time"
 
 	"github.com/Julian194/claude-sessions-tui/internal/adapters"
-	"github.com/Julian194/claude-sessions-tui/internal/adapters/claude"
-	"github.com/Julian194/claude-sessions-tui/internal/adapters/opencode"
+	_ "github.com/Julian194/claude-sessions-tui/internal/adapters/aider"
+	_ "github.com/Julian194/claude-sessions-tui/internal/adapters/claude"
+	_ "github.com/Julian194/claude-sessions-tui/internal/adapters/codex"
+	_ "github.com/Julian194/claude-sessions-tui/internal/adapters/gemini"
+	"github.com/Julian194/claude-sessions-tui/internal/adapters/multi"
+	_ "github.com/Julian194/claude-sessions-tui/internal/adapters/opencode"
+	"github.com/Julian194/claude-sessions-tui/internal/adapters/plugin"
 	"github.com/Julian194/claude-sessions-tui/internal/cache"
+	"github.com/Julian194/claude-sessions-tui/internal/diff"
 	"github.com/Julian194/claude-sessions-tui/internal/export"
 	"github.com/Julian194/claude-sessions-tui/internal/heatmap"
+	"github.com/Julian194/claude-sessions-tui/internal/pricing"
+	"github.com/Julian194/claude-sessions-tui/internal/search"
 	"github.com/Julian194/claude-sessions-tui/internal/stats"
+	"github.com/Julian194/claude-sessions-tui/internal/trash"
 	"github.com/Julian194/claude-sessions-tui/internal/tui"
+	"golang.org/x/term"
 )
 
 func main() {
-	// Detect adapter from binary name
 	binaryName := filepath.Base(os.Args[0])
-	adapter := getAdapter(binaryName)
+
+	flagProvider, args, err := parseGlobalFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// External sessions-adapter-<name> plugins join the built-in providers
+	plugin.Register(os.Getenv("PATH"))
+
+	// Prices from the user's pricing file override the built-in ones
+	prices, err := pricing.Load(pricing.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v (using built-in prices)\n", err)
+	}
+	pricing.Use(prices)
+
+	// Select adapter from flag, environment or binary name
+	provider, err := adapters.Select(flagProvider, os.Getenv("SESSIONS_PROVIDER"), binaryName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	adapter := provider.New()
+	ctx := context.Background()
 
 	// Get cache directory
 	cacheDir := getCacheDir(adapter)
 
 	// Route subcommand
 	cmd := ""
-	args := os.Args[1:]
 	if len(args) > 0 {
 		cmd = args[0]
 		args = args[1:]
 	}
 
-	var err error
 	switch cmd {
 	case "", "tui":
-		err = runTUI(adapter, cacheDir)
+		err = runTUI(ctx, adapter, cacheDir)
 	case "rebuild":
 		mainOnly := len(args) > 0 && args[0] == "--main-only"
-		err = runRebuild(adapter, cacheDir, mainOnly)
+		err = runRebuild(ctx, adapter, cacheDir, mainOnly)
 	case "preview":
 		if len(args) < 1 {
 			fmt.Fprintln(os.Stderr, "Usage: sessions preview <session-id>")
 			os.Exit(1)
 		}
-		err = runPreview(adapter, args[0])
+		err = runPreview(ctx, adapter, args[0])
 	case "stats":
 		if len(args) < 1 {
 			fmt.Fprintln(os.Stderr, "Usage: sessions stats <session-id>")
 			os.Exit(1)
 		}
-		err = runStats(adapter, args[0])
+		err = runStats(ctx, adapter, args[0])
 	case "export":
-		if len(args) < 1 {
-			fmt.Fprintln(os.Stderr, "Usage: sessions export <session-id>")
+		opts, rest := exportOptions(args)
+		if len(rest) < 1 {
+			fmt.Fprintln(os.Stderr, "Usage: sessions export [--thinking] <session-id>")
 			os.Exit(1)
 		}
-		err = runExport(adapter, args[0])
+		err = runExport(ctx, adapter, rest[0], opts)
 	case "copy-md":
-		if len(args) < 1 {
-			fmt.Fprintln(os.Stderr, "Usage: sessions copy-md <session-id>")
+		opts, rest := exportOptions(args)
+		if len(rest) < 1 {
+			fmt.Fprintln(os.Stderr, "Usage: sessions copy-md [--thinking] <session-id>")
 			os.Exit(1)
 		}
-		err = runCopyMD(adapter, args[0])
+		err = runCopyMD(ctx, adapter, rest[0], opts)
 	case "activity":
-		err = runActivity(adapter, cacheDir)
+		err = runActivity(ctx, adapter, cacheDir)
 	case "activity-preview":
-		err = runActivityPreview(adapter, cacheDir)
+		err = runActivityPreview(ctx, adapter, cacheDir)
+	case "providers":
+		err = runProviders(adapter)
+	case "pricing":
+		err = runPricing()
+	case "delete", "archive":
+		confirm := len(args) > 0 && args[0] == "--confirm"
+		if confirm {
+			args = args[1:]
+		}
+		if len(args) < 1 {
+			fmt.Fprintf(os.Stderr, "Usage: sessions %s [--confirm] <session-id>\n", cmd)
+			os.Exit(1)
+		}
+		kind := trash.KindTrash
+		if cmd == "archive" {
+			kind = trash.KindArchive
+		}
+		err = runRemove(adapter, cacheDir, kind, args[0], confirm)
+	case "restore":
+		if len(args) < 1 {
+			fmt.Fprintln(os.Stderr, "Usage: sessions restore <session-id>")
+			os.Exit(1)
+		}
+		err = runRestore(cacheDir, args[0])
+	case "trash":
+		err = runTrash(cacheDir)
+	case "list":
+		opts, rest := listOptions(args)
+		if len(rest) > 0 {
+			fmt.Fprintln(os.Stderr, "Usage: sessions list [--sort <key>] [--limit <n>]")
+			os.Exit(1)
+		}
+		err = runList(ctx, adapter, cacheDir, opts)
+	case "branch":
+		at := ""
+		if len(args) >= 3 && args[1] == "--at" {
+			at = args[2]
+		}
+		if len(args) < 1 || (len(args) > 1 && at == "") {
+			fmt.Fprintln(os.Stderr, "Usage: sessions branch <session-id> [--at <message-index-or-id>]")
+			os.Exit(1)
+		}
+		err = runBranch(adapter, args[0], at)
+	case "turns":
+		if len(args) < 1 {
+			fmt.Fprintln(os.Stderr, "Usage: sessions turns <session-id>")
+			os.Exit(1)
+		}
+		err = runTurns(ctx, adapter, args[0])
+	case "diff":
+		opts, rest := diffOptions(args)
+		if len(rest) < 1 {
+			fmt.Fprintln(os.Stderr, "Usage: sessions diff [--file <path>]... [--patch] <session-id>")
+			os.Exit(1)
+		}
+		err = runDiff(ctx, adapter, rest[0], opts)
+	case "file":
+		fzfOutput := len(args) > 0 && args[0] == "--fzf"
+		if fzfOutput {
+			args = args[1:]
+		}
+		if len(args) < 1 {
+			fmt.Fprintln(os.Stderr, "Usage: sessions file <path>")
+			os.Exit(1)
+		}
+		err = runFile(ctx, adapter, cacheDir, args[0], fzfOutput)
+	case "search":
+		opts, rest := searchOptions(args)
+		if len(rest) < 1 {
+			fmt.Fprintln(os.Stderr, "Usage: sessions search [--limit <n>] <query>")
+			os.Exit(1)
+		}
+		err = runSearch(ctx, adapter, cacheDir, strings.Join(rest, " "), opts)
+	case "search-prompt":
+		if len(args) < 1 {
+			os.Exit(1)
+		}
+		err = runSearchPrompt(ctx, adapter, cacheDir, args[0])
+	case "pick-file":
+		if len(args) < 2 {
+			os.Exit(1)
+		}
+		err = runPickFile(ctx, adapter, cacheDir, args[0], args[1])
+	case "rename":
+		if len(args) < 1 {
+			fmt.Fprintln(os.Stderr, "Usage: sessions rename <session-id> [title]")
+			os.Exit(1)
+		}
+		err = runRename(adapter, cacheDir, args[0], strings.Join(args[1:], " "))
 	case "reset-header":
 		if len(args) < 2 {
 			os.Exit(1)
@@ -90,11 +214,35 @@ func main() {
 	}
 }
 
-func getAdapter(binaryName string) adapters.Adapter {
-	if strings.Contains(binaryName, "opencode") {
-		return opencode.New("")
+// parseGlobalFlags strips the flags that precede the subcommand and
+// returns the requested provider, if any
+func parseGlobalFlags(args []string) (string, []string, error) {
+	provider := ""
+	for len(args) > 0 {
+		switch {
+		case args[0] == "--provider":
+			if len(args) < 2 {
+				return "", nil, fmt.Errorf("--provider requires a provider name")
+			}
+			provider, args = args[1], args[2:]
+		case strings.HasPrefix(args[0], "--provider="):
+			provider, args = strings.TrimPrefix(args[0], "--provider="), args[1:]
+		default:
+			return provider, args, nil
+		}
+	}
+	return provider, args, nil
+}
+
+// owner returns the adapter that owns a session and its provider-local ID.
+// In multi-provider mode this unwraps the namespaced ID.
+func owner(adapter adapters.Adapter, sid string) (adapters.Adapter, string) {
+	if m, ok := adapter.(*multi.Adapter); ok {
+		if a, local, err := m.Resolve(sid); err == nil {
+			return a, local
+		}
 	}
-	return claude.New("")
+	return adapter, sid
 }
 
 func getCacheDir(adapter adapters.Adapter) string {
@@ -107,19 +255,25 @@ func getCacheDir(adapter adapters.Adapter) string {
 	return adapter.CacheDir()
 }
 
-func runTUI(adapter adapters.Adapter, cacheDir string) error {
+// selfCommand returns the command line fzf runs subcommands with
+func selfCommand(adapter adapters.Adapter) string {
 	binPath, err := os.Executable()
 	if err != nil {
 		binPath = os.Args[0]
 	}
+	// Pin the provider for the subcommands fzf runs: os.Executable
+	// resolves symlinks, so the binary name alone may not select it
+	return binPath + " --provider " + adapter.Name()
+}
 
+func runTUI(ctx context.Context, adapter adapters.Adapter, cacheDir string) error {
 	cfg := tui.Config{
 		Adapter:  adapter,
 		CacheDir: cacheDir,
-		BinPath:  binPath,
+		BinPath:  selfCommand(adapter),
 	}
 
-	result, err := tui.Run(cfg)
+	result, err := tui.Run(ctx, cfg)
 	if err != nil {
 		return err
 	}
@@ -132,26 +286,162 @@ func runTUI(adapter adapters.Adapter, cacheDir string) error {
 	case tui.ActionResume:
 		return resumeSession(adapter, result.SessionID, result.WorkDir)
 	case tui.ActionBranch:
-		return branchSession(adapter, result.SessionID, result.WorkDir)
+		return branchSession(adapter, result.SessionID, "", result.WorkDir)
+	case tui.ActionBranchAt:
+		at, err := tui.PickTurn(ctx, adapter, result.SessionID)
+		if err != nil || at == "" {
+			return err
+		}
+		return branchSession(adapter, result.SessionID, at, result.WorkDir)
 	}
 
 	return nil
 }
 
-func runRebuild(adapter adapters.Adapter, cacheDir string, mainOnly bool) error {
+func runProviders(active adapters.Adapter) error {
+	for _, p := range adapters.Providers() {
+		marker := " "
+		if p.Name == active.Name() {
+			marker = "*"
+		}
+		// Plugins are listed from what discovery found, without starting them
+		if p.Plugin != "" {
+			fmt.Printf("%s %-10s %-8s %s\n", marker, p.Name, "plugin", p.Plugin)
+			continue
+		}
+		a := p.New()
+		status := "no data"
+		if p.Detected(a) {
+			status = "found"
+		}
+		where := a.DataDir()
+		if m, ok := a.(*multi.Adapter); ok {
+			// Its members' data dirs would start their plugins
+			var names []string
+			for _, member := range m.Members() {
+				names = append(names, member.Name())
+			}
+			where = strings.Join(names, ", ")
+		}
+		fmt.Printf("%s %-10s %-8s %s\n", marker, p.Name, status, where)
+	}
+	return nil
+}
+
+// runPricing prints the price table in effect and where overrides go
+func runPricing() error {
+	fmt.Printf("Pricing file: %s\n\n", pricing.Path())
+	fmt.Printf("%-24s %8s %8s %11s %12s  (USD per 1M tokens)\n", "MODEL", "INPUT", "OUTPUT", "CACHE READ", "CACHE WRITE")
+	table := pricing.Current()
+	for _, model := range table.Models() {
+		p, _ := table.Lookup(model)
+		fmt.Printf("%-24s %8.2f %8.2f %11.3f %12.2f\n", model, p.Input, p.Output, p.CacheRead, p.CacheWrite)
+	}
+	return nil
+}
+
+// runRemove moves a session's files to the trash or archive under the
+// cache dir and drops it from the cache
+func runRemove(adapter adapters.Adapter, cacheDir string, kind trash.Kind, sid string, confirm bool) error {
+	a, local := owner(adapter, sid)
+	files, err := adapters.SessionFiles(a, local)
+	if err != nil {
+		return err
+	}
+
+	verb := "Delete"
+	if kind == trash.KindArchive {
+		verb = "Archive"
+	}
+	if confirm && !ask(fmt.Sprintf("%s session %s (%d files)? [y/N] ", verb, sid, len(files))) {
+		return nil
+	}
+
+	if _, err := trash.Move(cacheDir, kind, a.Name(), sid, files); err != nil {
+		return err
+	}
+	cache.Remove(filepath.Join(cacheDir, "sessions-cache.tsv"), sid)
+	cache.RemoveFromFileIndex(filepath.Join(cacheDir, cache.FileIndexName), sid)
+
+	fmt.Printf("Moved %s to %s (restore with: sessions restore %s)\n", sid, trash.Dir(cacheDir, kind), sid)
+	return nil
+}
+
+// runRestore puts a removed session back and invalidates its cache lines.
+// The files keep their old mtimes, so only a missing entry makes the next
+// build extract the session again.
+func runRestore(cacheDir, sid string) error {
+	e, err := trash.Restore(cacheDir, sid)
+	if err != nil {
+		return err
+	}
+	cache.Remove(filepath.Join(cacheDir, "sessions-cache.tsv"), sid)
+	cache.RemoveFromFileIndex(filepath.Join(cacheDir, cache.FileIndexName), sid)
+	fmt.Printf("Restored %s from %s (%d files)\n", sid, e.Kind, len(e.Files))
+	return nil
+}
+
+func runTrash(cacheDir string) error {
+	entries, err := trash.List(cacheDir)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("Trash and archive are empty")
+		return nil
+	}
+	for _, e := range entries {
+		fmt.Printf("%-8s %s  %-9s %s\n", e.Kind, e.RemovedAt.Local().Format("2006-01-02 15:04"), e.Provider, e.SessionID)
+	}
+	return nil
+}
+
+// runRename sets a session's title and updates its cache line in place.
+// Without a title it asks for one, which is how the TUI calls it.
+func runRename(adapter adapters.Adapter, cacheDir, sid, title string) error {
+	if title == "" {
+		fmt.Print("New title: ")
+		title, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+	}
+	title = strings.Join(strings.Fields(title), " ")
+	if title == "" {
+		return fmt.Errorf("title cannot be empty")
+	}
+
+	a, local := owner(adapter, sid)
+	if err := adapters.RenameSession(a, local, title); err != nil {
+		return err
+	}
+	cache.Update(filepath.Join(cacheDir, "sessions-cache.tsv"), sid, func(e *cache.Entry) {
+		e.Summary = title
+	})
+
+	fmt.Printf("Renamed %s to %q\n", sid, title)
+	return nil
+}
+
+// ask prints a yes/no question and reports whether the answer was yes
+func ask(question string) bool {
+	fmt.Print(question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func runRebuild(ctx context.Context, adapter adapters.Adapter, cacheDir string, mainOnly bool) error {
 	cfg := tui.Config{
 		Adapter:  adapter,
 		CacheDir: cacheDir,
 	}
-	return tui.Rebuild(cfg, mainOnly)
+	return tui.Rebuild(ctx, cfg, mainOnly)
 }
 
-func runPreview(adapter adapters.Adapter, sid string) error {
-	return tui.Preview(adapter, sid)
+func runPreview(ctx context.Context, adapter adapters.Adapter, sid string) error {
+	return tui.Preview(ctx, adapter, sid)
 }
 
-func runStats(adapter adapters.Adapter, sid string) error {
-	s, err := adapter.GetStats(sid)
+func runStats(ctx context.Context, adapter adapters.Adapter, sid string) error {
+	s, err := adapters.GetStats(ctx, adapter, sid)
 	if err != nil {
 		return err
 	}
@@ -159,12 +449,73 @@ func runStats(adapter adapters.Adapter, sid string) error {
 	return nil
 }
 
-func runActivity(adapter adapters.Adapter, cacheDir string) error {
+// listFlags selects what runList prints
+type listFlags struct {
+	sort  string // One of cache.SortKeys
+	limit int    // Most sessions to list, 0 for all
+}
+
+func listOptions(args []string) (listFlags, []string) {
+	opts := listFlags{sort: "date"}
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--sort" && i+1 < len(args):
+			i++
+			opts.sort = args[i]
+		case strings.HasPrefix(args[i], "--sort="):
+			opts.sort = strings.TrimPrefix(args[i], "--sort=")
+		case args[i] == "--limit" && i+1 < len(args):
+			i++
+			fmt.Sscanf(args[i], "%d", &opts.limit)
+		case strings.HasPrefix(args[i], "--limit="):
+			fmt.Sscanf(strings.TrimPrefix(args[i], "--limit="), "%d", &opts.limit)
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return opts, rest
+}
+
+// runList prints the cached sessions with their message, token, cost and
+// file counts, without opening any session file
+func runList(ctx context.Context, adapter adapters.Adapter, cacheDir string, opts listFlags) error {
+	entries, err := cache.Read(filepath.Join(cacheDir, "sessions-cache.tsv"))
+	if err != nil {
+		entries, err = cache.BuildFrom(ctx, adapter)
+		if err != nil {
+			return err
+		}
+	}
+	if err := cache.Sort(entries, opts.sort); err != nil {
+		return err
+	}
+	if opts.limit > 0 && len(entries) > opts.limit {
+		entries = entries[:opts.limit]
+	}
+
+	for _, e := range entries {
+		fmt.Printf("%s  %s  %4d msgs  %11d tokens  $%8.4f  %3d files  %7s  %s  %s\n",
+			e.Date.Local().Format("2006-01-02 15:04"),
+			e.SessionID,
+			e.UserMessages+e.AssistantMessages,
+			e.Tokens(),
+			e.Usage.Cost,
+			e.FilesChanged,
+			e.Duration().Round(time.Minute),
+			e.Project,
+			strings.Join(strings.Fields(e.Summary), " "),
+		)
+	}
+	return nil
+}
+
+func runActivity(ctx context.Context, adapter adapters.Adapter, cacheDir string) error {
 	cacheFile := filepath.Join(cacheDir, "sessions-cache.tsv")
 
 	entries, err := cache.Read(cacheFile)
 	if err != nil {
-		entries, err = cache.BuildFrom(adapter)
+		entries, err = cache.BuildFrom(ctx, adapter)
 		if err != nil {
 			return err
 		}
@@ -174,12 +525,12 @@ func runActivity(adapter adapters.Adapter, cacheDir string) error {
 	return nil
 }
 
-func runActivityPreview(adapter adapters.Adapter, cacheDir string) error {
+func runActivityPreview(ctx context.Context, adapter adapters.Adapter, cacheDir string) error {
 	cacheFile := filepath.Join(cacheDir, "sessions-cache.tsv")
 
 	entries, err := cache.Read(cacheFile)
 	if err != nil {
-		entries, err = cache.BuildFrom(adapter)
+		entries, err = cache.BuildFrom(ctx, adapter)
 		if err != nil {
 			return err
 		}
@@ -190,8 +541,26 @@ func runActivityPreview(adapter adapters.Adapter, cacheDir string) error {
 	return nil
 }
 
-func runExport(adapter adapters.Adapter, sid string) error {
-	messages, err := adapter.ExportMessages(sid)
+// exportOptions reads export flags from args, defaulting to the
+// SESSIONS_EXPORT_THINKING environment variable so the TUI keys follow it
+func exportOptions(args []string) (export.Options, []string) {
+	opts := export.Options{Thinking: os.Getenv("SESSIONS_EXPORT_THINKING") == "1"}
+	var rest []string
+	for _, arg := range args {
+		switch arg {
+		case "--thinking":
+			opts.Thinking = true
+		case "--no-thinking":
+			opts.Thinking = false
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return opts, rest
+}
+
+func runExport(ctx context.Context, adapter adapters.Adapter, sid string, opts export.Options) error {
+	messages, err := adapter.ExportMessages(ctx, sid)
 	if err != nil {
 		return err
 	}
@@ -201,11 +570,12 @@ func runExport(adapter adapters.Adapter, sid string) error {
 		return err
 	}
 
-	models, _ := adapter.GetModels(sid)
-	html := export.ToHTML(messages, info, models)
+	models, _ := adapters.GetModels(adapter, sid)
+	opts.Diffs = diff.Replay(messages)
+	html := export.ToHTML(messages, info, models, opts)
 
 	// Write to /tmp for reliable access
-	shortID := sid
+	_, shortID := owner(adapter, sid)
 	if len(shortID) > 8 {
 		shortID = shortID[:8]
 	}
@@ -251,15 +621,15 @@ func runResetHeader(port, header string) {
 	}
 }
 
-func runCopyMD(adapter adapters.Adapter, sid string) error {
-	messages, err := adapter.ExportMessages(sid)
+func runCopyMD(ctx context.Context, adapter adapters.Adapter, sid string, opts export.Options) error {
+	messages, err := adapter.ExportMessages(ctx, sid)
 	if err != nil {
 		return err
 	}
 
 	info, _ := adapter.GetSessionInfo(sid)
-	models, _ := adapter.GetModels(sid)
-	md := export.ToMarkdown(messages, info, models)
+	models, _ := adapters.GetModels(adapter, sid)
+	md := export.ToMarkdown(messages, info, models, opts)
 
 	var clipboardCmd []string
 	switch {
@@ -294,6 +664,7 @@ func runCopyMD(adapter adapters.Adapter, sid string) error {
 }
 
 func resumeSession(adapter adapters.Adapter, sid string, workDir string) error {
+	adapter, sid = owner(adapter, sid)
 	resumeCmd := adapter.ResumeCmd(sid)
 	parts := strings.Fields(resumeCmd)
 
@@ -315,8 +686,10 @@ func resumeSession(adapter adapters.Adapter, sid string, workDir string) error {
 	return cmd.Run()
 }
 
-func branchSession(adapter adapters.Adapter, sid string, workDir string) error {
-	newSID, err := adapter.BranchSession(sid)
+// branchSession branches a session, at the given message if at is set,
+// and resumes the branch
+func branchSession(adapter adapters.Adapter, sid, at, workDir string) error {
+	newSID, err := newBranch(adapter, sid, at)
 	if err != nil {
 		return fmt.Errorf("branch failed: %w", err)
 	}
@@ -325,6 +698,225 @@ func branchSession(adapter adapters.Adapter, sid string, workDir string) error {
 	return resumeSession(adapter, newSID, workDir)
 }
 
+func newBranch(adapter adapters.Adapter, sid, at string) (string, error) {
+	if at == "" {
+		return adapters.BranchSession(adapter, sid)
+	}
+	return adapters.BranchSessionAt(adapter, sid, at)
+}
+
+// runBranch branches a session without resuming it
+func runBranch(adapter adapters.Adapter, sid, at string) error {
+	newSID, err := newBranch(adapter, sid, at)
+	if err != nil {
+		return err
+	}
+	fmt.Println(newSID)
+	return nil
+}
+
+// runTurns lists a session's messages with the indexes branch --at takes
+func runTurns(ctx context.Context, adapter adapters.Adapter, sid string) error {
+	messages, err := adapter.ExportMessages(ctx, sid)
+	if err != nil {
+		return err
+	}
+	for _, line := range tui.FormatTurns(messages) {
+		fmt.Println(strings.SplitN(line, "\t", 2)[1])
+	}
+	return nil
+}
+
+// runFile lists the sessions that touched a file, or any file under a
+// directory. With fzfOutput it prints them as TUI list lines instead.
+func runFile(ctx context.Context, adapter adapters.Adapter, cacheDir, path string, fzfOutput bool) error {
+	cfg := tui.Config{
+		Adapter:  adapter,
+		CacheDir: cacheDir,
+	}
+	if fzfOutput {
+		return tui.FileSessions(cfg, path)
+	}
+
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	entries, err := cache.Read(filepath.Join(cacheDir, "sessions-cache.tsv"))
+	if err != nil {
+		entries, err = cache.BuildFrom(ctx, adapter)
+		if err != nil {
+			return err
+		}
+	}
+	refs, err := cache.UpdateFileIndex(ctx, adapter, filepath.Join(cacheDir, cache.FileIndexName), entries)
+	if err != nil {
+		return err
+	}
+
+	found := cache.FindFile(refs, path)
+	if len(found) == 0 {
+		fmt.Fprintf(os.Stderr, "No sessions touched %s\n", path)
+		return nil
+	}
+	summaries := make(map[string]string, len(entries))
+	for _, e := range entries {
+		summaries[e.SessionID] = strings.Join(strings.Fields(e.Summary), " ")
+	}
+	for _, r := range found {
+		what := adapters.FormatOps(r.Ops)
+		if r.Path != path {
+			what = fmt.Sprintf("%s (%s)", tui.RelPath(r.Path, path), what)
+		}
+		fmt.Printf("%s  %s  %s  %s\n", r.Date.Local().Format("2006-01-02 15:04"), r.SessionID, what, summaries[r.SessionID])
+	}
+	return nil
+}
+
+// searchFlags selects what runSearch prints
+type searchFlags struct {
+	limit int  // Most sessions to list
+	fzf   bool // TUI list lines instead of results with snippets
+}
+
+func searchOptions(args []string) (searchFlags, []string) {
+	opts := searchFlags{limit: 20}
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--fzf":
+			opts.fzf = true
+		case args[i] == "--limit" && i+1 < len(args):
+			i++
+			fmt.Sscanf(args[i], "%d", &opts.limit)
+		case strings.HasPrefix(args[i], "--limit="):
+			fmt.Sscanf(strings.TrimPrefix(args[i], "--limit="), "%d", &opts.limit)
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return opts, rest
+}
+
+// runSearch lists the sessions whose messages, tool inputs or tool outputs
+// match query, best first, each with the text that matched
+func runSearch(ctx context.Context, adapter adapters.Adapter, cacheDir, query string, opts searchFlags) error {
+	cfg := tui.Config{
+		Adapter:  adapter,
+		CacheDir: cacheDir,
+	}
+	if opts.fzf {
+		return tui.SearchSessions(ctx, cfg, query, opts.limit)
+	}
+
+	q := search.ParseQuery(query)
+	if q.Empty() {
+		return fmt.Errorf("query has no searchable words")
+	}
+	matches, err := tui.Search(ctx, cfg, q, opts.limit)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		fmt.Fprintf(os.Stderr, "No sessions match %q\n", query)
+		return nil
+	}
+
+	on, off := "", ""
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		on, off = "\033[1;33m", "\033[0m"
+	}
+	for _, m := range matches {
+		e := m.Entry
+		fmt.Printf("%s  %s  %s  %s\n", e.Date.Local().Format("2006-01-02 15:04"), e.SessionID, e.Project, strings.Join(strings.Fields(e.Summary), " "))
+		if m.Found {
+			fmt.Printf("    [%d] %s: %s\n", m.Snippet.Turn, m.Snippet.Role, search.Highlight(m.Snippet.Text, q, on, off))
+		}
+	}
+	return nil
+}
+
+// runSearchPrompt is run by the TUI's alt-s binding
+func runSearchPrompt(ctx context.Context, adapter adapters.Adapter, cacheDir, port string) error {
+	cfg := tui.Config{
+		Adapter:  adapter,
+		CacheDir: cacheDir,
+		BinPath:  selfCommand(adapter),
+	}
+	return tui.SearchPrompt(ctx, cfg, port)
+}
+
+// runPickFile is run by the TUI's alt-f binding
+func runPickFile(ctx context.Context, adapter adapters.Adapter, cacheDir, port, sid string) error {
+	cfg := tui.Config{
+		Adapter:  adapter,
+		CacheDir: cacheDir,
+		BinPath:  selfCommand(adapter),
+	}
+	return tui.PickFile(ctx, cfg, port, sid)
+}
+
+// diffFlags selects what runDiff prints
+type diffFlags struct {
+	files []string // Only these files, matched by path or by suffix
+	patch bool     // Plain patch for patch -p1 instead of annotated diffs
+}
+
+func diffOptions(args []string) (diffFlags, []string) {
+	var opts diffFlags
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--patch":
+			opts.patch = true
+		case args[i] == "--file" && i+1 < len(args):
+			i++
+			opts.files = append(opts.files, args[i])
+		case strings.HasPrefix(args[i], "--file="):
+			opts.files = append(opts.files, strings.TrimPrefix(args[i], "--file="))
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return opts, rest
+}
+
+// runDiff prints the changes a session made, rebuilt from its edit and
+// write calls
+func runDiff(ctx context.Context, adapter adapters.Adapter, sid string, opts diffFlags) error {
+	messages, err := adapter.ExportMessages(ctx, sid)
+	if err != nil {
+		return err
+	}
+	workDir := ""
+	if info, err := adapter.GetSessionInfo(sid); err == nil {
+		workDir = info.WorkDir
+	}
+
+	files := diff.Replay(messages)
+	if len(opts.files) > 0 {
+		files = diff.Filter(files, opts.files, workDir)
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "No edits found")
+		return nil
+	}
+
+	for _, f := range files {
+		name := diff.Name(f.Path, workDir)
+		if opts.patch {
+			if f.Rewritten {
+				fmt.Fprintf(os.Stderr, "Skipping %s: overwritten without its previous content\n", name)
+				continue
+			}
+			fmt.Print(diff.Unified(f, name))
+			continue
+		}
+		fmt.Printf("━━━ %s ━━━\n%s\n", name, diff.Summary(f))
+		fmt.Println(diff.Unified(f, name))
+	}
+	return nil
+}
+
 func printUsage(binaryName string, adapter adapters.Adapter) {
 	fmt.Printf(`%s - browse and export AI coding sessions (v2.0.0)
 
@@ -332,15 +924,30 @@ Provider: %s
 Data:     %s
 Cache:    %s
 
-Usage: %s [command] [arguments]
+Usage: %s [--provider <name>] [command] [arguments]
 
 Commands:
   (default)     Launch interactive TUI
   rebuild       Rebuild the session cache
   preview <id>  Show preview for a session
   stats <id>    Show statistics for a session
-  export <id>   Export session to HTML
-  copy-md <id>  Copy session as markdown to clipboard
+  export <id>   Export session to HTML (--thinking to include model reasoning)
+  copy-md <id>  Copy session as markdown to clipboard (--thinking as well)
+  providers     List providers, their data dirs and whether data was found
+  pricing       Show the per-model prices used to estimate costs
+  delete <id>   Move a session to the trash (--confirm to ask first)
+  archive <id>  Move a session to the archive (--confirm to ask first)
+  restore <id>  Restore a deleted or archived session
+  trash         List deleted and archived sessions
+  list          List sessions with message, token, cost and file counts
+                (--sort date|cost|tokens|messages|files|duration, --limit <n>)
+  rename <id>   Set a custom title for a session
+  branch <id>   Branch a session (--at <n|message-id> to cut it at a message)
+  turns <id>    List a session's messages with the indexes --at takes
+  diff <id>     Show file changes rebuilt from the session's edits
+                (--file <path> to pick files, --patch for patch -p1)
+  file <path>   List the sessions that read or changed a file or directory
+  search <q>    Search all transcripts, best matches first (--limit <n>)
   help          Show this help message
 
 Keyboard shortcuts in TUI:
@@ -348,11 +955,41 @@ Keyboard shortcuts in TUI:
   Ctrl-O    Export session to HTML
   Ctrl-Y    Copy session as markdown
   Ctrl-B    Branch session
+  Alt-B     Branch session at a chosen message
+  Ctrl-X    Delete session (asks first)
+  Alt-X     Archive session (asks first)
+  Alt-R     Rename session
+  Alt-F     Show only sessions that touched a chosen file
+  Alt-S     Show only sessions matching a full-text search
   Ctrl-R    Refresh cache
 
+Provider selection (first match wins):
+  --provider <name>    Explicit flag
+  SESSIONS_PROVIDER    Environment variable
+  binary name          e.g. opencode-sessions, all-sessions
+  default              %s
+
+Providers: %s
+Plugins:   any sessions-adapter-<name> executable on PATH
+
 Environment:
+  SESSIONS_PROVIDER    Select the provider
   SESSIONS_CACHE_DIR   Override cache directory
   CLAUDE_DIR           Override Claude data directory
+  CODEX_HOME           Override Codex home directory
+  GEMINI_DIR           Override Gemini data directory
+  AIDER_ROOTS          Directories searched for aider histories (default: home)
+  SESSIONS_PRICING_FILE Override the pricing file (see: sessions pricing)
+  SESSIONS_EXPORT_THINKING=1  Include model reasoning in exports by default
+
+`, binaryName, adapter.Name(), adapter.DataDir(), adapter.CacheDir(), binaryName,
+		adapters.DefaultProvider, strings.Join(providerNames(), ", "))
+}
 
-`, binaryName, adapter.Name(), adapter.DataDir(), adapter.CacheDir(), binaryName)
+func providerNames() []string {
+	var names []string
+	for _, p := range adapters.Providers() {
+		names = append(names, p.Name)
+	}
+	return names
 }
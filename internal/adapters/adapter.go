@@ -1,8 +1,25 @@
 package adapters
 
-import "time"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
 
-// Adapter defines the interface for session providers
+// Adapter is the core interface every session provider implements.
+// Everything beyond listing, metadata and export is optional: providers
+// implement the capability interfaces below, and callers detect them with
+// type assertions or go through the helper functions in optional.go.
+// Adapters that wrap others implement every action interface, so whether
+// an action is available is asked through Supports.
+//
+// Methods that read session files take a context so long scans can be
+// cancelled; implementations check it between files and records.
 type Adapter interface {
 	// Metadata
 	Name() string
@@ -11,65 +28,454 @@ type Adapter interface {
 	ResumeCmd(id string) string
 
 	// Session listing
-	ListSessions() ([]string, error)
+	ListSessions(ctx context.Context) ([]string, error)
 	GetSessionFile(id string) string
 
 	// Metadata extraction
-	ExtractMeta(id string) (*SessionMeta, error)
+	ExtractMeta(ctx context.Context, id string) (*SessionMeta, error)
 	GetSessionInfo(id string) (*SessionInfo, error)
 
-	// Content extraction
+	// Export
+	ExportMessages(ctx context.Context, id string) ([]Message, error)
+}
+
+// Brancher is implemented by providers that can copy a session so it can
+// be resumed independently of the original
+type Brancher interface {
+	BranchSession(id string) (string, error) // Returns new session ID
+}
+
+// PointBrancher is implemented by providers that can branch a session at a
+// chosen message, keeping the conversation up to and including it
+type PointBrancher interface {
+	// BranchSessionAt takes a message ID or a 0-based index into
+	// ExportMessages and returns the new session ID
+	BranchSessionAt(id, at string) (string, error)
+}
+
+// Deleter is implemented by providers whose sessions are plain files that
+// can be removed or moved aside
+type Deleter interface {
+	// SessionFiles returns every file and directory that belongs to the session
+	SessionFiles(id string) ([]string, error)
+}
+
+// Renamer is implemented by providers that can store a custom session title
+type Renamer interface {
+	RenameSession(id, title string) error
+}
+
+// Capability names one of the optional actions above
+type Capability string
+
+const (
+	CanBranch   Capability = "branch"    // Brancher
+	CanBranchAt Capability = "branch-at" // PointBrancher
+	CanDelete   Capability = "delete"    // Deleter
+	CanRename   Capability = "rename"    // Renamer
+)
+
+// CapabilityChecker is implemented by adapters whose support for an
+// action isn't told by the interfaces they implement: the multi adapter
+// depends on the provider owning each session, a plugin on what it
+// declared in its handshake. An empty id asks whether any session can.
+type CapabilityChecker interface {
+	Supports(id string, c Capability) bool
+}
+
+// Streamer is implemented by providers that can emit messages one at a
+// time without materializing the whole session. Returning an error from
+// fn stops the stream and is passed back to the caller.
+type Streamer interface {
+	StreamMessages(ctx context.Context, id string, fn func(Message) error) error
+}
+
+// SummariesGetter is implemented by providers that record topic summaries
+type SummariesGetter interface {
 	GetSummaries(id string) ([]string, error)
+}
+
+// FilesTouchedGetter is implemented by providers that can tell which files a
+// session modified
+type FilesTouchedGetter interface {
 	GetFilesTouched(id string) ([]string, error)
+}
+
+// FileActivityGetter is implemented by providers that can tell which files
+// a session read or changed, and how
+type FileActivityGetter interface {
+	GetFileActivity(ctx context.Context, id string) ([]FileActivity, error)
+}
+
+// DetailsGetter is implemented by providers that can gather a session's
+// details in one pass instead of one lookup each
+type DetailsGetter interface {
+	GetDetails(ctx context.Context, id string) (*SessionDetails, error)
+}
+
+// SlashCommandsGetter is implemented by providers that record slash commands
+type SlashCommandsGetter interface {
 	GetSlashCommands(id string) ([]string, error)
+}
+
+// ModelsGetter is implemented by providers that record which models answered
+type ModelsGetter interface {
 	GetModels(id string) ([]string, error)
-	GetStats(id string) (*Stats, error)
-	GetFirstMessage(id string) (string, error)
+}
 
-	// Export
-	ExportMessages(id string) ([]Message, error)
+// StatsGetter is implemented by providers that track token usage and cost
+type StatsGetter interface {
+	GetStats(ctx context.Context, id string) (*Stats, error)
+}
 
-	// Session operations
-	BranchSession(id string) (string, error) // Returns new session ID
+// ForksGetter is implemented by providers whose session files can hold
+// several conversation paths, e.g. after rewinding to an earlier message
+type ForksGetter interface {
+	GetForks(id string) ([]Fork, error)
+}
+
+// FirstMessageGetter is implemented by providers that can return the first
+// prompt without a full export
+type FirstMessageGetter interface {
+	GetFirstMessage(id string) (string, error)
 }
 
 // SessionMeta contains basic session metadata for cache building
 type SessionMeta struct {
-	ID        string
-	Date      time.Time
-	Project   string
-	Summary   string
-	ParentSID string // Parent session ID for branches
+	ID        string    `json:"id"`
+	Date      time.Time `json:"date"`
+	Project   string    `json:"project"`
+	Summary   string    `json:"summary"`
+	ParentSID string    `json:"parent_sid,omitempty"` // Parent session ID for branches
 }
 
 // SessionInfo contains detailed session information for preview
 type SessionInfo struct {
-	ID      string
-	Project string
-	Date    time.Time
-	Branch  string
-	WorkDir string
+	ID      string    `json:"id"`
+	Project string    `json:"project"`
+	Date    time.Time `json:"date"`
+	Branch  string    `json:"branch,omitempty"`
+	WorkDir string    `json:"work_dir,omitempty"`
+}
+
+// SessionDetails are the figures the session cache keeps for each
+// session, so listings and reports don't have to parse session files
+type SessionDetails struct {
+	WorkDir           string    `json:"work_dir,omitempty"`
+	Branch            string    `json:"branch,omitempty"`
+	Models            []string  `json:"models,omitempty"`
+	UserMessages      int       `json:"user_messages"`
+	AssistantMessages int       `json:"assistant_messages"`
+	Usage             Totals    `json:"usage"`         // The session itself, without subagents
+	Start             time.Time `json:"start"`         // First message
+	End               time.Time `json:"end"`           // Last message
+	FilesRead         int       `json:"files_read"`    // Files only read
+	FilesChanged      int       `json:"files_changed"` // Files created, modified or deleted
+}
+
+// SetStats copies message counts and usage from s
+func (d *SessionDetails) SetStats(s *Stats) {
+	d.UserMessages = s.UserMessages
+	d.AssistantMessages = s.AssistantMessages
+	d.Usage = s.Self()
 }
 
-// Stats contains session statistics
+// CountFiles sets the file counts from a session's file activity
+func (d *SessionDetails) CountFiles(activity []FileActivity) {
+	d.FilesRead, d.FilesChanged = 0, 0
+	for _, f := range activity {
+		if f.Changed() {
+			d.FilesChanged++
+		} else {
+			d.FilesRead++
+		}
+	}
+}
+
+// SetSpan sets Start and End from the messages' timestamps
+func (d *SessionDetails) SetSpan(messages []Message) {
+	d.Start, d.End = time.Time{}, time.Time{}
+	for _, m := range messages {
+		if m.Timestamp <= 0 {
+			continue
+		}
+		t := time.Unix(m.Timestamp, 0)
+		if d.Start.IsZero() || t.Before(d.Start) {
+			d.Start = t
+		}
+		if t.After(d.End) {
+			d.End = t
+		}
+	}
+}
+
+// Stats contains session statistics. Tokens and cost cover the session
+// itself; Inclusive adds the subagents it spawned, which are broken down
+// in Subagents.
 type Stats struct {
-	UserMessages      int
-	AssistantMessages int
-	InputTokens       int
-	OutputTokens      int
-	CacheRead         int
-	CacheWrite        int
-	Cost              float64
-	ToolCalls         map[string]int
+	UserMessages      int             `json:"user_messages"`
+	AssistantMessages int             `json:"assistant_messages"`
+	InputTokens       int             `json:"input_tokens"`
+	OutputTokens      int             `json:"output_tokens"`
+	CacheRead         int             `json:"cache_read"`
+	CacheWrite        int             `json:"cache_write"`
+	ReasoningTokens   int             `json:"reasoning_tokens,omitempty"` // Part of OutputTokens spent thinking
+	Cost              float64         `json:"cost"`
+	ToolCalls         map[string]int  `json:"tool_calls,omitempty"`
+	Inclusive         Totals          `json:"inclusive"`
+	Subagents         []SubagentStats `json:"subagents,omitempty"`
 }
 
-// Message represents a normalized message for export
+// Totals is the token usage and cost of a session or subagent
+type Totals struct {
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	CacheRead    int     `json:"cache_read"`
+	CacheWrite   int     `json:"cache_write"`
+	Cost         float64 `json:"cost"`
+}
+
+// Add adds o to t
+func (t *Totals) Add(o Totals) {
+	t.InputTokens += o.InputTokens
+	t.OutputTokens += o.OutputTokens
+	t.CacheRead += o.CacheRead
+	t.CacheWrite += o.CacheWrite
+	t.Cost += o.Cost
+}
+
+// SubagentStats is the usage of one subagent session
+type SubagentStats struct {
+	ID          string `json:"id"`                    // Session ID of the subagent
+	Description string `json:"description,omitempty"` // Its task, if known
+	Messages    int    `json:"messages"`
+	Totals
+}
+
+// Self returns the session's own usage, without subagents
+func (s *Stats) Self() Totals {
+	return Totals{
+		InputTokens:  s.InputTokens,
+		OutputTokens: s.OutputTokens,
+		CacheRead:    s.CacheRead,
+		CacheWrite:   s.CacheWrite,
+		Cost:         s.Cost,
+	}
+}
+
+// Rollup records the session's subagents, most expensive first, and sets
+// the inclusive totals
+func (s *Stats) Rollup(subagents []SubagentStats) {
+	sort.SliceStable(subagents, func(i, j int) bool {
+		return subagents[i].Cost > subagents[j].Cost
+	})
+	s.Subagents = subagents
+	s.Inclusive = s.Self()
+	for _, sub := range subagents {
+		s.Inclusive.Add(sub.Totals)
+	}
+}
+
+// Fork is one conversation path inside a session file. The active fork is
+// the one ExportMessages returns; branching at LeafID turns any other fork
+// into a session of its own.
+type Fork struct {
+	LeafID    string `json:"leaf_id"` // Last message on the path
+	Active    bool   `json:"active"`
+	Messages  int    `json:"messages"`        // Messages from the start of the session
+	Shared    int    `json:"shared"`          // Leading messages shared with the active fork
+	First     string `json:"first,omitempty"` // Start of the first message after the shared ones
+	Timestamp int64  `json:"timestamp"`       // Time of the last message
+}
+
+// FileOp is what a session did to a file
+type FileOp string
+
+const (
+	FileRead   FileOp = "read"
+	FileCreate FileOp = "create"
+	FileModify FileOp = "modify"
+	FileDelete FileOp = "delete"
+)
+
+// FileOps lists the operations in the order they are displayed
+var FileOps = []FileOp{FileCreate, FileModify, FileDelete, FileRead}
+
+// FileActivity is everything a session did to one file. Turns are 0-based
+// indexes into ExportMessages, or -1 when the provider can't tell.
+type FileActivity struct {
+	Path      string         `json:"path"`
+	Ops       map[FileOp]int `json:"ops"` // Number of tool calls per operation
+	FirstTurn int            `json:"first_turn"`
+	LastTurn  int            `json:"last_turn"`
+}
+
+// Changed reports whether the session created, modified or deleted the file
+func (f FileActivity) Changed() bool {
+	return f.Ops[FileCreate]+f.Ops[FileModify]+f.Ops[FileDelete] > 0
+}
+
+// Op returns the operation the file is grouped under, the first of FileOps
+// the session performed on it
+func (f FileActivity) Op() FileOp {
+	for _, op := range FileOps {
+		if f.Ops[op] > 0 {
+			return op
+		}
+	}
+	return FileRead
+}
+
+// FormatOps lists operation counts in display order, e.g. "modify ×3, read"
+func FormatOps(ops map[FileOp]int) string {
+	var parts []string
+	for _, op := range FileOps {
+		switch n := ops[op]; {
+		case n == 1:
+			parts = append(parts, string(op))
+		case n > 1:
+			parts = append(parts, fmt.Sprintf("%s ×%d", op, n))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ChangedFiles returns the sorted paths the session created, modified or
+// deleted
+func ChangedFiles(activity []FileActivity) []string {
+	var files []string
+	for _, f := range activity {
+		if f.Changed() {
+			files = append(files, f.Path)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// FileRecorder collects file activity while a provider walks a session
+type FileRecorder struct {
+	files map[string]*FileActivity
+}
+
+// Record counts one operation on path at the given turn
+func (r *FileRecorder) Record(path string, op FileOp, turn int) {
+	if path == "" {
+		return
+	}
+	if r.files == nil {
+		r.files = make(map[string]*FileActivity)
+	}
+	f, ok := r.files[path]
+	if !ok {
+		f = &FileActivity{Path: path, Ops: make(map[FileOp]int), FirstTurn: turn, LastTurn: turn}
+		r.files[path] = f
+	}
+	f.Ops[op]++
+	if turn < f.FirstTurn {
+		f.FirstTurn = turn
+	}
+	if turn > f.LastTurn {
+		f.LastTurn = turn
+	}
+}
+
+// Seen reports whether any operation on path was recorded
+func (r *FileRecorder) Seen(path string) bool {
+	_, ok := r.files[path]
+	return ok
+}
+
+// Activity returns the recorded files sorted by path
+func (r *FileRecorder) Activity() []FileActivity {
+	activity := make([]FileActivity, 0, len(r.files))
+	for _, f := range r.files {
+		activity = append(activity, *f)
+	}
+	sort.Slice(activity, func(i, j int) bool {
+		return activity[i].Path < activity[j].Path
+	})
+	return activity
+}
+
+// Message represents a normalized message for export. Content, Thinking,
+// ToolCalls and ToolResults flatten the message; Blocks keeps its parts in
+// the order the provider recorded them, for providers that fill it in.
 type Message struct {
-	Role        string       `json:"role"`
-	Content     string       `json:"content"`
-	Timestamp   int64        `json:"timestamp"`
-	ToolCalls   []ToolCall   `json:"tool_calls,omitempty"`
-	ToolResults []ToolResult `json:"tool_results,omitempty"`
+	ID          string         `json:"id,omitempty"` // Provider message ID, if it has one
+	Role        string         `json:"role"`
+	Content     string         `json:"content"`
+	Thinking    string         `json:"thinking,omitempty"` // Reasoning the model showed before answering
+	Timestamp   int64          `json:"timestamp"`
+	ToolCalls   []ToolCall     `json:"tool_calls,omitempty"`
+	ToolResults []ToolResult   `json:"tool_results,omitempty"`
+	Blocks      []ContentBlock `json:"blocks,omitempty"`
+}
+
+// BlockKind is the type of a content block
+type BlockKind string
+
+const (
+	BlockText       BlockKind = "text"
+	BlockThinking   BlockKind = "thinking"
+	BlockToolUse    BlockKind = "tool_use"
+	BlockToolResult BlockKind = "tool_result"
+	BlockImage      BlockKind = "image"
+)
+
+// ContentBlock is one part of a message. Which fields are set depends on
+// the kind: Text for text, thinking and tool_result; ToolUseID for
+// tool_use and tool_result; Name and Input for tool_use; MediaType and
+// Data or URL for image.
+type ContentBlock struct {
+	Kind      BlockKind `json:"kind"`
+	Text      string    `json:"text,omitempty"`
+	ToolUseID string    `json:"tool_use_id,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Input     string    `json:"input,omitempty"` // JSON
+	IsError   bool      `json:"is_error,omitempty"`
+	MediaType string    `json:"media_type,omitempty"`
+	Data      string    `json:"data,omitempty"` // Base64
+	URL       string    `json:"url,omitempty"`
+}
+
+// CopyMessages returns a deep copy of messages, so adapters can hand out
+// messages they keep memoized without callers changing them
+func CopyMessages(messages []Message) []Message {
+	if messages == nil {
+		return nil
+	}
+	out := make([]Message, len(messages))
+	for i, m := range messages {
+		out[i] = m
+		out[i].ToolCalls = slices.Clone(m.ToolCalls)
+		out[i].ToolResults = slices.Clone(m.ToolResults)
+		out[i].Blocks = slices.Clone(m.Blocks)
+	}
+	return out
+}
+
+// MessageBlocks returns the message's content blocks. For messages without
+// Blocks they are rebuilt from the flat fields: thinking, text, tool calls,
+// then tool results.
+func MessageBlocks(m Message) []ContentBlock {
+	if len(m.Blocks) > 0 {
+		return m.Blocks
+	}
+	var blocks []ContentBlock
+	if m.Thinking != "" {
+		blocks = append(blocks, ContentBlock{Kind: BlockThinking, Text: m.Thinking})
+	}
+	if m.Content != "" {
+		blocks = append(blocks, ContentBlock{Kind: BlockText, Text: m.Content})
+	}
+	for _, tc := range m.ToolCalls {
+		blocks = append(blocks, ContentBlock{Kind: BlockToolUse, ToolUseID: tc.ID, Name: tc.Name, Input: tc.Input})
+	}
+	for _, tr := range m.ToolResults {
+		blocks = append(blocks, ContentBlock{Kind: BlockToolResult, ToolUseID: tr.ToolUseID, Text: tr.Content, IsError: !tr.Success})
+	}
+	return blocks
 }
 
 // ToolCall represents a tool invocation
@@ -85,3 +491,25 @@ type ToolResult struct {
 	Content   string `json:"content"`
 	Success   bool   `json:"success"`
 }
+
+// ErrNotSupported is returned by adapters for operations their provider
+// has no equivalent for
+var ErrNotSupported = errors.New("operation not supported by this provider")
+
+// ErrMessageNotFound is returned when a branch point names no message in
+// the session
+var ErrMessageNotFound = errors.New("message not found in session")
+
+// FindMessage returns the index of the message with the given ID, or of
+// the message at the given 0-based index
+func FindMessage(messages []Message, at string) (int, error) {
+	for i, m := range messages {
+		if m.ID != "" && m.ID == at {
+			return i, nil
+		}
+	}
+	if n, err := strconv.Atoi(at); err == nil && n >= 0 && n < len(messages) {
+		return n, nil
+	}
+	return -1, fmt.Errorf("%w: %s", ErrMessageNotFound, at)
+}
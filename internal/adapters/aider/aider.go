@@ -0,0 +1,744 @@
+package aider
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+const (
+	// ChatHistoryFile is the transcript aider appends to in each repo
+	ChatHistoryFile = ".aider.chat.history.md"
+	// InputHistoryFile holds the timestamped prompts typed into aider
+	InputHistoryFile = ".aider.input.history"
+
+	// maxDepth bounds how deep below a root the discovery walk descends
+	maxDepth = 6
+
+	sessionMarker = "# aider chat started at "
+	timeLayout    = "2006-01-02 15:04:05"
+)
+
+// skipDirs are never searched for history files
+var skipDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"Library":      true,
+	"target":       true,
+	"dist":         true,
+	"build":        true,
+}
+
+// Adapter implements the aider chat-history adapter.
+// Aider has no central store: every repo it ran in gets its own
+// .aider.chat.history.md, and each run appends a new session to it.
+type Adapter struct {
+	roots        []string
+	cacheDir     string
+	sessionPaths map[string]string            // Cache of session ID -> chat history path
+	pathsMu      sync.RWMutex                 // Protects sessionPaths
+	memo         *adapters.Memo[*HistoryFile] // Parsed history files keyed by path
+}
+
+func init() {
+	adapters.Register(adapters.Provider{
+		Name: "aider",
+		New:  func() adapters.Adapter { return New("") },
+		// Searching the whole home directory is too slow for a probe,
+		// so aider only counts as present when roots are configured
+		Detect: func(a adapters.Adapter) bool {
+			if os.Getenv("AIDER_ROOTS") == "" {
+				return false
+			}
+			files, _ := a.(*Adapter).historyFiles(context.Background())
+			return len(files) > 0
+		},
+	})
+}
+
+// New creates a new aider adapter searching the given root directories,
+// separated by the OS path list separator. Without roots it falls back
+// to $AIDER_ROOTS, then the home directory.
+func New(roots string) *Adapter {
+	if roots == "" {
+		roots = os.Getenv("AIDER_ROOTS")
+	}
+	list := filepath.SplitList(roots)
+	if len(list) == 0 {
+		home, _ := os.UserHomeDir()
+		list = []string{home}
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		home, _ := os.UserHomeDir()
+		cacheDir = filepath.Join(home, ".cache")
+	}
+
+	return &Adapter{
+		roots:        list,
+		cacheDir:     filepath.Join(cacheDir, "claude-sessions", "aider"),
+		sessionPaths: make(map[string]string),
+		memo:         adapters.NewMemo[*HistoryFile](adapters.MemoBytes),
+	}
+}
+
+func (a *Adapter) Name() string {
+	return "aider"
+}
+
+// DataDir returns the searched roots as a path list
+func (a *Adapter) DataDir() string {
+	return strings.Join(a.roots, string(os.PathListSeparator))
+}
+
+func (a *Adapter) CacheDir() string {
+	return a.cacheDir
+}
+
+// ResumeCmd reloads the repo's chat history; it must run in the repo
+func (a *Adapter) ResumeCmd(id string) string {
+	return "aider --restore-chat-history"
+}
+
+// ListSessions returns all session IDs sorted by last activity (newest first)
+func (a *Adapter) ListSessions(ctx context.Context) ([]string, error) {
+	files, err := a.historyFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*Session
+	for _, path := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		h, err := a.loadFile(path)
+		if err != nil {
+			continue // Skip unreadable histories
+		}
+		a.pathsMu.Lock()
+		for _, s := range h.Sessions {
+			a.sessionPaths[s.ID] = path
+		}
+		a.pathsMu.Unlock()
+		sessions = append(sessions, h.Sessions...)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Date.After(sessions[j].Date)
+	})
+
+	ids := make([]string, len(sessions))
+	for i, s := range sessions {
+		ids[i] = s.ID
+	}
+	return ids, nil
+}
+
+// historyFiles walks the roots for chat history files
+func (a *Adapter) historyFiles(ctx context.Context) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+
+	for _, root := range a.roots {
+		root = filepath.Clean(root)
+		depth := strings.Count(root, string(os.PathSeparator))
+
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if err != nil {
+				return nil // Skip errors
+			}
+			if info.IsDir() {
+				if path == root {
+					return nil
+				}
+				name := info.Name()
+				if strings.HasPrefix(name, ".") || skipDirs[name] ||
+					strings.Count(path, string(os.PathSeparator))-depth >= maxDepth {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.Name() == ChatHistoryFile && !seen[path] {
+				seen[path] = true
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// GetSessionFile returns the chat history file containing the session.
+// Several sessions share one file.
+func (a *Adapter) GetSessionFile(id string) string {
+	a.pathsMu.RLock()
+	path, ok := a.sessionPaths[id]
+	a.pathsMu.RUnlock()
+	if ok {
+		return path
+	}
+
+	// Cache miss - rediscover
+	a.ListSessions(context.Background())
+	a.pathsMu.RLock()
+	defer a.pathsMu.RUnlock()
+	return a.sessionPaths[id]
+}
+
+// ErrNoMessages indicates aider was started but never prompted
+var ErrNoMessages = fmt.Errorf("session has no messages")
+
+// ExtractMeta extracts metadata from a session for cache building
+func (a *Adapter) ExtractMeta(ctx context.Context, id string) (*adapters.SessionMeta, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.Messages) == 0 {
+		return nil, ErrNoMessages
+	}
+
+	return &adapters.SessionMeta{
+		ID:      id,
+		Date:    s.Date,
+		Project: filepath.Base(s.WorkDir),
+		Summary: truncate(s.FirstMessage, 100),
+	}, nil
+}
+
+// GetSessionInfo returns detailed session information
+func (a *Adapter) GetSessionInfo(id string) (*adapters.SessionInfo, error) {
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &adapters.SessionInfo{
+		ID:      id,
+		Project: filepath.Base(s.WorkDir),
+		Date:    s.Date,
+		WorkDir: s.WorkDir,
+	}, nil
+}
+
+// GetFilesTouched returns files named in edit blocks or applied edits
+func (a *Adapter) GetFilesTouched(id string) ([]string, error) {
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(s.FilesTouched), nil
+}
+
+// GetSlashCommands returns unique in-chat commands like /add or /run
+func (a *Adapter) GetSlashCommands(id string) ([]string, error) {
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(s.SlashCommands), nil
+}
+
+// GetModels returns the main models announced at startup
+func (a *Adapter) GetModels(id string) ([]string, error) {
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(s.Models), nil
+}
+
+// GetStats returns session statistics from aider's token/cost reports
+func (a *Adapter) GetStats(ctx context.Context, id string) (*adapters.Stats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := s.Stats
+	stats.ToolCalls = make(map[string]int, len(s.Stats.ToolCalls))
+	for name, n := range s.Stats.ToolCalls {
+		stats.ToolCalls[name] = n
+	}
+	return &stats, nil
+}
+
+// GetFirstMessage returns the first user message
+func (a *Adapter) GetFirstMessage(id string) (string, error) {
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return "", err
+	}
+	return truncate(s.FirstMessage, 200), nil
+}
+
+// ExportMessages returns all messages in normalized format
+func (a *Adapter) ExportMessages(ctx context.Context, id string) ([]adapters.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return adapters.CopyMessages(s.Messages), nil
+}
+
+// Session is one aider run, delimited by a "chat started at" marker
+type Session struct {
+	ID      string
+	Path    string
+	WorkDir string
+	Start   time.Time
+	Date    time.Time // Last prompt time, or Start when unknown
+
+	FirstMessage  string
+	FilesTouched  []string
+	SlashCommands []string
+	Models        []string
+	Stats         adapters.Stats
+	Messages      []adapters.Message
+
+	prompts []prompt // Pending until timestamps are applied
+}
+
+// HistoryFile is a parsed chat history with all of its sessions
+type HistoryFile struct {
+	Path     string
+	Sessions []*Session
+}
+
+// LoadSession returns the parsed session, reusing the memoized parse of its
+// history file while the file is unchanged. The session is shared with
+// other callers and must not be modified.
+func (a *Adapter) LoadSession(id string) (*Session, error) {
+	path := a.GetSessionFile(id)
+	if path == "" {
+		return nil, os.ErrNotExist
+	}
+
+	h, err := a.loadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range h.Sessions {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+func (a *Adapter) loadFile(path string) (*HistoryFile, error) {
+	return a.memo.Load(path, func(path string, info os.FileInfo) (*HistoryFile, error) {
+		return parseFile(path)
+	})
+}
+
+// inputEntry is one prompt from .aider.input.history
+type inputEntry struct {
+	time time.Time
+	text string
+}
+
+// parseFile splits a chat history into sessions and timestamps their
+// prompts from the sibling input history
+func parseFile(path string) (*HistoryFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	workDir := filepath.Dir(path)
+	h := &HistoryFile{Path: path}
+	inputs := parseInputHistory(filepath.Join(workDir, InputHistoryFile))
+	ids := make(map[string]int)
+
+	var b *sessionBuilder
+	finish := func() {
+		if b == nil {
+			return
+		}
+		s := b.finish()
+		// Two runs started within the same second get a suffix
+		base := s.ID
+		if n := ids[base]; n > 0 {
+			s.ID = fmt.Sprintf("%s-%d", base, n+1)
+		}
+		ids[base]++
+		h.Sessions = append(h.Sessions, s)
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024) // 10MB max line
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, sessionMarker) {
+			finish()
+			start, _ := time.ParseInLocation(timeLayout, strings.TrimSpace(strings.TrimPrefix(line, sessionMarker)), time.Local)
+			b = newSessionBuilder(path, workDir, start)
+			continue
+		}
+		if b != nil {
+			b.add(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	finish()
+
+	// Hand each session the prompts typed before the next one started
+	for i, s := range h.Sessions {
+		end := time.Time{}
+		if i+1 < len(h.Sessions) {
+			end = h.Sessions[i+1].Start
+		}
+		var window []inputEntry
+		for _, in := range inputs {
+			if !in.time.Before(s.Start) && (end.IsZero() || in.time.Before(end)) {
+				window = append(window, in)
+			}
+		}
+		s.applyTimestamps(window)
+	}
+
+	return h, nil
+}
+
+// parseInputHistory reads "# <timestamp>" headers followed by "+" lines
+func parseInputHistory(path string) []inputEntry {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var entries []inputEntry
+	var cur *inputEntry
+	var lines []string
+	flush := func() {
+		if cur != nil {
+			cur.text = strings.Join(lines, "\n")
+			entries = append(entries, *cur)
+		}
+		cur, lines = nil, nil
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "# "):
+			flush()
+			ts := strings.TrimPrefix(line, "# ")
+			if t, err := time.ParseInLocation(timeLayout+".999999", ts, time.Local); err == nil {
+				cur = &inputEntry{time: t}
+			}
+		case strings.HasPrefix(line, "+") && cur != nil:
+			lines = append(lines, strings.TrimPrefix(line, "+"))
+		}
+	}
+	flush()
+	return entries
+}
+
+// block kinds while walking a session's lines
+const (
+	blockNone = iota
+	blockUser
+	blockAssistant
+)
+
+// sessionBuilder accumulates one session line by line
+type sessionBuilder struct {
+	s      *Session
+	kind   int
+	lines  []string
+	files  map[string]bool
+	cmds   map[string]bool
+	models map[string]bool
+	// prompts records the raw text of every user block, including
+	// commands, in order, with the message index it produced (-1 for commands)
+	prompts []prompt
+}
+
+type prompt struct {
+	text  string
+	index int
+}
+
+func newSessionBuilder(path, workDir string, start time.Time) *sessionBuilder {
+	sum := sha256.Sum256([]byte(workDir))
+	return &sessionBuilder{
+		s: &Session{
+			ID:      hex.EncodeToString(sum[:])[:8] + "-" + start.Format("20060102T150405"),
+			Path:    path,
+			WorkDir: workDir,
+			Start:   start,
+			Date:    start,
+			Stats:   adapters.Stats{ToolCalls: make(map[string]int)},
+		},
+		files:  make(map[string]bool),
+		cmds:   make(map[string]bool),
+		models: make(map[string]bool),
+	}
+}
+
+func (b *sessionBuilder) add(line string) {
+	switch {
+	case line == "####" || strings.HasPrefix(line, "#### "):
+		if b.kind != blockUser {
+			b.flush()
+			b.kind = blockUser
+		}
+		b.lines = append(b.lines, strings.TrimRight(strings.TrimPrefix(strings.TrimPrefix(line, "####"), " "), " "))
+	case line == ">" || strings.HasPrefix(line, "> "):
+		b.flush()
+		b.addInfo(strings.TrimSpace(strings.TrimPrefix(line, ">")))
+	default:
+		if b.kind != blockAssistant {
+			if strings.TrimSpace(line) == "" {
+				return
+			}
+			b.flush()
+			b.kind = blockAssistant
+		}
+		b.lines = append(b.lines, line)
+	}
+}
+
+// flush turns the pending block into a message
+func (b *sessionBuilder) flush() {
+	kind, lines := b.kind, b.lines
+	b.kind, b.lines = blockNone, nil
+
+	switch kind {
+	case blockUser:
+		text := strings.TrimSpace(strings.Join(lines, "\n"))
+		if text == "" {
+			return
+		}
+		if strings.HasPrefix(text, "/") {
+			b.cmds[strings.Fields(text)[0]] = true
+			b.prompts = append(b.prompts, prompt{text: text, index: -1})
+			return
+		}
+		b.s.Stats.UserMessages++
+		if b.s.FirstMessage == "" {
+			b.s.FirstMessage = text
+		}
+		b.prompts = append(b.prompts, prompt{text: text, index: len(b.s.Messages)})
+		b.s.Messages = append(b.s.Messages, adapters.Message{Role: "user", Content: text})
+
+	case blockAssistant:
+		text := strings.TrimSpace(strings.Join(lines, "\n"))
+		if text == "" {
+			return
+		}
+		for _, f := range editBlockFiles(lines) {
+			b.files[b.resolve(f)] = true
+		}
+		b.s.Stats.AssistantMessages++
+		b.s.Messages = append(b.s.Messages, adapters.Message{Role: "assistant", Content: text})
+	}
+}
+
+var costRegex = regexp.MustCompile(`\$([0-9.]+) (?:message|request)`)
+
+// addInfo interprets one "> " status line
+func (b *sessionBuilder) addInfo(info string) {
+	switch {
+	case strings.HasPrefix(info, "Main model: "), strings.HasPrefix(info, "Model: "), strings.HasPrefix(info, "Models: "):
+		_, model, _ := strings.Cut(info, ": ")
+		model, _, _ = strings.Cut(model, " with ")
+		if model = strings.TrimSpace(model); model != "" {
+			b.models[model] = true
+		}
+	case strings.HasPrefix(info, "Tokens: "):
+		b.addTokens(strings.TrimPrefix(info, "Tokens: "))
+	case strings.HasPrefix(info, "Applied edit to "):
+		b.s.Stats.ToolCalls["edit"]++
+		b.files[b.resolve(strings.TrimPrefix(info, "Applied edit to "))] = true
+	}
+}
+
+// addTokens parses "4.2k sent, 1.5k cache write, 2.0k cache hit, 180 received. Cost: $0.02 message, ..."
+func (b *sessionBuilder) addTokens(report string) {
+	counts, cost, _ := strings.Cut(report, ". Cost: ")
+	for _, field := range strings.Split(counts, ", ") {
+		num, label, ok := strings.Cut(strings.TrimSpace(field), " ")
+		if !ok {
+			continue
+		}
+		n := parseCount(num)
+		switch strings.TrimSuffix(label, ".") {
+		case "sent":
+			b.s.Stats.InputTokens += n
+		case "received":
+			b.s.Stats.OutputTokens += n
+		case "cache write":
+			b.s.Stats.CacheWrite += n
+		case "cache hit":
+			b.s.Stats.CacheRead += n
+		}
+	}
+	if m := costRegex.FindStringSubmatch(cost); m != nil {
+		if c, err := strconv.ParseFloat(m[1], 64); err == nil {
+			b.s.Stats.Cost += c
+		}
+	}
+}
+
+// resolve makes a repo-relative path absolute
+func (b *sessionBuilder) resolve(path string) string {
+	path = strings.TrimSpace(path)
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(b.s.WorkDir, path)
+}
+
+func (b *sessionBuilder) finish() *Session {
+	b.flush()
+	s := b.s
+
+	s.FilesTouched = sortedKeys(b.files)
+	s.SlashCommands = sortedKeys(b.cmds)
+	s.Models = sortedKeys(b.models)
+	s.prompts = b.prompts
+	return s
+}
+
+// applyTimestamps matches the session's prompts, in order, against the
+// input history entries typed during the session
+func (s *Session) applyTimestamps(inputs []inputEntry) {
+	next := 0
+	var last int64
+	for _, p := range s.prompts {
+		for i := next; i < len(inputs); i++ {
+			if strings.TrimSpace(inputs[i].text) == p.text {
+				last = inputs[i].time.Unix()
+				next = i + 1
+				if inputs[i].time.After(s.Date) {
+					s.Date = inputs[i].time
+				}
+				break
+			}
+		}
+		if p.index >= 0 {
+			s.Messages[p.index].Timestamp = last
+		}
+	}
+
+	// Replies inherit the time of the prompt they answer
+	for i := range s.Messages {
+		if s.Messages[i].Role == "assistant" && i > 0 {
+			s.Messages[i].Timestamp = s.Messages[i-1].Timestamp
+		}
+	}
+	s.prompts = nil
+}
+
+// editBlockFiles returns the file names that head edit blocks: the line
+// before an opening fence (or the first line inside a fenced SEARCH block)
+func editBlockFiles(lines []string) []string {
+	var files []string
+	inFence := false
+	for i, l := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(l), "```") {
+			continue
+		}
+		if inFence {
+			inFence = false
+			continue
+		}
+		inFence = true
+
+		if i+2 < len(lines) && strings.HasPrefix(lines[i+2], "<<<<<<< SEARCH") && looksLikePath(lines[i+1]) {
+			files = append(files, cleanPath(lines[i+1]))
+		} else if i > 0 && looksLikePath(lines[i-1]) {
+			files = append(files, cleanPath(lines[i-1]))
+		}
+	}
+	return files
+}
+
+func cleanPath(s string) string {
+	return strings.Trim(strings.TrimSpace(s), "`*")
+}
+
+// looksLikePath accepts bare file names as aider prints them above edit blocks
+func looksLikePath(s string) bool {
+	s = cleanPath(s)
+	if s == "" || strings.ContainsAny(s, " \t") || strings.HasSuffix(s, ":") {
+		return false
+	}
+	return strings.ContainsAny(s, "./")
+}
+
+// parseCount parses aider's token counts: "180", "5,100", "4.2k", "1.1M"
+func parseCount(s string) int {
+	s = strings.ReplaceAll(s, ",", "")
+	mult := 1.0
+	switch {
+	case strings.HasSuffix(s, "k"):
+		mult, s = 1e3, strings.TrimSuffix(s, "k")
+	case strings.HasSuffix(s, "M"):
+		mult, s = 1e6, strings.TrimSuffix(s, "M")
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return int(f*mult + 0.5)
+}
+
+// Helper functions
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen-3] + "..."
+}
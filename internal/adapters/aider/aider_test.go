@@ -0,0 +1,335 @@
+package aider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+func testDataDir(t *testing.T) string {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(wd, "testdata", "projects")
+}
+
+func setupTestAdapter(t *testing.T) *Adapter {
+	t.Helper()
+	return New(testDataDir(t))
+}
+
+// sessionID builds the expected ID for a run started in dir at start
+func sessionID(dir, start string) string {
+	sum := sha256.Sum256([]byte(dir))
+	t, _ := time.ParseInLocation(timeLayout, start, time.Local)
+	return hex.EncodeToString(sum[:])[:8] + "-" + t.Format("20060102T150405")
+}
+
+func webappSession(t *testing.T) string {
+	return sessionID(filepath.Join(testDataDir(t), "webapp"), "2025-03-10 09:00:00")
+}
+
+func TestNew(t *testing.T) {
+	a := New("/test/path")
+	if a.DataDir() != "/test/path" {
+		t.Errorf("DataDir() = %q, want %q", a.DataDir(), "/test/path")
+	}
+	if a.Name() != "aider" {
+		t.Errorf("Name() = %q, want %q", a.Name(), "aider")
+	}
+}
+
+func TestNew_AiderRoots(t *testing.T) {
+	roots := "/code" + string(os.PathListSeparator) + "/work"
+	t.Setenv("AIDER_ROOTS", roots)
+	a := New("")
+	if len(a.roots) != 2 || a.roots[0] != "/code" || a.roots[1] != "/work" {
+		t.Errorf("roots = %v, want [/code /work]", a.roots)
+	}
+	if a.DataDir() != roots {
+		t.Errorf("DataDir() = %q, want %q", a.DataDir(), roots)
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	sessions, err := a.ListSessions(context.Background())
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+
+	// node_modules and hidden directories are not searched
+	if len(sessions) != 4 {
+		t.Fatalf("ListSessions() returned %d sessions, want 4: %v", len(sessions), sessions)
+	}
+
+	// Newest run first
+	want := sessionID(filepath.Join(testDataDir(t), "webapp"), "2025-03-12 08:00:00")
+	if sessions[0] != want {
+		t.Errorf("sessions[0] = %q, want %q", sessions[0], want)
+	}
+	oldest := sessionID(filepath.Join(testDataDir(t), "tools", "cli"), "2025-02-01 18:00:00")
+	if sessions[3] != oldest {
+		t.Errorf("sessions[3] = %q, want %q", sessions[3], oldest)
+	}
+}
+
+func TestGetSessionFile_CacheMiss(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	path := a.GetSessionFile(webappSession(t))
+	want := filepath.Join(testDataDir(t), "webapp", ChatHistoryFile)
+	if path != want {
+		t.Errorf("GetSessionFile() = %q, want %q", path, want)
+	}
+	if a.GetSessionFile("nonexistent") != "" {
+		t.Error("GetSessionFile() should return empty for unknown IDs")
+	}
+}
+
+func TestExtractMeta(t *testing.T) {
+	a := setupTestAdapter(t)
+	id := webappSession(t)
+
+	meta, err := a.ExtractMeta(context.Background(), id)
+	if err != nil {
+		t.Fatalf("ExtractMeta() error = %v", err)
+	}
+
+	if meta.ID != id {
+		t.Errorf("ID = %q, want %q", meta.ID, id)
+	}
+	if meta.Project != "webapp" {
+		t.Errorf("Project = %q, want %q", meta.Project, "webapp")
+	}
+	// The /add command is not the first prompt; multi-line prompts are joined
+	want := "Add a /health endpoint\nthat returns {\"ok\": true}"
+	if meta.Summary != want {
+		t.Errorf("Summary = %q, want %q", meta.Summary, want)
+	}
+	// Date is the last prompt typed, taken from the input history
+	if got := meta.Date.Format(timeLayout); got != "2025-03-10 09:03:40" {
+		t.Errorf("Date = %q, want %q", got, "2025-03-10 09:03:40")
+	}
+}
+
+func TestExtractMeta_NoMessages(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	id := sessionID(filepath.Join(testDataDir(t), "webapp"), "2025-03-12 08:00:00")
+	if _, err := a.ExtractMeta(context.Background(), id); !errors.Is(err, ErrNoMessages) {
+		t.Errorf("ExtractMeta() error = %v, want ErrNoMessages", err)
+	}
+}
+
+func TestGetSessionInfo(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	info, err := a.GetSessionInfo(webappSession(t))
+	if err != nil {
+		t.Fatalf("GetSessionInfo() error = %v", err)
+	}
+
+	if info.WorkDir != filepath.Join(testDataDir(t), "webapp") {
+		t.Errorf("WorkDir = %q, want the repo directory", info.WorkDir)
+	}
+}
+
+func TestGetFilesTouched(t *testing.T) {
+	a := setupTestAdapter(t)
+	dir := filepath.Join(testDataDir(t), "webapp")
+
+	files, err := a.GetFilesTouched(webappSession(t))
+	if err != nil {
+		t.Fatalf("GetFilesTouched() error = %v", err)
+	}
+
+	want := []string{
+		filepath.Join(dir, "app.py"),
+		filepath.Join(dir, "tests", "test_health.py"),
+	}
+	if len(files) != len(want) {
+		t.Fatalf("GetFilesTouched() = %v, want %v", files, want)
+	}
+	for i := range want {
+		if files[i] != want[i] {
+			t.Errorf("files[%d] = %q, want %q", i, files[i], want[i])
+		}
+	}
+}
+
+func TestGetFilesTouched_WholeFormat(t *testing.T) {
+	a := setupTestAdapter(t)
+	dir := filepath.Join(testDataDir(t), "tools", "cli")
+
+	files, err := a.GetFilesTouched(sessionID(dir, "2025-02-01 18:00:00"))
+	if err != nil {
+		t.Fatalf("GetFilesTouched() error = %v", err)
+	}
+	if len(files) != 1 || files[0] != filepath.Join(dir, "main.go") {
+		t.Errorf("GetFilesTouched() = %v, want [%s]", files, filepath.Join(dir, "main.go"))
+	}
+}
+
+func TestGetSlashCommands(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	cmds, err := a.GetSlashCommands(webappSession(t))
+	if err != nil {
+		t.Fatalf("GetSlashCommands() error = %v", err)
+	}
+	if len(cmds) != 1 || cmds[0] != "/add" {
+		t.Errorf("GetSlashCommands() = %v, want [/add]", cmds)
+	}
+}
+
+func TestGetModels(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	models, err := a.GetModels(webappSession(t))
+	if err != nil {
+		t.Fatalf("GetModels() error = %v", err)
+	}
+	// The weak model is not reported
+	if len(models) != 1 || models[0] != "claude-3-7-sonnet-20250219" {
+		t.Errorf("GetModels() = %v, want [claude-3-7-sonnet-20250219]", models)
+	}
+
+	models, _ = a.GetModels(sessionID(filepath.Join(testDataDir(t), "webapp"), "2025-03-11 14:30:00"))
+	if len(models) != 1 || models[0] != "gpt-4o" {
+		t.Errorf("GetModels() = %v, want [gpt-4o]", models)
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	stats, err := a.GetStats(context.Background(), webappSession(t))
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+
+	if stats.UserMessages != 2 {
+		t.Errorf("UserMessages = %d, want 2", stats.UserMessages)
+	}
+	if stats.AssistantMessages != 2 {
+		t.Errorf("AssistantMessages = %d, want 2", stats.AssistantMessages)
+	}
+	if stats.InputTokens != 9300 {
+		t.Errorf("InputTokens = %d, want 9300", stats.InputTokens)
+	}
+	if stats.OutputTokens != 275 {
+		t.Errorf("OutputTokens = %d, want 275", stats.OutputTokens)
+	}
+	if stats.CacheWrite != 1500 {
+		t.Errorf("CacheWrite = %d, want 1500", stats.CacheWrite)
+	}
+	if stats.CacheRead != 2000 {
+		t.Errorf("CacheRead = %d, want 2000", stats.CacheRead)
+	}
+	if stats.Cost < 0.0299 || stats.Cost > 0.0301 {
+		t.Errorf("Cost = %f, want 0.03", stats.Cost)
+	}
+	if stats.ToolCalls["edit"] != 2 {
+		t.Errorf("ToolCalls[edit] = %d, want 2", stats.ToolCalls["edit"])
+	}
+}
+
+func TestExportMessages(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	messages, err := a.ExportMessages(context.Background(), webappSession(t))
+	if err != nil {
+		t.Fatalf("ExportMessages() error = %v", err)
+	}
+
+	if len(messages) != 4 {
+		t.Fatalf("ExportMessages() returned %d messages, want 4", len(messages))
+	}
+
+	roles := []string{"user", "assistant", "user", "assistant"}
+	for i, want := range roles {
+		if messages[i].Role != want {
+			t.Errorf("messages[%d].Role = %q, want %q", i, messages[i].Role, want)
+		}
+	}
+
+	// Status lines are not part of the reply
+	reply := messages[1].Content
+	if !strings.HasPrefix(reply, "I'll add the endpoint") {
+		t.Errorf("messages[1].Content = %q, want reply text", reply)
+	}
+	for _, m := range messages {
+		if strings.Contains(m.Content, "> Tokens:") {
+			t.Errorf("message content starts with a status line: %q", m.Content)
+		}
+	}
+
+	// Prompts are timestamped from the input history; replies inherit them
+	want, _ := time.ParseInLocation(timeLayout, "2025-03-10 09:01:05", time.Local)
+	if messages[0].Timestamp != want.Unix() || messages[1].Timestamp != want.Unix() {
+		t.Errorf("timestamps = %d/%d, want %d", messages[0].Timestamp, messages[1].Timestamp, want.Unix())
+	}
+}
+
+func TestParseCount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"180", 180},
+		{"5,100", 5100},
+		{"4.2k", 4200},
+		{"1.1M", 1100000},
+		{"bogus", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseCount(tt.in); got != tt.want {
+			t.Errorf("parseCount(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLoadSession_Memoized(t *testing.T) {
+	a := setupTestAdapter(t)
+	id := webappSession(t)
+
+	s1, err := a.LoadSession(id)
+	if err != nil {
+		t.Fatalf("LoadSession() error = %v", err)
+	}
+	s2, _ := a.LoadSession(id)
+	if s1 != s2 {
+		t.Error("LoadSession() reparsed an unchanged history file")
+	}
+}
+
+func TestResumeCmd(t *testing.T) {
+	a := New("")
+	if cmd := a.ResumeCmd("abc"); cmd != "aider --restore-chat-history" {
+		t.Errorf("ResumeCmd() = %q, want %q", cmd, "aider --restore-chat-history")
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	var a adapters.Adapter = setupTestAdapter(t)
+
+	if _, ok := a.(adapters.Brancher); ok {
+		t.Error("adapter should not implement Brancher")
+	}
+	if _, err := adapters.BranchSession(a, "x"); !errors.Is(err, adapters.ErrNotSupported) {
+		t.Errorf("BranchSession() error = %v, want ErrNotSupported", err)
+	}
+}
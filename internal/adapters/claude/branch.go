@@ -0,0 +1,359 @@
+package claude
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+// BranchSession creates a copy of a session for branching
+func (a *Adapter) BranchSession(id string) (string, error) {
+	return a.branch(id, nil)
+}
+
+// BranchSessionAt creates a branch that ends at the given message. Only
+// the message's ancestors along the parentUuid chain are kept, so turns
+// from other forks in the file don't leak into the copy. Besides the
+// exported messages, at may name any message uuid in the file, such as
+// the leaf of an inactive fork.
+func (a *Adapter) BranchSessionAt(id, at string) (string, error) {
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return "", err
+	}
+	c := &cut{uuid: at}
+	if n, err := adapters.FindMessage(s.Messages, at); err == nil {
+		c = &cut{uuid: s.Messages[n].ID, index: n}
+	}
+	return a.branch(id, c)
+}
+
+// cut is where a branch ends: the message with the given uuid or, in
+// files without uuids, the index-th exported message
+type cut struct {
+	uuid  string
+	index int
+}
+
+// branch writes the new session file, cut at c unless it is nil.
+//
+// The copy is a session of its own: every record gets the new sessionId
+// and fresh uuids, so Claude Code never confuses it with the parent. The
+// transcripts of the subagents it spawned are copied along with it.
+func (a *Adapter) branch(id string, c *cut) (string, error) {
+	originalPath := a.GetSessionFile(id)
+	if originalPath == "" {
+		return "", os.ErrNotExist
+	}
+
+	// Generate new UUID
+	newID := generateUUID()
+
+	// New file in same directory
+	dir := filepath.Dir(originalPath)
+	newPath := filepath.Join(dir, newID+".jsonl")
+
+	// Read original content
+	content, err := os.ReadFile(originalPath)
+	if err != nil {
+		return "", err
+	}
+	lines, err := branchLines(content, c)
+	if err != nil {
+		return "", err
+	}
+	rw := newRewriter(newID, lines)
+
+	// Create branch metadata
+	branchMeta := map[string]interface{}{
+		"type":          "branch",
+		"parentSession": id,
+		"sessionId":     newID,
+		"branchedAt":    time.Now().UTC().Format(time.RFC3339),
+	}
+	metaJSON, _ := json.Marshal(branchMeta)
+
+	// Write new file with branch metadata prepended
+	newContent := append(metaJSON, '\n')
+	for _, line := range lines {
+		newContent = append(newContent, rw.line(line)...)
+		newContent = append(newContent, '\n')
+	}
+
+	if err := os.WriteFile(newPath, newContent, 0644); err != nil {
+		return "", err
+	}
+
+	// Subagent transcripts of a regular session live next to it
+	if !strings.Contains(id, "/") {
+		src, dst := filepath.Join(dir, id, "subagents"), filepath.Join(dir, newID, "subagents")
+		if err := rw.copySubagents(src, dst, cutAgents(content, lines)); err != nil {
+			os.Remove(newPath)
+			os.RemoveAll(filepath.Join(dir, newID))
+			return "", err
+		}
+	}
+
+	// Touch the file to ensure proper mtime
+	now := time.Now()
+	os.Chtimes(newPath, now, now)
+
+	// Cache the new session path (write lock)
+	a.pathsMu.Lock()
+	a.sessionPaths[newID] = newPath
+	a.pathsMu.Unlock()
+
+	return newID, nil
+}
+
+// branchLines returns the records to copy into a branch. Branch records of
+// the source are dropped so the copy points at its direct parent. With a
+// cut the copy ends at that message and keeps only its ancestors, plus
+// metadata records (summaries, titles) that precede it.
+func branchLines(content []byte, c *cut) ([][]byte, error) {
+	type line struct {
+		raw []byte
+		r   record
+	}
+	var lines []line
+	for _, raw := range bytes.Split(content, []byte("\n")) {
+		if len(bytes.TrimSpace(raw)) == 0 {
+			continue
+		}
+		var r record
+		json.Unmarshal(raw, &r) // Malformed lines are copied as they are
+		if r.Type == "branch" {
+			continue
+		}
+		lines = append(lines, line{raw: raw, r: r})
+	}
+
+	if c == nil {
+		out := make([][]byte, len(lines))
+		for i, l := range lines {
+			out[i] = l.raw
+		}
+		return out, nil
+	}
+
+	// Find the line of the branch point
+	target, n := -1, 0
+	for i, l := range lines {
+		if (l.r.Type != "user" && l.r.Type != "assistant") || l.r.IsMeta {
+			continue
+		}
+		if (c.uuid != "" && l.r.UUID == c.uuid) || (c.uuid == "" && n == c.index) {
+			target = i
+			break
+		}
+		n++
+	}
+	if target < 0 {
+		return nil, fmt.Errorf("%w: %s", adapters.ErrMessageNotFound, c.uuid)
+	}
+
+	// Walk the parentUuid chain back from the branch point
+	keep := make(map[string]bool)
+	if lines[target].r.UUID != "" {
+		byUUID := make(map[string]record, target+1)
+		for _, l := range lines[:target+1] {
+			if l.r.UUID != "" {
+				byUUID[l.r.UUID] = l.r
+			}
+		}
+		for u := lines[target].r.UUID; u != "" && !keep[u]; {
+			keep[u] = true
+			r := byUUID[u]
+			u = r.ParentUUID
+			if u == "" {
+				u = r.LogicalParent
+			}
+		}
+	}
+
+	var out [][]byte
+	for i, l := range lines[:target+1] {
+		// Records without a UUID predate the chain format; keep them all
+		if l.r.UUID == "" || keep[l.r.UUID] || lines[target].r.UUID == "" {
+			out = append(out, lines[i].raw)
+		}
+	}
+	return out, nil
+}
+
+// cutAgents returns the IDs of the subagents whose spawning tool call is
+// not among the branch's lines. The parent learns an agent's ID from the
+// result of the call, in its toolUseResult; agents no result names are
+// kept, since there is no telling where they started.
+func cutAgents(content []byte, lines [][]byte) map[string]bool {
+	type spawn struct {
+		Message struct {
+			Content interface{} `json:"content"`
+		} `json:"message"`
+		ToolUseResult interface{} `json:"toolUseResult"`
+	}
+	blocks := func(raw []byte, kind string) (spawn, []map[string]interface{}) {
+		var r spawn
+		json.Unmarshal(raw, &r)
+		content, _ := r.Message.Content.([]interface{})
+		var out []map[string]interface{}
+		for _, item := range content {
+			if m, ok := item.(map[string]interface{}); ok && m["type"] == kind {
+				out = append(out, m)
+			}
+		}
+		return r, out
+	}
+
+	calls := make(map[string]bool) // Tool calls kept in the branch
+	for _, raw := range lines {
+		_, uses := blocks(raw, "tool_use")
+		for _, m := range uses {
+			calls[getString(m, "id")] = true
+		}
+	}
+
+	cut := make(map[string]bool)
+	for _, raw := range bytes.Split(content, []byte("\n")) {
+		r, results := blocks(raw, "tool_result")
+		result, _ := r.ToolUseResult.(map[string]interface{})
+		agent := getString(result, "agentId")
+		if agent == "" {
+			continue
+		}
+		for _, m := range results {
+			if !calls[getString(m, "tool_use_id")] {
+				cut[agent] = true
+			}
+		}
+	}
+	return cut
+}
+
+// rewriter gives copied records the branch's session ID and a fresh uuid
+// for every message, remapping references (parentUuid, leafUuid,
+// messageId, ...) to match
+type rewriter struct {
+	sessionID string
+	uuids     map[string]string // Old uuid -> new uuid
+	leaf      string            // New uuid of the branch's last message
+}
+
+func newRewriter(sessionID string, lines [][]byte) *rewriter {
+	rw := &rewriter{sessionID: sessionID, uuids: make(map[string]string)}
+	rw.add(lines)
+	for _, raw := range lines {
+		var r record
+		if json.Unmarshal(raw, &r) == nil && r.UUID != "" {
+			rw.leaf = rw.uuids[r.UUID]
+		}
+	}
+	return rw
+}
+
+// add gives the messages of lines fresh uuids
+func (rw *rewriter) add(lines [][]byte) {
+	for _, raw := range lines {
+		var r record
+		if json.Unmarshal(raw, &r) == nil && r.UUID != "" {
+			rw.uuids[r.UUID] = generateUUID()
+		}
+	}
+}
+
+// line rewrites one record. Lines that aren't JSON objects are returned
+// unchanged.
+func (rw *rewriter) line(raw []byte) []byte {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return raw
+	}
+	rw.object(obj)
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return raw
+	}
+	return out
+}
+
+// object rewrites identifiers in place. The message body is left alone:
+// identifiers never appear there, but text that happens to look like one
+// might.
+func (rw *rewriter) object(obj map[string]interface{}) {
+	for k, v := range obj {
+		switch v := v.(type) {
+		case string:
+			if k == "sessionId" {
+				obj[k] = rw.sessionID
+			} else if u, ok := rw.uuids[v]; ok {
+				obj[k] = u
+			} else if k == "leafUuid" {
+				// The leaf was cut off or lives in another session; a
+				// summary then describes where the branch ends
+				if rw.leaf != "" {
+					obj[k] = rw.leaf
+				} else {
+					delete(obj, k)
+				}
+			}
+		case map[string]interface{}:
+			if k != "message" {
+				rw.object(v)
+			}
+		}
+	}
+}
+
+// copySubagents copies the subagent transcripts into the branch, except
+// those of the cut agents. Their records point at the new session and get
+// fresh uuids like the branch's own.
+func (rw *rewriter) copySubagents(src, dst string, cut map[string]bool) error {
+	entries, err := os.ReadDir(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		// Transcripts are named agent-<id>.jsonl
+		agent, _, _ := strings.Cut(strings.TrimPrefix(e.Name(), "agent-"), ".")
+		if cut[agent] {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(src, e.Name()))
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(e.Name(), ".jsonl") {
+			lines := bytes.Split(data, []byte("\n"))
+			rw.add(lines)
+			var out []byte
+			for _, raw := range lines {
+				if len(bytes.TrimSpace(raw)) == 0 {
+					continue
+				}
+				out = append(out, rw.line(raw)...)
+				out = append(out, '\n')
+			}
+			data = out
+		}
+		if err := os.WriteFile(filepath.Join(dst, e.Name()), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
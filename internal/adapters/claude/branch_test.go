@@ -0,0 +1,346 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+// forkedSession was rewound once: u3 was sent again from a1, so u2/a2 are
+// an abandoned branch that is still in the file
+const forkedSession = `{"type":"summary","summary":"Greeting","leafUuid":"a3"}
+{"type":"user","sessionId":"forked","uuid":"u1","parentUuid":null,"cwd":"/test","timestamp":"2025-01-10T09:00:00.000Z","message":{"role":"user","content":"Hello"}}
+{"type":"assistant","sessionId":"forked","uuid":"a1","parentUuid":"u1","timestamp":"2025-01-10T09:00:01.000Z","message":{"role":"assistant","content":[{"type":"text","text":"Hi"}]}}
+{"type":"user","sessionId":"forked","uuid":"u2","parentUuid":"a1","timestamp":"2025-01-10T09:00:02.000Z","message":{"role":"user","content":"Delete everything"}}
+{"type":"assistant","sessionId":"forked","uuid":"a2","parentUuid":"u2","timestamp":"2025-01-10T09:00:03.000Z","message":{"role":"assistant","content":[{"type":"text","text":"Done"}]}}
+{"type":"user","sessionId":"forked","uuid":"u3","parentUuid":"a1","timestamp":"2025-01-10T09:00:04.000Z","message":{"role":"user","content":"Write a test"}}
+{"type":"assistant","sessionId":"forked","uuid":"a3","parentUuid":"u3","timestamp":"2025-01-10T09:00:05.000Z","message":{"role":"assistant","content":[{"type":"text","text":"Added"}]}}
+`
+
+// setupForked writes forkedSession into a fresh data dir
+func setupForked(t *testing.T) *Adapter {
+	t.Helper()
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "test-project")
+	os.MkdirAll(projectDir, 0755)
+	os.WriteFile(filepath.Join(projectDir, "forked.jsonl"), []byte(forkedSession), 0644)
+	return New(tmpDir)
+}
+
+func TestBranchSessionAt(t *testing.T) {
+	tests := []struct {
+		name string
+		at   string
+		want []string // Message contents of the branch
+	}{
+		{"by uuid on the live branch", "a3", []string{"Hello", "Hi", "Write a test", "Added"}},
+		{"by uuid on the abandoned branch", "u2", []string{"Hello", "Hi", "Delete everything"}},
+		{"by index", "1", []string{"Hello", "Hi"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := setupForked(t)
+			newID, err := a.BranchSessionAt("forked", tt.at)
+			if err != nil {
+				t.Fatalf("BranchSessionAt() error = %v", err)
+			}
+
+			messages, err := a.ExportMessages(context.Background(), newID)
+			if err != nil {
+				t.Fatalf("ExportMessages() error = %v", err)
+			}
+			var got []string
+			for _, m := range messages {
+				got = append(got, m.Content)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("branch messages = %q, want %q", got, tt.want)
+			}
+
+			meta, err := a.ExtractMeta(context.Background(), newID)
+			if err != nil {
+				t.Fatalf("ExtractMeta() error = %v", err)
+			}
+			if meta.ParentSID != "forked" || meta.Summary != "Greeting" {
+				t.Errorf("ExtractMeta() = %+v, want parent forked and the summary kept", meta)
+			}
+		})
+	}
+}
+
+func TestBranchSessionAt_BranchOfBranch(t *testing.T) {
+	a := setupForked(t)
+	first, err := a.BranchSessionAt("forked", "a3")
+	if err != nil {
+		t.Fatalf("BranchSessionAt() error = %v", err)
+	}
+	second, err := a.BranchSessionAt(first, "1")
+	if err != nil {
+		t.Fatalf("BranchSessionAt() error = %v", err)
+	}
+
+	meta, _ := a.ExtractMeta(context.Background(), second)
+	if meta == nil || meta.ParentSID != first {
+		t.Errorf("ExtractMeta() = %+v, want parent %s", meta, first)
+	}
+}
+
+func TestBranchSessionAt_UnknownMessage(t *testing.T) {
+	a := setupForked(t)
+	for _, at := range []string{"nope", "6", "-1"} {
+		if _, err := a.BranchSessionAt("forked", at); !errors.Is(err, adapters.ErrMessageNotFound) {
+			t.Errorf("BranchSessionAt(%q) error = %v, want ErrMessageNotFound", at, err)
+		}
+	}
+}
+
+// setupForkedWithAgent adds a subagent transcript to the forked session
+func setupForkedWithAgent(t *testing.T) (*Adapter, string) {
+	t.Helper()
+	a := setupForked(t)
+	dir := filepath.Dir(a.GetSessionFile("forked"))
+	agentDir := filepath.Join(dir, "forked", "subagents")
+	os.MkdirAll(agentDir, 0755)
+	os.WriteFile(filepath.Join(agentDir, "agent-1.jsonl"), []byte(
+		`{"type":"user","sessionId":"forked","agentId":"1","uuid":"x1","parentUuid":null,"message":{"role":"user","content":"Find the tests"}}
+{"type":"assistant","sessionId":"forked","agentId":"1","uuid":"x2","parentUuid":"x1","message":{"role":"assistant","content":[{"type":"text","text":"Found"}]}}
+`), 0644)
+	return a, dir
+}
+
+// readRecords returns every record of a JSONL file
+func readRecords(t *testing.T, path string) []map[string]interface{} {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var r map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("invalid record %q: %v", line, err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestBranchSession_RewritesIdentifiers(t *testing.T) {
+	a, dir := setupForkedWithAgent(t)
+	newID, err := a.BranchSession("forked")
+	if err != nil {
+		t.Fatalf("BranchSession() error = %v", err)
+	}
+
+	parentUUIDs := make(map[string]bool)
+	for _, r := range readRecords(t, filepath.Join(dir, "forked.jsonl")) {
+		if u, ok := r["uuid"].(string); ok {
+			parentUUIDs[u] = true
+		}
+	}
+
+	records := readRecords(t, filepath.Join(dir, newID+".jsonl"))
+	uuids := make(map[string]bool)
+	for _, r := range records {
+		if u, ok := r["uuid"].(string); ok {
+			if parentUUIDs[u] {
+				t.Errorf("uuid %s is shared with the parent", u)
+			}
+			uuids[u] = true
+		}
+	}
+	if len(uuids) != len(parentUUIDs) {
+		t.Errorf("branch has %d uuids, want %d", len(uuids), len(parentUUIDs))
+	}
+
+	for i, r := range records {
+		if r["type"] == "summary" {
+			if leaf, _ := r["leafUuid"].(string); !uuids[leaf] {
+				t.Errorf("summary leafUuid %q was not remapped", leaf)
+			}
+			continue
+		}
+		if sid := r["sessionId"]; sid != newID {
+			t.Errorf("record %d sessionId = %v, want %s", i, sid, newID)
+		}
+		if p, ok := r["parentUuid"].(string); ok && !uuids[p] {
+			t.Errorf("record %d parentUuid %q is not a uuid of the branch", i, p)
+		}
+	}
+}
+
+func TestBranchSession_CopiesSubagents(t *testing.T) {
+	a, dir := setupForkedWithAgent(t)
+	newID, err := a.BranchSession("forked")
+	if err != nil {
+		t.Fatalf("BranchSession() error = %v", err)
+	}
+
+	for _, r := range readRecords(t, filepath.Join(dir, newID, "subagents", "agent-1.jsonl")) {
+		if r["sessionId"] != newID {
+			t.Errorf("subagent sessionId = %v, want %s", r["sessionId"], newID)
+		}
+	}
+
+	ids, err := a.ListSessions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, id := range ids {
+		found = found || id == newID+"/agent-1"
+	}
+	if !found {
+		t.Errorf("ListSessions() = %v, want the branch's subagent", ids)
+	}
+	meta, err := a.ExtractMeta(context.Background(), newID+"/agent-1")
+	if err != nil || meta.ParentSID != newID {
+		t.Errorf("subagent ExtractMeta() = %+v, %v; want parent %s", meta, err, newID)
+	}
+
+	// The parent keeps its own subagent untouched
+	for _, r := range readRecords(t, filepath.Join(dir, "forked", "subagents", "agent-1.jsonl")) {
+		if r["sessionId"] != "forked" {
+			t.Errorf("parent subagent sessionId = %v, want forked", r["sessionId"])
+		}
+	}
+}
+
+func TestBranchSession_RemapsSubagentUUIDs(t *testing.T) {
+	a, dir := setupForkedWithAgent(t)
+	newID, err := a.BranchSession("forked")
+	if err != nil {
+		t.Fatalf("BranchSession() error = %v", err)
+	}
+
+	records := readRecords(t, filepath.Join(dir, newID, "subagents", "agent-1.jsonl"))
+	if len(records) != 2 {
+		t.Fatalf("subagent has %d records, want 2", len(records))
+	}
+	for _, r := range records {
+		if r["uuid"] == "x1" || r["uuid"] == "x2" {
+			t.Errorf("subagent record kept the parent's uuid %v", r["uuid"])
+		}
+	}
+	if records[1]["parentUuid"] != records[0]["uuid"] {
+		t.Errorf("subagent parentUuid = %v, want %v", records[1]["parentUuid"], records[0]["uuid"])
+	}
+}
+
+// spawningSession starts a subagent from each of two Task calls, the
+// second after a1
+const spawningSession = `{"type":"user","sessionId":"spawner","uuid":"u1","parentUuid":null,"message":{"role":"user","content":"Find the tests"}}
+{"type":"assistant","sessionId":"spawner","uuid":"a1","parentUuid":"u1","message":{"role":"assistant","content":[{"type":"tool_use","id":"toolu_1","name":"Task","input":{"prompt":"Find the tests"}}]}}
+{"type":"user","sessionId":"spawner","uuid":"r1","parentUuid":"a1","toolUseResult":{"status":"completed","agentId":"1"},"message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"Found"}]}}
+{"type":"assistant","sessionId":"spawner","uuid":"a2","parentUuid":"r1","message":{"role":"assistant","content":[{"type":"tool_use","id":"toolu_2","name":"Task","input":{"prompt":"Run them"}}]}}
+{"type":"user","sessionId":"spawner","uuid":"r2","parentUuid":"a2","toolUseResult":{"status":"completed","agentId":"2"},"message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_2","content":"Passed"}]}}
+{"type":"summary","summary":"Tests","leafUuid":"r2"}
+`
+
+func TestBranchSessionAt_CopiesSurvivingSubagents(t *testing.T) {
+	tmpDir := t.TempDir()
+	dir := filepath.Join(tmpDir, "test-project")
+	agentDir := filepath.Join(dir, "spawner", "subagents")
+	os.MkdirAll(agentDir, 0755)
+	os.WriteFile(filepath.Join(dir, "spawner.jsonl"), []byte(spawningSession), 0644)
+	for _, agent := range []string{"1", "2"} {
+		os.WriteFile(filepath.Join(agentDir, "agent-"+agent+".jsonl"), []byte(
+			`{"type":"user","sessionId":"spawner","agentId":"`+agent+`","uuid":"x`+agent+`","parentUuid":null,"message":{"role":"user","content":"Go"}}`+"\n"), 0644)
+	}
+	a := New(tmpDir)
+
+	newID, err := a.BranchSessionAt("spawner", "r1")
+	if err != nil {
+		t.Fatalf("BranchSessionAt() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, newID, "subagents", "agent-1.jsonl")); err != nil {
+		t.Errorf("subagent spawned before the cut was not copied: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, newID, "subagents", "agent-2.jsonl")); !os.IsNotExist(err) {
+		t.Errorf("subagent spawned after the cut was copied: %v", err)
+	}
+}
+
+func TestBranchSessionAt_RewritesCutLeaves(t *testing.T) {
+	a, dir := setupForkedWithAgent(t)
+	// The summary's leaf a3 is on the other fork
+	newID, err := a.BranchSessionAt("forked", "u2")
+	if err != nil {
+		t.Fatalf("BranchSessionAt() error = %v", err)
+	}
+
+	records := readRecords(t, filepath.Join(dir, newID+".jsonl"))
+	uuids := make(map[string]bool)
+	for _, r := range records {
+		if u, ok := r["uuid"].(string); ok {
+			uuids[u] = true
+		}
+	}
+	summaries := 0
+	for _, r := range records {
+		if r["type"] != "summary" {
+			continue
+		}
+		summaries++
+		if leaf, _ := r["leafUuid"].(string); !uuids[leaf] {
+			t.Errorf("summary leafUuid %q is not a message of the branch", leaf)
+		}
+	}
+	if summaries != 1 {
+		t.Errorf("branch has %d summaries, want 1", summaries)
+	}
+}
+
+// A resumed branch appends records with its own sessionId and parentUuids
+// pointing into its own chain. None of that may reach the parent.
+func TestBranchSession_ResumesIndependently(t *testing.T) {
+	a, dir := setupForkedWithAgent(t)
+	newID, err := a.BranchSessionAt("forked", "a3")
+	if err != nil {
+		t.Fatalf("BranchSessionAt() error = %v", err)
+	}
+	parentBefore, _ := os.ReadFile(filepath.Join(dir, "forked.jsonl"))
+
+	branch, _ := a.ExportMessages(context.Background(), newID)
+	leaf := branch[len(branch)-1].ID
+	f, err := os.OpenFile(filepath.Join(dir, newID+".jsonl"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"type":"user","sessionId":"` + newID + `","uuid":"b1","parentUuid":"` + leaf + `","message":{"role":"user","content":"Now in the branch"}}` + "\n")
+	f.Close()
+	if err := a.RenameSession(newID, "Branch only"); err != nil {
+		t.Fatal(err)
+	}
+
+	parentAfter, _ := os.ReadFile(filepath.Join(dir, "forked.jsonl"))
+	if string(parentAfter) != string(parentBefore) {
+		t.Error("working in the branch changed the parent file")
+	}
+	parent, _ := a.ExtractMeta(context.Background(), "forked")
+	if parent.Summary != "Greeting" {
+		t.Errorf("parent summary = %q, want it unchanged", parent.Summary)
+	}
+	messages, _ := a.ExportMessages(context.Background(), "forked")
+	if len(messages) != 4 || messages[3].ID != "a3" {
+		t.Errorf("parent messages = %+v, want its active path", messages)
+	}
+
+	messages, _ = a.ExportMessages(context.Background(), newID)
+	if len(messages) != 5 || messages[4].Content != "Now in the branch" {
+		t.Errorf("branch messages = %+v, want the 4 copied plus the new one", messages)
+	}
+	for _, m := range messages[:4] {
+		switch m.ID {
+		case "u1", "a1", "u3", "a3":
+			t.Errorf("branch message kept the parent's uuid %s", m.ID)
+		}
+	}
+}
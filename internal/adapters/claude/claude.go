@@ -2,12 +2,15 @@ package claude
 
 import (
 	"bufio"
+	"context"
 	"crypto/rand"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -20,8 +23,21 @@ import (
 type Adapter struct {
 	dataDir      string
 	cacheDir     string
-	sessionPaths map[string]string // Cache of session ID -> full file path
-	pathsMu      sync.RWMutex      // Protects sessionPaths
+	sessionPaths map[string]string        // Cache of session ID -> full file path
+	pathsMu      sync.RWMutex             // Protects sessionPaths
+	memo         *adapters.Memo[*Session] // Parsed sessions keyed by file path
+
+	// Legacy agent transcripts by project directory and parent session,
+	// read once per listing rather than once per session
+	agents   map[string]map[string][]string
+	agentsMu sync.Mutex // Protects agents
+}
+
+func init() {
+	adapters.Register(adapters.Provider{
+		Name: "claude",
+		New:  func() adapters.Adapter { return New("") },
+	})
 }
 
 // New creates a new Claude adapter
@@ -38,6 +54,7 @@ func New(dataDir string) *Adapter {
 		dataDir:      dataDir,
 		cacheDir:     filepath.Join(dataDir, "..", ".cache"),
 		sessionPaths: make(map[string]string),
+		memo:         adapters.NewMemo[*Session](adapters.MemoBytes),
 	}
 }
 
@@ -60,10 +77,18 @@ func (a *Adapter) ResumeCmd(id string) string {
 // ListSessions returns all session IDs sorted by modification time (newest first)
 // This includes both regular sessions and agent sessions (sub-agents spawned by Claude)
 // Agent sessions use a unique ID format: parent-session-id/agent-id to avoid conflicts
-func (a *Adapter) ListSessions() ([]string, error) {
+func (a *Adapter) ListSessions(ctx context.Context) ([]string, error) {
 	var sessions []sessionFile
 
+	// Agent files may have come and gone since the last listing
+	a.agentsMu.Lock()
+	a.agents = nil
+	a.agentsMu.Unlock()
+
 	err := filepath.Walk(a.dataDir, func(path string, info os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 		if err != nil {
 			return nil // Skip errors
 		}
@@ -173,98 +198,37 @@ func (a *Adapter) GetSessionFile(id string) string {
 var ErrNoMessages = fmt.Errorf("session has no messages")
 
 // ExtractMeta extracts metadata from a session for cache building
-func (a *Adapter) ExtractMeta(id string) (*adapters.SessionMeta, error) {
-	path := a.GetSessionFile(id)
-	if path == "" {
-		return nil, os.ErrNotExist
+func (a *Adapter) ExtractMeta(ctx context.Context, id string) (*adapters.SessionMeta, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-
-	info, err := os.Stat(path)
+	s, err := a.LoadSession(id)
 	if err != nil {
 		return nil, err
 	}
 
-	// Extract project from path
-	project := extractProject(path)
-
-	// Parse file for summary and parent session
-	summary := ""
-	parentSID := ""
-	hasMessages := false
-	records, err := a.parseFile(path)
-	if err == nil {
-		// Check if this is an agent session (first record has agentId)
-		isAgent := false
-		for _, r := range records {
-			if r.AgentID != "" {
-				isAgent = true
-				// For agent sessions, the sessionId field points to parent
-				if r.SessionID != "" {
-					parentSID = r.SessionID
-				}
-				break
-			}
-		}
-
-		for _, r := range records {
-			if r.Type == "summary" && summary == "" {
-				summary = r.Summary
-			}
-			// For non-agent sessions, check for branch metadata
-			if !isAgent && r.Type == "branch" && r.ParentSession != "" {
-				parentSID = r.ParentSession
-			}
-			// Check if session has actual messages
-			if r.Type == "user" || r.Type == "assistant" {
-				hasMessages = true
-			}
-		}
-
-		// Fallback to first user message if no summary
-		if summary == "" {
-			summary = extractFirstUserMessage(records)
-		}
-	}
-
 	// Skip metadata-only sessions (no actual conversation)
-	if !hasMessages {
+	if !s.HasMessages {
 		return nil, ErrNoMessages
 	}
 
+	summary := s.Title
+	switch {
+	case s.CustomTitle != "":
+		summary = s.CustomTitle
+	case len(s.Summaries) > 0:
+		summary = s.Summaries[0]
+	}
+
 	return &adapters.SessionMeta{
 		ID:        id,
-		Date:      info.ModTime(),
-		Project:   project,
+		Date:      s.ModTime,
+		Project:   extractProject(s.Path),
 		Summary:   summary,
-		ParentSID: parentSID,
+		ParentSID: s.ParentSID,
 	}, nil
 }
 
-// extractFirstUserMessage finds the first meaningful user message content
-// Falls back to first assistant message if no user message found (for warmup agents)
-func extractFirstUserMessage(records []record) string {
-	// First try to find a user message
-	for _, r := range records {
-		if r.Type == "user" && r.Message.Role == "user" {
-			content := extractTextContent(r.Message.Content)
-			if content != "" {
-				return truncate(content, 100)
-			}
-		}
-	}
-	// Fallback to first assistant message (for warmup/agent sessions without user prompt)
-	for _, r := range records {
-		if r.Type == "assistant" && r.Message.Role == "assistant" {
-			content := extractTextContent(r.Message.Content)
-			if content != "" {
-				// Prefix to indicate this is from assistant
-				return truncate(content, 100)
-			}
-		}
-	}
-	return ""
-}
-
 // extractTextContent extracts text from message content (handles both string and array formats)
 func extractTextContent(content interface{}) string {
 	switch c := content.(type) {
@@ -292,325 +256,210 @@ func extractTextContent(content interface{}) string {
 
 // GetSessionInfo returns detailed session information
 func (a *Adapter) GetSessionInfo(id string) (*adapters.SessionInfo, error) {
-	path := a.GetSessionFile(id)
-	if path == "" {
-		return nil, os.ErrNotExist
-	}
-
-	info, err := os.Stat(path)
+	s, err := a.LoadSession(id)
 	if err != nil {
 		return nil, err
 	}
 
-	project := extractProject(path)
-	branch := ""
-	workDir := ""
-
-	records, err := a.parseFile(path)
-	if err == nil {
-		for _, r := range records {
-			if r.GitBranch != "" && branch == "" {
-				branch = r.GitBranch
-			}
-			if r.Cwd != "" && workDir == "" {
-				workDir = r.Cwd
-			}
-			if branch != "" && workDir != "" {
-				break
-			}
-		}
-	}
-
 	return &adapters.SessionInfo{
 		ID:      id,
-		Project: project,
-		Date:    info.ModTime(),
-		Branch:  branch,
-		WorkDir: workDir,
+		Project: extractProject(s.Path),
+		Date:    s.ModTime,
+		Branch:  s.Branch,
+		WorkDir: s.WorkDir,
 	}, nil
 }
 
 // GetSummaries returns all topic summaries from the session
 func (a *Adapter) GetSummaries(id string) ([]string, error) {
-	path := a.GetSessionFile(id)
-	if path == "" {
-		return nil, os.ErrNotExist
-	}
-
-	records, err := a.parseFile(path)
+	s, err := a.LoadSession(id)
 	if err != nil {
 		return nil, err
 	}
-
-	var summaries []string
-	for _, r := range records {
-		if r.Type == "summary" && r.Summary != "" {
-			summaries = append(summaries, r.Summary)
-		}
-	}
-	return summaries, nil
+	return slices.Clone(s.Summaries), nil
 }
 
-// GetFilesTouched returns files modified in the session
+// GetFilesTouched returns files created, modified or deleted in the session
 func (a *Adapter) GetFilesTouched(id string) ([]string, error) {
-	path := a.GetSessionFile(id)
-	if path == "" {
-		return nil, os.ErrNotExist
-	}
-
-	records, err := a.parseFile(path)
+	activity, err := a.GetFileActivity(context.Background(), id)
 	if err != nil {
 		return nil, err
 	}
+	return adapters.ChangedFiles(activity), nil
+}
 
-	fileSet := make(map[string]bool)
-	for _, r := range records {
-		if r.Type == "assistant" {
-			// Look for tool calls that modify files
-			if content, ok := r.Message.Content.([]interface{}); ok {
-				for _, item := range content {
-					if m, ok := item.(map[string]interface{}); ok {
-						if m["type"] == "tool_use" {
-							name, _ := m["name"].(string)
-							if name == "Edit" || name == "Write" {
-								if input, ok := m["input"].(map[string]interface{}); ok {
-									if fp, ok := input["file_path"].(string); ok {
-										fileSet[fp] = true
-									}
-								}
-							}
-						}
-					}
-				}
-			}
-		}
+// GetFileActivity returns the files read and changed on the active path
+func (a *Adapter) GetFileActivity(ctx context.Context, id string) ([]adapters.FileActivity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-
-	files := make([]string, 0, len(fileSet))
-	for f := range fileSet {
-		files = append(files, f)
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
 	}
-	sort.Strings(files)
-	return files, nil
+	return copyActivity(s.Files), nil
 }
 
 // GetSlashCommands returns slash commands used in the session
 func (a *Adapter) GetSlashCommands(id string) ([]string, error) {
-	path := a.GetSessionFile(id)
-	if path == "" {
-		return nil, os.ErrNotExist
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(s.SlashCommands), nil
+}
+
+// GetModels returns unique model names used in the session
+func (a *Adapter) GetModels(id string) ([]string, error) {
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
 	}
+	return slices.Clone(s.Models), nil
+}
 
-	records, err := a.parseFile(path)
+// GetStats returns session statistics
+func (a *Adapter) GetStats(ctx context.Context, id string) (*adapters.Stats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s, err := a.LoadSession(id)
 	if err != nil {
 		return nil, err
 	}
 
-	cmdSet := make(map[string]bool)
-	cmdRegex := regexp.MustCompile(`<command-name>(/[^<]*)</command-name>`)
+	// Copy so callers can't mutate the memoized session
+	stats := s.Stats
+	stats.ToolCalls = make(map[string]int, len(s.Stats.ToolCalls))
+	for name, n := range s.Stats.ToolCalls {
+		stats.ToolCalls[name] = n
+	}
 
-	for _, r := range records {
-		// Check for command-name tags in user messages
-		if r.Type == "user" {
-			if content, ok := r.Message.Content.(string); ok {
-				matches := cmdRegex.FindAllStringSubmatch(content, -1)
-				for _, m := range matches {
-					if len(m) > 1 {
-						cmdSet[m[1]] = true
-					}
-				}
+	// Subagent transcripts belong to their parent; they have none of their own
+	var subagents []adapters.SubagentStats
+	if !strings.Contains(id, "/") {
+		for _, f := range a.subagentFiles(s.Path, id) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
 			}
-		}
-		// Check for SlashCommand tool calls
-		if r.Type == "assistant" {
-			if content, ok := r.Message.Content.([]interface{}); ok {
-				for _, item := range content {
-					if m, ok := item.(map[string]interface{}); ok {
-						if m["type"] == "tool_use" && m["name"] == "SlashCommand" {
-							if input, ok := m["input"].(map[string]interface{}); ok {
-								if cmd, ok := input["command"].(string); ok {
-									cmdSet[cmd] = true
-								}
-							}
-						}
-					}
-				}
+			sub, err := a.loadPath(f)
+			if err != nil {
+				continue
+			}
+			agentID := strings.TrimSuffix(filepath.Base(f), ".jsonl")
+			if filepath.Base(filepath.Dir(f)) == "subagents" {
+				agentID = id + "/" + agentID
 			}
+			subagents = append(subagents, adapters.SubagentStats{
+				ID:          agentID,
+				Description: truncate(sub.Title, 60),
+				Messages:    sub.Stats.UserMessages + sub.Stats.AssistantMessages,
+				Totals:      sub.Stats.Self(),
+			})
 		}
 	}
-
-	cmds := make([]string, 0, len(cmdSet))
-	for cmd := range cmdSet {
-		cmds = append(cmds, cmd)
-	}
-	sort.Strings(cmds)
-	return cmds, nil
+	stats.Rollup(subagents)
+	return &stats, nil
 }
 
-// GetModels returns unique model names used in the session
-func (a *Adapter) GetModels(id string) ([]string, error) {
-	path := a.GetSessionFile(id)
-	if path == "" {
-		return nil, os.ErrNotExist
+// GetDetails returns the figures the session cache keeps, all from one
+// parse of the session file. Subagents are left out.
+func (a *Adapter) GetDetails(ctx context.Context, id string) (*adapters.SessionDetails, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-
-	records, err := a.parseFile(path)
+	s, err := a.LoadSession(id)
 	if err != nil {
 		return nil, err
 	}
 
-	modelSet := make(map[string]bool)
-	for _, r := range records {
-		if r.Type == "assistant" && r.Message.Model != "" {
-			modelSet[r.Message.Model] = true
-		}
+	d := &adapters.SessionDetails{
+		WorkDir: s.WorkDir,
+		Branch:  s.Branch,
+		Models:  slices.Clone(s.Models),
 	}
+	d.SetStats(&s.Stats)
+	d.CountFiles(s.Files)
+	d.SetSpan(s.Messages)
+	return d, nil
+}
 
-	models := make([]string, 0, len(modelSet))
-	for m := range modelSet {
-		models = append(models, m)
+// copyActivity copies file activity out of a memoized session
+func copyActivity(files []adapters.FileActivity) []adapters.FileActivity {
+	if files == nil {
+		return nil
 	}
-	sort.Strings(models)
-	return models, nil
+	out := make([]adapters.FileActivity, len(files))
+	for i, f := range files {
+		out[i] = f
+		out[i].Ops = maps.Clone(f.Ops)
+	}
+	return out
 }
 
-// GetStats returns session statistics
-func (a *Adapter) GetStats(id string) (*adapters.Stats, error) {
-	path := a.GetSessionFile(id)
-	if path == "" {
-		return nil, os.ErrNotExist
-	}
+// subagentFiles returns the transcripts of a session's subagents: those in
+// its companion directory and, from older Claude versions, agent files next
+// to it that name it as their parent
+func (a *Adapter) subagentFiles(path, id string) []string {
+	dir := filepath.Dir(path)
+	files, _ := filepath.Glob(filepath.Join(dir, id, "subagents", "agent-*.jsonl"))
 
-	records, err := a.parseFile(path)
-	if err != nil {
-		return nil, err
+	a.agentsMu.Lock()
+	if a.agents == nil {
+		a.agents = make(map[string]map[string][]string)
 	}
-
-	stats := &adapters.Stats{
-		ToolCalls: make(map[string]int),
+	byParent, ok := a.agents[dir]
+	if !ok {
+		byParent = legacyAgents(dir)
+		a.agents[dir] = byParent
 	}
+	a.agentsMu.Unlock()
 
-	for _, r := range records {
-		switch r.Type {
-		case "user":
-			if !r.IsMeta {
-				stats.UserMessages++
-			}
-		case "assistant":
-			stats.AssistantMessages++
-			if r.Message.Usage != nil {
-				stats.InputTokens += r.Message.Usage.InputTokens
-				stats.OutputTokens += r.Message.Usage.OutputTokens
-				stats.CacheRead += r.Message.Usage.CacheReadInputTokens
-				stats.CacheWrite += r.Message.Usage.CacheCreationInputTokens
-			}
-			// Count tool calls
-			if content, ok := r.Message.Content.([]interface{}); ok {
-				for _, item := range content {
-					if m, ok := item.(map[string]interface{}); ok {
-						if m["type"] == "tool_use" {
-							name, _ := m["name"].(string)
-							if name != "" {
-								stats.ToolCalls[name]++
-							}
-						}
-					}
-				}
-			}
+	return append(files, byParent[id]...)
+}
+
+// legacyAgents returns the agent transcripts in dir by the session that
+// spawned them. Older Claude versions kept them next to the session.
+func legacyAgents(dir string) map[string][]string {
+	byParent := make(map[string][]string)
+	agents, _ := filepath.Glob(filepath.Join(dir, "agent-*.jsonl"))
+	for _, agent := range agents {
+		if parent := agentParent(agent); parent != "" {
+			byParent[parent] = append(byParent[parent], agent)
 		}
 	}
+	return byParent
+}
 
-	// Calculate cost (approximate)
-	stats.Cost = calculateCost(stats.InputTokens, stats.OutputTokens, stats.CacheRead, stats.CacheWrite)
-
-	return stats, nil
+// GetForks lists the conversation paths inside the session file. Sessions
+// that were never rewound have none.
+func (a *Adapter) GetForks(id string) ([]adapters.Fork, error) {
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(s.Forks), nil
 }
 
 // GetFirstMessage returns the first user message
 func (a *Adapter) GetFirstMessage(id string) (string, error) {
-	path := a.GetSessionFile(id)
-	if path == "" {
-		return "", os.ErrNotExist
-	}
-
-	records, err := a.parseFile(path)
+	s, err := a.LoadSession(id)
 	if err != nil {
 		return "", err
 	}
-
-	for _, r := range records {
-		if r.Type == "user" && !r.IsMeta && r.Message.Role == "user" {
-			content := extractTextContent(r.Message.Content)
-			if content != "" {
-				return truncate(content, 200), nil
-			}
-		}
-	}
-	return "", nil
+	return s.FirstMessage, nil
 }
 
 // ExportMessages returns all messages in normalized format
-func (a *Adapter) ExportMessages(id string) ([]adapters.Message, error) {
-	path := a.GetSessionFile(id)
-	if path == "" {
-		return nil, os.ErrNotExist
+func (a *Adapter) ExportMessages(ctx context.Context, id string) ([]adapters.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-
-	records, err := a.parseFile(path)
+	s, err := a.LoadSession(id)
 	if err != nil {
 		return nil, err
 	}
-
-	var messages []adapters.Message
-	for _, r := range records {
-		if r.Type == "user" || r.Type == "assistant" {
-			if r.IsMeta {
-				continue
-			}
-			msg := adapters.Message{
-				Role:      r.Message.Role,
-				Timestamp: parseTimestamp(r.Timestamp),
-			}
-
-			// Extract content
-			switch c := r.Message.Content.(type) {
-			case string:
-				msg.Content = c
-			case []interface{}:
-				for _, item := range c {
-					if m, ok := item.(map[string]interface{}); ok {
-						switch m["type"] {
-						case "text":
-							if text, ok := m["text"].(string); ok {
-								msg.Content += text
-							}
-						case "tool_use":
-							tc := adapters.ToolCall{
-								ID:   getString(m, "id"),
-								Name: getString(m, "name"),
-							}
-							if input, ok := m["input"]; ok {
-								if b, err := json.Marshal(input); err == nil {
-									tc.Input = string(b)
-								}
-							}
-							msg.ToolCalls = append(msg.ToolCalls, tc)
-						case "tool_result":
-							tr := adapters.ToolResult{
-								ToolUseID: getString(m, "tool_use_id"),
-								Content:   getString(m, "content"),
-							}
-							msg.ToolResults = append(msg.ToolResults, tr)
-						}
-					}
-				}
-			}
-
-			messages = append(messages, msg)
-		}
-	}
-
-	return messages, nil
+	return adapters.CopyMessages(s.Messages), nil
 }
 
 // Internal types for parsing
@@ -618,6 +467,7 @@ func (a *Adapter) ExportMessages(id string) ([]adapters.Message, error) {
 type record struct {
 	Type          string  `json:"type"`
 	Summary       string  `json:"summary,omitempty"`
+	CustomTitle   string  `json:"customTitle,omitempty"`
 	Message       message `json:"message,omitempty"`
 	Cwd           string  `json:"cwd,omitempty"`
 	GitBranch     string  `json:"gitBranch,omitempty"`
@@ -626,6 +476,10 @@ type record struct {
 	ParentSession string  `json:"parentSession,omitempty"` // For branch metadata
 	SessionID     string  `json:"sessionId,omitempty"`     // Parent session ID (for agent sessions)
 	AgentID       string  `json:"agentId,omitempty"`       // Agent ID (for agent sessions)
+	UUID          string  `json:"uuid,omitempty"`
+	ParentUUID    string  `json:"parentUuid,omitempty"`
+	LogicalParent string  `json:"logicalParentUuid,omitempty"` // Parent across a compaction boundary
+	IsSidechain   bool    `json:"isSidechain,omitempty"`
 }
 
 type message struct {
@@ -642,28 +496,6 @@ type usage struct {
 	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
 }
 
-func (a *Adapter) parseFile(path string) ([]record, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	var records []record
-	scanner := bufio.NewScanner(f)
-	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024) // 10MB max line
-
-	for scanner.Scan() {
-		var r record
-		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
-			continue // Skip malformed lines
-		}
-		records = append(records, r)
-	}
-
-	return records, scanner.Err()
-}
-
 // Helper functions
 
 func extractProject(path string) string {
@@ -706,67 +538,94 @@ func getString(m map[string]interface{}, key string) string {
 	return ""
 }
 
-func calculateCost(input, output, cacheRead, cacheWrite int) float64 {
-	// Sonnet 3.5 pricing (per 1M tokens)
-	inputPrice := 3.0
-	outputPrice := 15.0
-	cacheReadPrice := 0.30
-	cacheWritePrice := 3.75
-
-	cost := float64(input) * inputPrice / 1_000_000
-	cost += float64(output) * outputPrice / 1_000_000
-	cost += float64(cacheRead) * cacheReadPrice / 1_000_000
-	cost += float64(cacheWrite) * cacheWritePrice / 1_000_000
-
-	return cost
-}
-
-// BranchSession creates a copy of a session for branching
-func (a *Adapter) BranchSession(id string) (string, error) {
-	originalPath := a.GetSessionFile(id)
-	if originalPath == "" {
-		return "", os.ErrNotExist
+// RenameSession appends a custom-title record, which takes precedence over
+// summaries. The file's modification time is kept so renaming doesn't
+// move the session to the top of the list.
+func (a *Adapter) RenameSession(id, title string) error {
+	path := a.GetSessionFile(id)
+	if path == "" {
+		return os.ErrNotExist
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
 	}
 
-	// Generate new UUID
-	newID := generateUUID()
-
-	// New file in same directory
-	dir := filepath.Dir(originalPath)
-	newPath := filepath.Join(dir, newID+".jsonl")
+	line, err := json.Marshal(map[string]string{
+		"type":        "custom-title",
+		"customTitle": title,
+		"sessionId":   strings.SplitN(id, "/", 2)[0],
+	})
+	if err != nil {
+		return err
+	}
 
-	// Read original content
-	content, err := os.ReadFile(originalPath)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0)
 	if err != nil {
-		return "", err
+		return err
+	}
+	// Start on a fresh line if the last record wasn't terminated
+	if info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			line = append([]byte{'\n'}, line...)
+		}
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
 	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(path, info.ModTime(), info.ModTime())
+}
 
-	// Create branch metadata
-	branchMeta := map[string]interface{}{
-		"type":          "branch",
-		"parentSession": id,
-		"branchedAt":    time.Now().UTC().Format(time.RFC3339),
+// SessionFiles returns the session file together with its companion
+// directory (subagent transcripts, tool results) and any legacy agent files
+// that name the session as their parent
+func (a *Adapter) SessionFiles(id string) ([]string, error) {
+	path := a.GetSessionFile(id)
+	if path == "" {
+		return nil, os.ErrNotExist
 	}
-	metaJSON, _ := json.Marshal(branchMeta)
+	files := []string{path}
 
-	// Write new file with branch metadata prepended
-	newContent := append(metaJSON, '\n')
-	newContent = append(newContent, content...)
+	// A subagent transcript belongs to its parent; only the file itself goes
+	if strings.Contains(id, "/") {
+		return files, nil
+	}
 
-	if err := os.WriteFile(newPath, newContent, 0644); err != nil {
-		return "", err
+	dir := filepath.Dir(path)
+	if info, err := os.Stat(filepath.Join(dir, id)); err == nil && info.IsDir() {
+		files = append(files, filepath.Join(dir, id))
 	}
 
-	// Touch the file to ensure proper mtime
-	now := time.Now()
-	os.Chtimes(newPath, now, now)
+	// Read the agents afresh; what goes to the trash must be current
+	return append(files, legacyAgents(dir)[id]...), nil
+}
 
-	// Cache the new session path (write lock)
-	a.pathsMu.Lock()
-	a.sessionPaths[newID] = newPath
-	a.pathsMu.Unlock()
+// agentParent returns the parent session of an agent transcript, read
+// from the first record that carries an agent ID
+func agentParent(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
 
-	return newID, nil
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024) // 10MB max line
+	for i := 0; i < 10 && scanner.Scan(); i++ {
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		if r.AgentID != "" {
+			return r.SessionID
+		}
+	}
+	return ""
 }
 
 // generateUUID creates a random UUID v4
@@ -1,6 +1,7 @@
 package claude
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -12,7 +13,7 @@ func BenchmarkListSessions(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := a.ListSessions()
+		_, err := a.ListSessions(context.Background())
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -39,7 +40,7 @@ func BenchmarkExtractMeta(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := a.ExtractMeta("test-session")
+		_, err := a.ExtractMeta(context.Background(), "test-session")
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -84,7 +85,7 @@ func BenchmarkRealWorld_ListSessions(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		sessions, err := a.ListSessions()
+		sessions, err := a.ListSessions(context.Background())
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -103,7 +104,7 @@ func BenchmarkRealWorld_GetSessionFile(b *testing.B) {
 	a := New(dataDir)
 
 	// Get a real session ID
-	sessions, err := a.ListSessions()
+	sessions, err := a.ListSessions(context.Background())
 	if err != nil || len(sessions) == 0 {
 		b.Skip("No sessions found")
 	}
@@ -128,7 +129,7 @@ func BenchmarkRealWorld_ExtractMeta(b *testing.B) {
 
 	a := New(dataDir)
 
-	sessions, err := a.ListSessions()
+	sessions, err := a.ListSessions(context.Background())
 	if err != nil || len(sessions) == 0 {
 		b.Skip("No sessions found")
 	}
@@ -136,7 +137,7 @@ func BenchmarkRealWorld_ExtractMeta(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := a.ExtractMeta(sessionID)
+		_, err := a.ExtractMeta(context.Background(), sessionID)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -156,7 +157,7 @@ func BenchmarkRealWorld_FullLoad(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		sessions, err := a.ListSessions()
+		sessions, err := a.ListSessions(context.Background())
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -167,7 +168,7 @@ func BenchmarkRealWorld_FullLoad(b *testing.B) {
 			if path == "" {
 				continue
 			}
-			_, _ = a.ExtractMeta(id)
+			_, _ = a.ExtractMeta(context.Background(), id)
 		}
 
 		b.ReportMetric(float64(len(sessions)), "sessions")
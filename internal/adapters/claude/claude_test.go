@@ -1,10 +1,17 @@
 package claude
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
 )
 
 func testDataDir(t *testing.T) string {
@@ -35,7 +42,7 @@ func TestNew(t *testing.T) {
 func TestListSessions(t *testing.T) {
 	a := setupTestAdapter(t)
 
-	sessions, err := a.ListSessions()
+	sessions, err := a.ListSessions(context.Background())
 	if err != nil {
 		t.Fatalf("ListSessions() error = %v", err)
 	}
@@ -57,6 +64,20 @@ func TestListSessions(t *testing.T) {
 	}
 }
 
+func TestListSessions_Cancelled(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := a.ListSessions(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListSessions() error = %v, want context.Canceled", err)
+	}
+	if _, err := a.ExtractMeta(ctx, "test-session"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ExtractMeta() error = %v, want context.Canceled", err)
+	}
+}
+
 func TestGetSessionFile(t *testing.T) {
 	a := setupTestAdapter(t)
 
@@ -72,7 +93,7 @@ func TestGetSessionFile(t *testing.T) {
 func TestExtractMeta(t *testing.T) {
 	a := setupTestAdapter(t)
 
-	meta, err := a.ExtractMeta("test-session")
+	meta, err := a.ExtractMeta(context.Background(), "test-session")
 	if err != nil {
 		t.Fatalf("ExtractMeta() error = %v", err)
 	}
@@ -88,7 +109,7 @@ func TestExtractMeta(t *testing.T) {
 func TestExtractMeta_NoSummary(t *testing.T) {
 	a := setupTestAdapter(t)
 
-	meta, err := a.ExtractMeta("minimal-session")
+	meta, err := a.ExtractMeta(context.Background(), "minimal-session")
 	if err != nil {
 		t.Fatalf("ExtractMeta() error = %v", err)
 	}
@@ -150,10 +171,55 @@ func TestGetFilesTouched(t *testing.T) {
 	}
 }
 
+func TestGetFileActivity(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "p")
+	os.MkdirAll(projectDir, 0755)
+
+	content := `{"type":"user","sessionId":"s1","message":{"role":"user","content":"Tidy up"}}
+{"type":"assistant","sessionId":"s1","message":{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/a.go"}},{"type":"tool_use","id":"t2","name":"Write","input":{"file_path":"/new.go","content":"package a"}},{"type":"tool_use","id":"t3","name":"Edit","input":{"file_path":"/b.go"}}]}}
+{"type":"user","sessionId":"s1","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"package a"},{"type":"tool_result","tool_use_id":"t2","content":"File created successfully at: /new.go"},{"type":"tool_result","tool_use_id":"t3","content":"String not found","is_error":true}]}}
+{"type":"assistant","sessionId":"s1","message":{"role":"assistant","content":[{"type":"tool_use","id":"t4","name":"MultiEdit","input":{"file_path":"/a.go","edits":[]}},{"type":"tool_use","id":"t5","name":"NotebookEdit","input":{"notebook_path":"/n.ipynb"}},{"type":"tool_use","id":"t6","name":"Write","input":{"file_path":"/a.go"}}]}}
+`
+	os.WriteFile(filepath.Join(projectDir, "s1.jsonl"), []byte(content), 0644)
+	a := New(tmpDir)
+
+	activity, err := a.GetFileActivity(context.Background(), "s1")
+	if err != nil {
+		t.Fatalf("GetFileActivity() error = %v", err)
+	}
+	got := make(map[string]adapters.FileActivity)
+	for _, f := range activity {
+		got[f.Path] = f
+	}
+	if len(got) != 3 {
+		t.Fatalf("GetFileActivity() = %+v, want a.go, new.go and n.ipynb", activity)
+	}
+
+	if f := got["/a.go"]; f.Ops[adapters.FileRead] != 1 || f.Ops[adapters.FileModify] != 2 || f.FirstTurn != 1 || f.LastTurn != 3 {
+		t.Errorf("a.go = %+v, want read, then MultiEdit and Write as modifies over turns 1-3", f)
+	}
+	if f := got["/new.go"]; f.Ops[adapters.FileCreate] != 1 || f.Op() != adapters.FileCreate {
+		t.Errorf("new.go = %+v, want created", f)
+	}
+	if f := got["/n.ipynb"]; f.Ops[adapters.FileModify] != 1 {
+		t.Errorf("n.ipynb = %+v, want modified by NotebookEdit", f)
+	}
+
+	// The failed Edit never touched b.go
+	files, err := a.GetFilesTouched("s1")
+	if err != nil {
+		t.Fatalf("GetFilesTouched() error = %v", err)
+	}
+	if strings.Join(files, ",") != "/a.go,/n.ipynb,/new.go" {
+		t.Errorf("GetFilesTouched() = %v, want the changed files", files)
+	}
+}
+
 func TestGetStats(t *testing.T) {
 	a := setupTestAdapter(t)
 
-	stats, err := a.GetStats("test-session")
+	stats, err := a.GetStats(context.Background(), "test-session")
 	if err != nil {
 		t.Fatalf("GetStats() error = %v", err)
 	}
@@ -185,6 +251,150 @@ func TestGetStats(t *testing.T) {
 	}
 }
 
+func TestGetStats_PricesEachTurnWithItsModel(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "p")
+	os.MkdirAll(projectDir, 0755)
+
+	content := `{"type":"user","sessionId":"s1","message":{"role":"user","content":"Plan it"}}
+{"type":"assistant","sessionId":"s1","message":{"role":"assistant","model":"claude-opus-4-1-20250805","content":[{"type":"text","text":"Plan"}],"usage":{"input_tokens":1000000,"output_tokens":0}}}
+{"type":"user","sessionId":"s1","message":{"role":"user","content":"Do it"}}
+{"type":"assistant","sessionId":"s1","message":{"role":"assistant","model":"claude-haiku-4-5-20251001","content":[{"type":"text","text":"Done"}],"usage":{"input_tokens":1000000,"output_tokens":0}}}
+`
+	os.WriteFile(filepath.Join(projectDir, "s1.jsonl"), []byte(content), 0644)
+
+	stats, err := New(tmpDir).GetStats(context.Background(), "s1")
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	// $15 for the Opus turn plus $1 for the Haiku one
+	if stats.Cost < 15.9999 || stats.Cost > 16.0001 {
+		t.Errorf("Cost = %f, want 16", stats.Cost)
+	}
+}
+
+func TestGetStats_Subagents(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "p")
+	os.MkdirAll(filepath.Join(projectDir, "s1", "subagents"), 0755)
+
+	assistant := func(sid, agent string, in, out int) string {
+		agentField := ""
+		if agent != "" {
+			agentField = `"agentId":"` + agent + `",`
+		}
+		return fmt.Sprintf(`{"type":"user","sessionId":"%s",%s"message":{"role":"user","content":"Task for %s"}}
+{"type":"assistant","sessionId":"%s",%s"message":{"role":"assistant","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":%d,"output_tokens":%d}}}
+`, sid, agentField, agent, sid, agentField, in, out)
+	}
+	os.WriteFile(filepath.Join(projectDir, "s1.jsonl"), []byte(assistant("s1", "", 1000, 100)), 0644)
+	os.WriteFile(filepath.Join(projectDir, "s1", "subagents", "agent-a.jsonl"), []byte(assistant("s1", "a", 200, 20)), 0644)
+	os.WriteFile(filepath.Join(projectDir, "agent-b.jsonl"), []byte(assistant("s1", "b", 3000, 300)), 0644)
+	os.WriteFile(filepath.Join(projectDir, "agent-c.jsonl"), []byte(assistant("other", "c", 50, 5)), 0644)
+
+	a := New(tmpDir)
+	stats, err := a.GetStats(context.Background(), "s1")
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+
+	if stats.InputTokens != 1000 {
+		t.Errorf("InputTokens = %d, want the session's own 1000", stats.InputTokens)
+	}
+	if len(stats.Subagents) != 2 {
+		t.Fatalf("Subagents = %+v, want agent-b and s1/agent-a", stats.Subagents)
+	}
+	if stats.Subagents[0].ID != "agent-b" || stats.Subagents[1].ID != "s1/agent-a" {
+		t.Errorf("Subagents = %+v, want the legacy agent-b (costlier) then s1/agent-a", stats.Subagents)
+	}
+	if stats.Subagents[1].Description != "Task for a" || stats.Subagents[1].Messages != 2 {
+		t.Errorf("subagent = %+v", stats.Subagents[1])
+	}
+	if stats.Inclusive.InputTokens != 1000+200+3000 || stats.Inclusive.OutputTokens != 100+20+300 {
+		t.Errorf("Inclusive = %+v, want the session plus both subagents", stats.Inclusive)
+	}
+	if stats.Inclusive.Cost <= stats.Cost {
+		t.Errorf("Inclusive.Cost = %v, want more than the session's own %v", stats.Inclusive.Cost, stats.Cost)
+	}
+
+	// A subagent's own stats have nothing to roll up
+	stats, err = a.GetStats(context.Background(), "s1/agent-a")
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if len(stats.Subagents) != 0 || stats.Inclusive != stats.Self() {
+		t.Errorf("subagent stats = %+v, want no rollup", stats)
+	}
+}
+
+func TestGetStats_LegacyAgentsReadOncePerListing(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "p")
+	os.MkdirAll(projectDir, 0755)
+
+	agent := func(sid, agent string) string {
+		return fmt.Sprintf(`{"type":"user","sessionId":"%s","agentId":"%s","message":{"role":"user","content":"Task"}}
+{"type":"assistant","sessionId":"%s","agentId":"%s","message":{"role":"assistant","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":10,"output_tokens":1}}}
+`, sid, agent, sid, agent)
+	}
+	for _, sid := range []string{"s1", "s2"} {
+		os.WriteFile(filepath.Join(projectDir, sid+".jsonl"), []byte(`{"type":"user","sessionId":"`+sid+`","message":{"role":"user","content":"Hi"}}`+"\n"), 0644)
+	}
+	os.WriteFile(filepath.Join(projectDir, "agent-a.jsonl"), []byte(agent("s1", "a")), 0644)
+
+	a := New(tmpDir)
+	ctx := context.Background()
+	a.ListSessions(ctx)
+	if stats, _ := a.GetStats(ctx, "s1"); len(stats.Subagents) != 1 {
+		t.Fatalf("s1 Subagents = %+v, want agent-a", stats.Subagents)
+	}
+
+	// The agent files of a directory are read once until the next listing
+	os.WriteFile(filepath.Join(projectDir, "agent-b.jsonl"), []byte(agent("s2", "b")), 0644)
+	if stats, _ := a.GetStats(ctx, "s2"); len(stats.Subagents) != 0 {
+		t.Errorf("s2 Subagents = %+v, want none before relisting", stats.Subagents)
+	}
+	a.ListSessions(ctx)
+	if stats, _ := a.GetStats(ctx, "s2"); len(stats.Subagents) != 1 {
+		t.Errorf("s2 Subagents = %+v, want agent-b after relisting", stats.Subagents)
+	}
+}
+
+func TestGetDetails(t *testing.T) {
+	a := setupTestAdapter(t)
+	ctx := context.Background()
+
+	d, err := a.GetDetails(ctx, "test-session")
+	if err != nil {
+		t.Fatalf("GetDetails() error = %v", err)
+	}
+	stats, _ := a.GetStats(ctx, "test-session")
+	if d.UserMessages != stats.UserMessages || d.AssistantMessages != stats.AssistantMessages || d.Usage != stats.Self() {
+		t.Errorf("GetDetails() = %+v, want the counts and usage of GetStats", d)
+	}
+	if d.WorkDir != "/Users/test/projects/my-app" || d.Branch != "main" {
+		t.Errorf("WorkDir, Branch = %q, %q", d.WorkDir, d.Branch)
+	}
+	if d.FilesChanged != 1 {
+		t.Errorf("FilesChanged = %d, want 1", d.FilesChanged)
+	}
+
+	messages, _ := a.ExportMessages(ctx, "test-session")
+	var want adapters.SessionDetails
+	want.SetSpan(messages)
+	if d.Start.IsZero() || !d.Start.Equal(want.Start) || !d.End.Equal(want.End) {
+		t.Errorf("Start, End = %v, %v, want %v, %v", d.Start, d.End, want.Start, want.End)
+	}
+
+	// The models are a copy of the memoized ones
+	if len(d.Models) > 0 {
+		d.Models[0] = "changed"
+		if models, _ := a.GetModels("test-session"); models[0] == "changed" {
+			t.Error("GetDetails() shares Models with the memoized session")
+		}
+	}
+}
+
 func TestGetFirstMessage(t *testing.T) {
 	a := setupTestAdapter(t)
 
@@ -202,7 +412,7 @@ func TestGetFirstMessage(t *testing.T) {
 func TestExportMessages(t *testing.T) {
 	a := setupTestAdapter(t)
 
-	messages, err := a.ExportMessages("test-session")
+	messages, err := a.ExportMessages(context.Background(), "test-session")
 	if err != nil {
 		t.Fatalf("ExportMessages() error = %v", err)
 	}
@@ -233,6 +443,142 @@ func TestExportMessages(t *testing.T) {
 	}
 }
 
+func TestExportMessages_Thinking(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "p")
+	os.MkdirAll(projectDir, 0755)
+
+	content := `{"type":"user","sessionId":"s1","message":{"role":"user","content":"Why does it fail?"}}
+{"type":"assistant","sessionId":"s1","message":{"role":"assistant","content":[{"type":"thinking","thinking":"The test uses a stale fixture.","signature":"abc"},{"type":"thinking","thinking":"Check the loader too."},{"type":"text","text":"The fixture is stale."}]}}
+`
+	os.WriteFile(filepath.Join(projectDir, "s1.jsonl"), []byte(content), 0644)
+
+	messages, err := New(tmpDir).ExportMessages(context.Background(), "s1")
+	if err != nil {
+		t.Fatalf("ExportMessages() error = %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("ExportMessages() returned %d messages, want 2", len(messages))
+	}
+	if messages[1].Thinking != "The test uses a stale fixture.\n\nCheck the loader too." {
+		t.Errorf("Thinking = %q, want both thinking blocks", messages[1].Thinking)
+	}
+	if messages[1].Content != "The fixture is stale." {
+		t.Errorf("Content = %q, want only the text block", messages[1].Content)
+	}
+}
+
+func TestExportMessages_Blocks(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "p")
+	os.MkdirAll(projectDir, 0755)
+
+	content := `{"type":"user","sessionId":"s1","message":{"role":"user","content":[{"type":"text","text":"What's on screen?"},{"type":"image","source":{"type":"base64","media_type":"image/png","data":"iVBORw0KGgo="}}]}}
+{"type":"assistant","sessionId":"s1","message":{"role":"assistant","content":[{"type":"text","text":"Let me check."},{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/a.go"}},{"type":"text","text":"And the other one."},{"type":"tool_use","id":"t2","name":"Read","input":{"file_path":"/b.go"}}]}}
+{"type":"user","sessionId":"s1","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":[{"type":"text","text":"package a"},{"type":"text","text":"func A() {}"}]},{"type":"tool_result","tool_use_id":"t2","content":"no such file","is_error":true}]}}
+`
+	os.WriteFile(filepath.Join(projectDir, "s1.jsonl"), []byte(content), 0644)
+
+	messages, err := New(tmpDir).ExportMessages(context.Background(), "s1")
+	if err != nil {
+		t.Fatalf("ExportMessages() error = %v", err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("ExportMessages() returned %d messages, want 3", len(messages))
+	}
+
+	if b := messages[0].Blocks; len(b) != 2 || b[1].Kind != adapters.BlockImage || b[1].MediaType != "image/png" || b[1].Data != "iVBORw0KGgo=" {
+		t.Errorf("user blocks = %+v, want text then image", b)
+	}
+
+	var kinds []adapters.BlockKind
+	for _, b := range messages[1].Blocks {
+		kinds = append(kinds, b.Kind)
+	}
+	want := []adapters.BlockKind{adapters.BlockText, adapters.BlockToolUse, adapters.BlockText, adapters.BlockToolUse}
+	if fmt.Sprint(kinds) != fmt.Sprint(want) {
+		t.Errorf("assistant block kinds = %v, want %v", kinds, want)
+	}
+
+	results := messages[2].Blocks
+	if len(results) != 2 {
+		t.Fatalf("result blocks = %+v, want 2", results)
+	}
+	if results[0].Text != "package a\nfunc A() {}" || results[0].IsError {
+		t.Errorf("array result = %+v, want its text joined", results[0])
+	}
+	if results[1].Text != "no such file" || !results[1].IsError {
+		t.Errorf("error result = %+v, want is_error set", results[1])
+	}
+	if tr := messages[2].ToolResults; len(tr) != 2 || tr[0].Content != "package a\nfunc A() {}" || !tr[0].Success || tr[1].Success {
+		t.Errorf("ToolResults = %+v, want flattened results with success flags", tr)
+	}
+}
+
+func TestExportMessages_FollowsActiveLeaf(t *testing.T) {
+	a := setupForked(t)
+	messages, err := a.ExportMessages(context.Background(), "forked")
+	if err != nil {
+		t.Fatalf("ExportMessages() error = %v", err)
+	}
+	var ids []string
+	for _, m := range messages {
+		ids = append(ids, m.ID)
+	}
+	if strings.Join(ids, ",") != "u1,a1,u3,a3" {
+		t.Errorf("ExportMessages() ids = %v, want the path to the last message", ids)
+	}
+}
+
+func TestExportMessages_AcrossCompaction(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "p"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "p", "s.jsonl"), []byte(
+		`{"type":"user","uuid":"u1","parentUuid":null,"message":{"role":"user","content":"Before"}}
+{"type":"assistant","uuid":"a1","parentUuid":"u1","message":{"role":"assistant","content":[{"type":"text","text":"Reply"}]}}
+{"type":"system","subtype":"compact_boundary","uuid":"c1","parentUuid":null,"logicalParentUuid":"a1"}
+{"type":"user","uuid":"u2","parentUuid":"c1","message":{"role":"user","content":"After"}}
+{"type":"assistant","uuid":"s1","parentUuid":"u2","isSidechain":true,"message":{"role":"assistant","content":[{"type":"text","text":"Agent"}]}}
+`), 0644)
+
+	messages, err := New(tmpDir).ExportMessages(context.Background(), "s")
+	if err != nil {
+		t.Fatalf("ExportMessages() error = %v", err)
+	}
+	var got []string
+	for _, m := range messages {
+		got = append(got, m.Content)
+	}
+	if strings.Join(got, "|") != "Before|Reply|After" {
+		t.Errorf("ExportMessages() = %q, want the history before the compaction and no sidechain", got)
+	}
+}
+
+func TestGetForks(t *testing.T) {
+	a := setupForked(t)
+	forks, err := a.GetForks("forked")
+	if err != nil {
+		t.Fatalf("GetForks() error = %v", err)
+	}
+	if len(forks) != 2 {
+		t.Fatalf("GetForks() returned %d forks, want 2: %+v", len(forks), forks)
+	}
+
+	old, active := forks[0], forks[1]
+	if old.LeafID != "a2" || old.Active || old.Messages != 4 || old.Shared != 2 || old.First != "Delete everything" {
+		t.Errorf("abandoned fork = %+v", old)
+	}
+	if active.LeafID != "a3" || !active.Active || active.Shared != 4 || active.First != "" {
+		t.Errorf("active fork = %+v", active)
+	}
+
+	// A linear session has no forks
+	forks, err = setupTestAdapter(t).GetForks("test-session")
+	if err != nil || forks != nil {
+		t.Errorf("GetForks(linear) = %+v, %v; want none", forks, err)
+	}
+}
+
 func TestResumeCmd(t *testing.T) {
 	a := New("")
 	cmd := a.ResumeCmd("abc123")
@@ -333,6 +679,93 @@ func TestBranchSession_NotFound(t *testing.T) {
 	}
 }
 
+func TestRenameSession(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "test-project")
+	os.MkdirAll(projectDir, 0755)
+
+	// No trailing newline: the record must still start on its own line
+	path := filepath.Join(projectDir, "s1.jsonl")
+	os.WriteFile(path, []byte(`{"type":"summary","summary":"Auto summary"}
+{"type":"user","message":{"role":"user","content":"Hello"}}`), 0644)
+	old := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	os.Chtimes(path, old, old)
+
+	a := New(tmpDir)
+	if err := a.RenameSession("s1", "First name"); err != nil {
+		t.Fatalf("RenameSession() error = %v", err)
+	}
+	if err := a.RenameSession("s1", "Better name"); err != nil {
+		t.Fatalf("RenameSession() error = %v", err)
+	}
+
+	meta, err := a.ExtractMeta(context.Background(), "s1")
+	if err != nil {
+		t.Fatalf("ExtractMeta() error = %v", err)
+	}
+	if meta.Summary != "Better name" {
+		t.Errorf("Summary = %q, want the latest custom title", meta.Summary)
+	}
+
+	info, _ := os.Stat(path)
+	if !info.ModTime().Equal(old) {
+		t.Errorf("mtime = %v, want it unchanged", info.ModTime())
+	}
+	messages, _ := a.ExportMessages(context.Background(), "s1")
+	if len(messages) != 1 {
+		t.Errorf("ExportMessages() returned %d messages, want the original 1", len(messages))
+	}
+
+	if err := a.RenameSession("missing", "x"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("RenameSession(missing) error = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestSessionFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "test-project")
+	subagentDir := filepath.Join(projectDir, "parent-session", "subagents")
+	os.MkdirAll(subagentDir, 0755)
+
+	write := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(projectDir, "parent-session.jsonl"), `{"type":"user","message":{"role":"user","content":"Hi"}}`)
+	write(filepath.Join(subagentDir, "agent-new.jsonl"), `{"type":"user","sessionId":"parent-session","agentId":"new"}`)
+	write(filepath.Join(projectDir, "agent-legacy.jsonl"), `{"type":"user","sessionId":"parent-session","agentId":"legacy"}`)
+	write(filepath.Join(projectDir, "agent-other.jsonl"), `{"type":"user","sessionId":"other-session","agentId":"other"}`)
+
+	a := New(tmpDir)
+
+	files, err := a.SessionFiles("parent-session")
+	if err != nil {
+		t.Fatalf("SessionFiles() error = %v", err)
+	}
+	want := []string{
+		filepath.Join(projectDir, "parent-session.jsonl"),
+		filepath.Join(projectDir, "parent-session"),
+		filepath.Join(projectDir, "agent-legacy.jsonl"),
+	}
+	if strings.Join(files, "\n") != strings.Join(want, "\n") {
+		t.Errorf("SessionFiles() = %v, want %v", files, want)
+	}
+
+	// A subagent on its own is just its transcript
+	files, err = a.SessionFiles("parent-session/agent-new")
+	if err != nil {
+		t.Fatalf("SessionFiles(subagent) error = %v", err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "agent-new.jsonl" {
+		t.Errorf("SessionFiles(subagent) = %v, want the agent file only", files)
+	}
+
+	if _, err := a.SessionFiles("missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("SessionFiles(missing) error = %v, want os.ErrNotExist", err)
+	}
+}
+
 func TestGenerateUUID(t *testing.T) {
 	id1 := generateUUID()
 	id2 := generateUUID()
@@ -353,7 +786,7 @@ func TestListSessions_PopulatesPathCache(t *testing.T) {
 	a := setupTestAdapter(t)
 
 	// First call should populate cache
-	sessions, err := a.ListSessions()
+	sessions, err := a.ListSessions(context.Background())
 	if err != nil {
 		t.Fatalf("ListSessions() error = %v", err)
 	}
@@ -372,7 +805,7 @@ func TestGetSessionFile_UsesCache(t *testing.T) {
 	a := setupTestAdapter(t)
 
 	// Populate cache
-	a.ListSessions()
+	a.ListSessions(context.Background())
 
 	// Multiple calls should return same result (from cache)
 	path1 := a.GetSessionFile("test-session")
@@ -404,7 +837,7 @@ func TestPathCache_ThreadSafety(t *testing.T) {
 	a := setupTestAdapter(t)
 
 	// Populate cache first
-	sessions, _ := a.ListSessions()
+	sessions, _ := a.ListSessions(context.Background())
 	if len(sessions) == 0 {
 		t.Skip("No sessions for concurrency test")
 	}
@@ -422,3 +855,205 @@ func TestPathCache_ThreadSafety(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+// Test that repeated accessors share a single parse
+func TestLoadSession_Memoized(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	s1, err := a.LoadSession("test-session")
+	if err != nil {
+		t.Fatalf("LoadSession() error = %v", err)
+	}
+	s2, err := a.LoadSession("test-session")
+	if err != nil {
+		t.Fatalf("LoadSession() error = %v", err)
+	}
+
+	if s1 != s2 {
+		t.Error("LoadSession() parsed the file twice for an unchanged session")
+	}
+}
+
+// Test that the memo is invalidated when the file changes
+func TestAccessors_ReturnCopies(t *testing.T) {
+	a := setupTestAdapter(t)
+	ctx := context.Background()
+
+	messages, _ := a.ExportMessages(ctx, "test-session")
+	messages[0].Content = "changed"
+	for _, m := range messages {
+		for i := range m.Blocks {
+			m.Blocks[i].Text = "changed"
+		}
+		for i := range m.ToolCalls {
+			m.ToolCalls[i].Name = "changed"
+		}
+	}
+	summaries, _ := a.GetSummaries("test-session")
+	summaries[0] = "changed"
+	files, _ := a.GetFileActivity(ctx, "test-session")
+	for _, f := range files {
+		f.Ops[adapters.FileDelete] = 9
+	}
+
+	s, _ := a.LoadSession("test-session")
+	if s.Messages[0].Content == "changed" || s.Summaries[0] == "changed" {
+		t.Error("changes to returned slices reached the memoized session")
+	}
+	for _, m := range s.Messages {
+		for _, b := range m.Blocks {
+			if b.Text == "changed" {
+				t.Errorf("changes to returned blocks reached the memoized session: %+v", b)
+			}
+		}
+		for _, c := range m.ToolCalls {
+			if c.Name == "changed" {
+				t.Errorf("changes to returned tool calls reached the memoized session: %+v", c)
+			}
+		}
+	}
+	for _, f := range s.Files {
+		if f.Ops[adapters.FileDelete] == 9 {
+			t.Errorf("changes to returned file activity reached the memoized session: %+v", f)
+		}
+	}
+}
+
+func TestLoadSession_ReparsesOnChange(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "test-project")
+	os.MkdirAll(projectDir, 0755)
+
+	path := filepath.Join(projectDir, "changing.jsonl")
+	os.WriteFile(path, []byte(`{"type":"user","message":{"role":"user","content":"First"}}`+"\n"), 0644)
+
+	a := New(tmpDir)
+	s1, err := a.LoadSession("changing")
+	if err != nil {
+		t.Fatalf("LoadSession() error = %v", err)
+	}
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"type":"summary","summary":"Appended"}` + "\n")
+	f.Close()
+
+	s2, err := a.LoadSession("changing")
+	if err != nil {
+		t.Fatalf("LoadSession() error = %v", err)
+	}
+
+	if s1 == s2 {
+		t.Fatal("LoadSession() returned stale session after file changed")
+	}
+	if len(s2.Summaries) != 1 || s2.Summaries[0] != "Appended" {
+		t.Errorf("Summaries = %v, want [Appended]", s2.Summaries)
+	}
+}
+
+func TestGetSlashCommands(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "test-project")
+	os.MkdirAll(projectDir, 0755)
+
+	content := `{"type":"user","message":{"role":"user","content":"<command-name>/review</command-name>"}}
+{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"SlashCommand","input":{"command":"/commit"}}]}}
+`
+	os.WriteFile(filepath.Join(projectDir, "cmds.jsonl"), []byte(content), 0644)
+
+	a := New(tmpDir)
+	cmds, err := a.GetSlashCommands("cmds")
+	if err != nil {
+		t.Fatalf("GetSlashCommands() error = %v", err)
+	}
+
+	if len(cmds) != 2 || cmds[0] != "/commit" || cmds[1] != "/review" {
+		t.Errorf("GetSlashCommands() = %v, want [/commit /review]", cmds)
+	}
+}
+
+func TestStreamMessages_MatchesExport(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	exported, err := a.ExportMessages(context.Background(), "test-session")
+	if err != nil {
+		t.Fatalf("ExportMessages() error = %v", err)
+	}
+
+	var streamed []adapters.Message
+	err = a.StreamMessages(context.Background(), "test-session", func(m adapters.Message) error {
+		streamed = append(streamed, m)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamMessages() error = %v", err)
+	}
+
+	if len(streamed) != len(exported) {
+		t.Fatalf("streamed %d messages, exported %d", len(streamed), len(exported))
+	}
+	for i := range exported {
+		if streamed[i].Role != exported[i].Role || streamed[i].Content != exported[i].Content {
+			t.Errorf("message %d differs: %+v vs %+v", i, streamed[i], exported[i])
+		}
+	}
+}
+
+func TestStreamMessages_FollowsActiveLeaf(t *testing.T) {
+	compacted := t.TempDir()
+	os.MkdirAll(filepath.Join(compacted, "p"), 0755)
+	os.WriteFile(filepath.Join(compacted, "p", "s.jsonl"), []byte(
+		`{"type":"user","uuid":"u1","parentUuid":null,"message":{"role":"user","content":"Before"}}
+{"type":"assistant","uuid":"a1","parentUuid":"u1","message":{"role":"assistant","content":[{"type":"text","text":"Reply"}]}}
+{"type":"system","subtype":"compact_boundary","uuid":"c1","parentUuid":null,"logicalParentUuid":"a1"}
+{"type":"user","uuid":"u2","parentUuid":"c1","message":{"role":"user","content":"After"}}
+{"type":"assistant","uuid":"s1","parentUuid":"u2","isSidechain":true,"message":{"role":"assistant","content":[{"type":"text","text":"Agent"}]}}
+`), 0644)
+
+	tests := []struct {
+		name string
+		a    *Adapter
+		id   string
+	}{
+		{"rewound", setupForked(t), "forked"},
+		{"compacted", New(compacted), "s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exported, err := tt.a.ExportMessages(context.Background(), tt.id)
+			if err != nil {
+				t.Fatalf("ExportMessages() error = %v", err)
+			}
+			var want, got []string
+			for _, m := range exported {
+				want = append(want, m.ID+":"+m.Content)
+			}
+			err = tt.a.StreamMessages(context.Background(), tt.id, func(m adapters.Message) error {
+				got = append(got, m.ID+":"+m.Content)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("StreamMessages() error = %v", err)
+			}
+			if strings.Join(got, "|") != strings.Join(want, "|") {
+				t.Errorf("StreamMessages() = %q, want the exported path %q", got, want)
+			}
+		})
+	}
+}
+
+func TestStreamMessages_StopsOnError(t *testing.T) {
+	a := setupTestAdapter(t)
+	stop := errors.New("stop")
+
+	count := 0
+	err := a.StreamMessages(context.Background(), "test-session", func(adapters.Message) error {
+		count++
+		return stop
+	})
+	if err != stop {
+		t.Errorf("StreamMessages() error = %v, want callback error", err)
+	}
+	if count != 1 {
+		t.Errorf("callback ran %d times, want 1", count)
+	}
+}
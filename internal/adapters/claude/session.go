@@ -0,0 +1,654 @@
+package claude
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+	"github.com/Julian194/claude-sessions-tui/internal/pricing"
+)
+
+var cmdRegex = regexp.MustCompile(`<command-name>(/[^<]*)</command-name>`)
+
+// Session is the parsed form of a Claude session file. It is built in a
+// single streaming pass so every Get* accessor can share one parse.
+type Session struct {
+	Path    string
+	ModTime time.Time
+	Size    int64
+
+	Summaries     []string
+	CustomTitle   string // Set with RenameSession; the last one wins
+	ParentSID     string // Parent session (agent sessions) or branch source
+	IsAgent       bool
+	HasMessages   bool
+	Branch        string
+	WorkDir       string
+	Title         string                  // First user message, falling back to first assistant message
+	FirstMessage  string                  // First non-meta user message
+	Files         []adapters.FileActivity // Files read or changed on the active path
+	SlashCommands []string
+	Models        []string
+	Stats         adapters.Stats
+	Messages      []adapters.Message // The path to the active leaf
+	Forks         []adapters.Fork    // Set when the file holds more than one path
+}
+
+// LoadSession returns the parsed session, reusing the memoized parse while
+// the file's mtime and size are unchanged. The session is shared with
+// other callers and must not be modified; the adapter's methods return
+// copies of what they take from it.
+func (a *Adapter) LoadSession(id string) (*Session, error) {
+	path := a.GetSessionFile(id)
+	if path == "" {
+		return nil, os.ErrNotExist
+	}
+	return a.loadPath(path)
+}
+
+// loadPath is LoadSession for a known file
+func (a *Adapter) loadPath(path string) (*Session, error) {
+	return a.memo.Load(path, func(path string, info os.FileInfo) (*Session, error) {
+		s, err := a.parseFile(path)
+		if err != nil {
+			return nil, err
+		}
+		s.ModTime = info.ModTime()
+		s.Size = info.Size()
+		return s, nil
+	})
+}
+
+// parseFile streams a session file once and folds every record into a Session
+func (a *Adapter) parseFile(path string) (*Session, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b := newSessionBuilder(path)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024) // 10MB max line
+
+	for scanner.Scan() {
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue // Skip malformed lines
+		}
+		b.add(&r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return b.finish(), nil
+}
+
+// StreamMessages calls fn for each message ExportMessages returns, in the
+// same order, without holding the whole session in memory. A first pass
+// reads only the uuid links to find the active path; the second converts
+// the messages on it.
+func (a *Adapter) StreamMessages(ctx context.Context, id string, fn func(adapters.Message) error) error {
+	path := a.GetSessionFile(id)
+	if path == "" {
+		return os.ErrNotExist
+	}
+
+	active, err := activePath(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024) // 10MB max line
+
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue // Skip malformed lines
+		}
+		if !exported(&r) || (active != nil && !active[r.UUID]) {
+			continue
+		}
+		if active != nil {
+			delete(active, r.UUID) // Each message once, should a line repeat
+		}
+		if err := fn(convertMessage(&r)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// activePath returns the uuids of the messages on the path to the active
+// leaf, as tree picks it, or nil when the file has no uuids and every
+// message is exported
+func activePath(ctx context.Context, path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024) // 10MB max line
+
+	nodes := make(map[string]node)
+	var last string
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var r struct {
+			Type          string `json:"type"`
+			IsMeta        bool   `json:"isMeta"`
+			UUID          string `json:"uuid"`
+			ParentUUID    string `json:"parentUuid"`
+			LogicalParent string `json:"logicalParentUuid"`
+			IsSidechain   bool   `json:"isSidechain"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil || r.UUID == "" {
+			continue
+		}
+		n := node{parent: r.ParentUUID, msg: -1, sidechain: r.IsSidechain}
+		if n.parent == "" {
+			n.parent = r.LogicalParent
+		}
+		if (r.Type == "user" || r.Type == "assistant") && !r.IsMeta {
+			n.msg = 0
+			if !r.IsSidechain {
+				last = r.UUID
+			}
+		}
+		nodes[r.UUID] = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if last == "" {
+		return nil, nil
+	}
+
+	active := make(map[string]bool)
+	for _, u := range linkPath(nodes, last) {
+		active[u] = true
+	}
+	return active, nil
+}
+
+// exported reports whether a record is a message that gets exported
+func exported(r *record) bool {
+	return (r.Type == "user" || r.Type == "assistant") && !r.IsMeta
+}
+
+// sessionBuilder accumulates a Session record by record
+type sessionBuilder struct {
+	s              *Session
+	branchParent   string
+	firstAssistant string
+	cmds           map[string]bool
+	models         map[string]bool
+	all            []adapters.Message // Exported messages in file order
+	nodes          map[string]node    // Records with a uuid
+	order          []string           // Message uuids in file order
+}
+
+// node is one record of the conversation tree
+type node struct {
+	parent    string
+	msg       int // Index into all, or -1 for records that aren't exported
+	sidechain bool
+}
+
+func newSessionBuilder(path string) *sessionBuilder {
+	return &sessionBuilder{
+		s: &Session{
+			Path:  path,
+			Stats: adapters.Stats{ToolCalls: make(map[string]int)},
+		},
+		cmds:   make(map[string]bool),
+		models: make(map[string]bool),
+		nodes:  make(map[string]node),
+	}
+}
+
+func (b *sessionBuilder) add(r *record) {
+	s := b.s
+
+	// Agent sessions carry agentId; their sessionId points to the parent
+	if !s.IsAgent && r.AgentID != "" {
+		s.IsAgent = true
+		s.ParentSID = r.SessionID
+	}
+	if r.Type == "branch" && r.ParentSession != "" {
+		b.branchParent = r.ParentSession
+	}
+	if r.Type == "summary" && r.Summary != "" {
+		s.Summaries = append(s.Summaries, r.Summary)
+	}
+	if r.Type == "custom-title" {
+		s.CustomTitle = r.CustomTitle
+	}
+	if s.Branch == "" && r.GitBranch != "" {
+		s.Branch = r.GitBranch
+	}
+	if s.WorkDir == "" && r.Cwd != "" {
+		s.WorkDir = r.Cwd
+	}
+	if r.UUID != "" {
+		parent := r.ParentUUID
+		if parent == "" {
+			parent = r.LogicalParent
+		}
+		b.nodes[r.UUID] = node{parent: parent, msg: -1, sidechain: r.IsSidechain}
+	}
+
+	switch r.Type {
+	case "user":
+		s.HasMessages = true
+		b.addUser(r)
+	case "assistant":
+		s.HasMessages = true
+		b.addAssistant(r)
+	}
+}
+
+func (b *sessionBuilder) addUser(r *record) {
+	s := b.s
+
+	if r.Message.Role == "user" {
+		text := extractTextContent(r.Message.Content)
+		if s.Title == "" && text != "" {
+			s.Title = truncate(text, 100)
+		}
+		if s.FirstMessage == "" && !r.IsMeta && text != "" {
+			s.FirstMessage = truncate(text, 200)
+		}
+	}
+
+	// Slash commands show up as command-name tags in user messages
+	if content, ok := r.Message.Content.(string); ok {
+		for _, m := range cmdRegex.FindAllStringSubmatch(content, -1) {
+			if len(m) > 1 {
+				b.cmds[m[1]] = true
+			}
+		}
+	}
+
+	if r.IsMeta {
+		return
+	}
+	s.Stats.UserMessages++
+	b.addMessage(r)
+}
+
+func (b *sessionBuilder) addAssistant(r *record) {
+	s := b.s
+
+	if b.firstAssistant == "" && r.Message.Role == "assistant" {
+		if text := extractTextContent(r.Message.Content); text != "" {
+			b.firstAssistant = truncate(text, 100)
+		}
+	}
+	if r.Message.Model != "" {
+		b.models[r.Message.Model] = true
+	}
+
+	s.Stats.AssistantMessages++
+	if u := r.Message.Usage; u != nil {
+		s.Stats.InputTokens += u.InputTokens
+		s.Stats.OutputTokens += u.OutputTokens
+		s.Stats.CacheRead += u.CacheReadInputTokens
+		s.Stats.CacheWrite += u.CacheCreationInputTokens
+		// Each turn is priced with its own model; sessions can switch midway
+		s.Stats.Cost += pricing.Cost(r.Message.Model, u.InputTokens, u.OutputTokens, u.CacheReadInputTokens, u.CacheCreationInputTokens)
+	}
+
+	if content, ok := r.Message.Content.([]interface{}); ok {
+		for _, item := range content {
+			m, ok := item.(map[string]interface{})
+			if !ok || m["type"] != "tool_use" {
+				continue
+			}
+			name, _ := m["name"].(string)
+			if name != "" {
+				s.Stats.ToolCalls[name]++
+			}
+			input, _ := m["input"].(map[string]interface{})
+			if name == "SlashCommand" {
+				if cmd, ok := input["command"].(string); ok {
+					b.cmds[cmd] = true
+				}
+			}
+		}
+	}
+
+	if !r.IsMeta {
+		b.addMessage(r)
+	}
+}
+
+func (b *sessionBuilder) addMessage(r *record) {
+	if n, ok := b.nodes[r.UUID]; ok {
+		n.msg = len(b.all)
+		b.nodes[r.UUID] = n
+		if !n.sidechain {
+			b.order = append(b.order, r.UUID)
+		}
+	}
+	b.all = append(b.all, convertMessage(r))
+}
+
+func (b *sessionBuilder) finish() *Session {
+	s := b.s
+
+	// Branch metadata only applies to regular sessions
+	if !s.IsAgent && b.branchParent != "" {
+		s.ParentSID = b.branchParent
+	}
+	// Warmup/agent sessions may have no user prompt
+	if s.Title == "" {
+		s.Title = b.firstAssistant
+	}
+
+	s.Messages, s.Forks = b.tree()
+	s.Files = fileActivity(s.Messages)
+	s.SlashCommands = sortedKeys(b.cmds)
+	s.Models = sortedKeys(b.models)
+
+	return s
+}
+
+// tree resolves the parentUuid links. Rewinding or editing a prompt in
+// Claude Code starts a new path from an earlier message while the old one
+// stays in the file; the path ending in the last message written is the
+// one Claude Code resumes, so that is what gets exported. Files without
+// uuids are exported in file order.
+func (b *sessionBuilder) tree() ([]adapters.Message, []adapters.Fork) {
+	if len(b.order) == 0 {
+		return b.all, nil
+	}
+	active := b.order[len(b.order)-1]
+	activePath := b.path(active)
+
+	messages := make([]adapters.Message, len(activePath))
+	for i, m := range activePath {
+		messages[i] = b.all[m]
+	}
+
+	// A leaf is a message that no later message continues from
+	continued := make(map[string]bool)
+	for _, u := range b.order {
+		p := b.nodes[u].parent
+		for steps := 0; p != "" && steps < len(b.nodes); steps++ {
+			n, ok := b.nodes[p]
+			if !ok {
+				break
+			}
+			if n.msg >= 0 {
+				continued[p] = true
+				break
+			}
+			p = n.parent
+		}
+	}
+	var leaves []string
+	for _, u := range b.order {
+		if !continued[u] {
+			leaves = append(leaves, u)
+		}
+	}
+	if len(leaves) < 2 {
+		return messages, nil
+	}
+
+	forks := make([]adapters.Fork, len(leaves))
+	for i, leaf := range leaves {
+		path := activePath
+		if leaf != active {
+			path = b.path(leaf)
+		}
+		shared := 0
+		for shared < len(path) && shared < len(activePath) && path[shared] == activePath[shared] {
+			shared++
+		}
+
+		f := adapters.Fork{
+			LeafID:    leaf,
+			Active:    leaf == active,
+			Messages:  len(path),
+			Shared:    shared,
+			Timestamp: b.all[path[len(path)-1]].Timestamp,
+		}
+		if shared < len(path) {
+			f.First = truncate(strings.Join(strings.Fields(b.all[path[shared]].Content), " "), 100)
+		}
+		forks[i] = f
+	}
+	return messages, forks
+}
+
+// path returns the exported messages from the root to leaf, as indexes
+// into all
+func (b *sessionBuilder) path(leaf string) []int {
+	uuids := linkPath(b.nodes, leaf)
+	path := make([]int, len(uuids))
+	for i, u := range uuids {
+		path[i] = b.nodes[u].msg
+	}
+	return path
+}
+
+// linkPath follows the parent links from leaf up to the root and returns
+// the uuids of the exported messages on the way, root first
+func linkPath(nodes map[string]node, leaf string) []string {
+	var path []string
+	seen := make(map[string]bool)
+	for u := leaf; u != "" && !seen[u]; u = nodes[u].parent {
+		seen[u] = true
+		n, ok := nodes[u]
+		if !ok {
+			break
+		}
+		if n.msg >= 0 {
+			path = append(path, u)
+		}
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// convertMessage normalizes a user/assistant record for export
+func convertMessage(r *record) adapters.Message {
+	msg := adapters.Message{
+		ID:        r.UUID,
+		Role:      r.Message.Role,
+		Timestamp: parseTimestamp(r.Timestamp),
+	}
+
+	switch c := r.Message.Content.(type) {
+	case string:
+		msg.Content = c
+		if c != "" {
+			msg.Blocks = []adapters.ContentBlock{{Kind: adapters.BlockText, Text: c}}
+		}
+	case []interface{}:
+		for _, item := range c {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch m["type"] {
+			case "text":
+				if text, ok := m["text"].(string); ok {
+					msg.Content += text
+					msg.Blocks = append(msg.Blocks, adapters.ContentBlock{Kind: adapters.BlockText, Text: text})
+				}
+			case "thinking":
+				if text, ok := m["thinking"].(string); ok && text != "" {
+					if msg.Thinking != "" {
+						msg.Thinking += "\n\n"
+					}
+					msg.Thinking += text
+					msg.Blocks = append(msg.Blocks, adapters.ContentBlock{Kind: adapters.BlockThinking, Text: text})
+				}
+			case "tool_use":
+				tc := adapters.ToolCall{
+					ID:   getString(m, "id"),
+					Name: getString(m, "name"),
+				}
+				if input, ok := m["input"]; ok {
+					if b, err := json.Marshal(input); err == nil {
+						tc.Input = string(b)
+					}
+				}
+				msg.ToolCalls = append(msg.ToolCalls, tc)
+				msg.Blocks = append(msg.Blocks, adapters.ContentBlock{
+					Kind:      adapters.BlockToolUse,
+					ToolUseID: tc.ID,
+					Name:      tc.Name,
+					Input:     tc.Input,
+				})
+			case "tool_result":
+				text, images := resultContent(m["content"])
+				isError, _ := m["is_error"].(bool)
+				msg.ToolResults = append(msg.ToolResults, adapters.ToolResult{
+					ToolUseID: getString(m, "tool_use_id"),
+					Content:   text,
+					Success:   !isError,
+				})
+				msg.Blocks = append(msg.Blocks, adapters.ContentBlock{
+					Kind:      adapters.BlockToolResult,
+					ToolUseID: getString(m, "tool_use_id"),
+					Text:      text,
+					IsError:   isError,
+				})
+				msg.Blocks = append(msg.Blocks, images...)
+			case "image":
+				if b, ok := imageBlock(m); ok {
+					msg.Blocks = append(msg.Blocks, b)
+				}
+			}
+		}
+	}
+
+	return msg
+}
+
+// resultContent returns the text of a tool_result's content, which is
+// either a string or a list of text and image blocks, and its images
+func resultContent(content interface{}) (string, []adapters.ContentBlock) {
+	switch c := content.(type) {
+	case string:
+		return c, nil
+	case []interface{}:
+		var texts []string
+		var images []adapters.ContentBlock
+		for _, item := range c {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch m["type"] {
+			case "text":
+				texts = append(texts, getString(m, "text"))
+			case "image":
+				if b, ok := imageBlock(m); ok {
+					images = append(images, b)
+				}
+			}
+		}
+		return strings.Join(texts, "\n"), images
+	}
+	return "", nil
+}
+
+// imageBlock converts an image content block with a base64 or URL source
+func imageBlock(m map[string]interface{}) (adapters.ContentBlock, bool) {
+	src, ok := m["source"].(map[string]interface{})
+	if !ok {
+		return adapters.ContentBlock{}, false
+	}
+	b := adapters.ContentBlock{Kind: adapters.BlockImage, MediaType: getString(src, "media_type")}
+	switch getString(src, "type") {
+	case "base64":
+		b.Data = getString(src, "data")
+	case "url":
+		b.URL = getString(src, "url")
+	}
+	return b, b.Data != "" || b.URL != ""
+}
+
+// fileActivity classifies the file tools called on a path. Calls whose
+// result is an error didn't touch the file and are skipped.
+func fileActivity(messages []adapters.Message) []adapters.FileActivity {
+	results := make(map[string]adapters.ToolResult)
+	for _, m := range messages {
+		for _, tr := range m.ToolResults {
+			results[tr.ToolUseID] = tr
+		}
+	}
+
+	var r adapters.FileRecorder
+	for turn, m := range messages {
+		for _, tc := range m.ToolCalls {
+			res, ok := results[tc.ID]
+			if ok && !res.Success {
+				continue
+			}
+			var input struct {
+				FilePath     string `json:"file_path"`
+				NotebookPath string `json:"notebook_path"`
+			}
+			json.Unmarshal([]byte(tc.Input), &input)
+
+			switch tc.Name {
+			case "Read":
+				r.Record(input.FilePath, adapters.FileRead, turn)
+			case "NotebookRead":
+				r.Record(input.NotebookPath, adapters.FileRead, turn)
+			case "Edit", "MultiEdit":
+				r.Record(input.FilePath, adapters.FileModify, turn)
+			case "NotebookEdit":
+				r.Record(input.NotebookPath, adapters.FileModify, turn)
+			case "Write":
+				// The result says whether the file was new; without one, a
+				// file Claude hadn't read or written before was most likely
+				// created since Write refuses to overwrite unread files
+				op := adapters.FileModify
+				if strings.HasPrefix(res.Content, "File created") || (!ok && !r.Seen(input.FilePath)) {
+					op = adapters.FileCreate
+				}
+				r.Record(input.FilePath, op, turn)
+			}
+		}
+	}
+	return r.Activity()
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
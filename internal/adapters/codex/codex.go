@@ -0,0 +1,613 @@
+package codex
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+	"github.com/Julian194/claude-sessions-tui/internal/pricing"
+)
+
+// Adapter implements the OpenAI Codex CLI session adapter.
+// Codex writes one rollout file per session under sessions/YYYY/MM/DD/.
+type Adapter struct {
+	dataDir      string
+	cacheDir     string
+	sessionPaths map[string]string        // Cache of session ID -> full file path
+	pathsMu      sync.RWMutex             // Protects sessionPaths
+	memo         *adapters.Memo[*Session] // Parsed sessions keyed by file path
+}
+
+func init() {
+	adapters.Register(adapters.Provider{
+		Name: "codex",
+		New:  func() adapters.Adapter { return New("") },
+	})
+}
+
+// New creates a new Codex adapter
+func New(dataDir string) *Adapter {
+	if dataDir == "" {
+		if envDir := os.Getenv("CODEX_HOME"); envDir != "" {
+			dataDir = filepath.Join(envDir, "sessions")
+		} else {
+			home, _ := os.UserHomeDir()
+			dataDir = filepath.Join(home, ".codex", "sessions")
+		}
+	}
+	return &Adapter{
+		dataDir:      dataDir,
+		cacheDir:     filepath.Join(dataDir, "..", ".cache"),
+		sessionPaths: make(map[string]string),
+		memo:         adapters.NewMemo[*Session](adapters.MemoBytes),
+	}
+}
+
+func (a *Adapter) Name() string {
+	return "codex"
+}
+
+func (a *Adapter) DataDir() string {
+	return a.dataDir
+}
+
+func (a *Adapter) CacheDir() string {
+	return a.cacheDir
+}
+
+func (a *Adapter) ResumeCmd(id string) string {
+	return "codex resume " + id
+}
+
+// ListSessions returns all session IDs sorted by modification time (newest first)
+func (a *Adapter) ListSessions(ctx context.Context) ([]string, error) {
+	var sessions []sessionFile
+
+	err := filepath.Walk(a.dataDir, func(path string, info os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			return nil // Skip errors
+		}
+		if info.IsDir() {
+			return nil
+		}
+		id := sessionIDFromPath(path)
+		if id == "" {
+			return nil
+		}
+		sessions = append(sessions, sessionFile{
+			id:    id,
+			mtime: info.ModTime(),
+		})
+		a.pathsMu.Lock()
+		a.sessionPaths[id] = path
+		a.pathsMu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].mtime.After(sessions[j].mtime)
+	})
+
+	ids := make([]string, len(sessions))
+	for i, s := range sessions {
+		ids[i] = s.id
+	}
+	return ids, nil
+}
+
+type sessionFile struct {
+	id    string
+	mtime time.Time
+}
+
+// sessionIDFromPath extracts the session UUID from a rollout file name:
+// rollout-2025-01-20T10-00-00-<uuid>.jsonl
+func sessionIDFromPath(path string) string {
+	base := filepath.Base(path)
+	if !strings.HasPrefix(base, "rollout-") || !strings.HasSuffix(base, ".jsonl") {
+		return ""
+	}
+	name := strings.TrimSuffix(base, ".jsonl")
+	if len(name) < len("rollout-")+36 {
+		return ""
+	}
+	return name[len(name)-36:]
+}
+
+// GetSessionFile returns the path to a session's rollout file
+func (a *Adapter) GetSessionFile(id string) string {
+	a.pathsMu.RLock()
+	if path, ok := a.sessionPaths[id]; ok {
+		a.pathsMu.RUnlock()
+		return path
+	}
+	a.pathsMu.RUnlock()
+
+	// Cache miss - search the dated directories
+	var found string
+	filepath.Walk(a.dataDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() && sessionIDFromPath(path) == id {
+			found = path
+			a.pathsMu.Lock()
+			a.sessionPaths[id] = path
+			a.pathsMu.Unlock()
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found
+}
+
+// ErrNoMessages indicates a rollout has no user/assistant messages
+var ErrNoMessages = fmt.Errorf("session has no messages")
+
+// ExtractMeta extracts metadata from a session for cache building
+func (a *Adapter) ExtractMeta(ctx context.Context, id string) (*adapters.SessionMeta, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip rollouts that never got past the environment context
+	if len(s.Messages) == 0 {
+		return nil, ErrNoMessages
+	}
+
+	return &adapters.SessionMeta{
+		ID:      id,
+		Date:    s.ModTime,
+		Project: extractProject(s.WorkDir),
+		Summary: truncate(s.FirstMessage, 100),
+	}, nil
+}
+
+// GetSessionInfo returns detailed session information
+func (a *Adapter) GetSessionInfo(id string) (*adapters.SessionInfo, error) {
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &adapters.SessionInfo{
+		ID:      id,
+		Project: extractProject(s.WorkDir),
+		Date:    s.ModTime,
+		Branch:  s.Branch,
+		WorkDir: s.WorkDir,
+	}, nil
+}
+
+// GetFilesTouched returns files changed through apply_patch
+func (a *Adapter) GetFilesTouched(id string) ([]string, error) {
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(s.FilesTouched), nil
+}
+
+// GetModels returns unique model names used in the session
+func (a *Adapter) GetModels(id string) ([]string, error) {
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(s.Models), nil
+}
+
+// GetStats returns session statistics
+func (a *Adapter) GetStats(ctx context.Context, id string) (*adapters.Stats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := s.Stats
+	stats.ToolCalls = make(map[string]int, len(s.Stats.ToolCalls))
+	for name, n := range s.Stats.ToolCalls {
+		stats.ToolCalls[name] = n
+	}
+	return &stats, nil
+}
+
+// GetFirstMessage returns the first user message
+func (a *Adapter) GetFirstMessage(id string) (string, error) {
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return "", err
+	}
+	return truncate(s.FirstMessage, 200), nil
+}
+
+// ExportMessages returns all messages in normalized format
+func (a *Adapter) ExportMessages(ctx context.Context, id string) ([]adapters.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return adapters.CopyMessages(s.Messages), nil
+}
+
+// Session is the parsed form of a rollout file, built in a single pass
+type Session struct {
+	Path    string
+	ModTime time.Time
+	Size    int64
+
+	ID           string
+	WorkDir      string
+	Branch       string
+	FirstMessage string
+	FilesTouched []string
+	Models       []string
+	Stats        adapters.Stats
+	Messages     []adapters.Message
+}
+
+// LoadSession returns the parsed session, reusing the memoized parse while
+// the file is unchanged. The session is shared with other callers and
+// must not be modified.
+func (a *Adapter) LoadSession(id string) (*Session, error) {
+	path := a.GetSessionFile(id)
+	if path == "" {
+		return nil, os.ErrNotExist
+	}
+	return a.memo.Load(path, func(path string, info os.FileInfo) (*Session, error) {
+		s, err := parseFile(path)
+		if err != nil {
+			return nil, err
+		}
+		s.ModTime = info.ModTime()
+		s.Size = info.Size()
+		return s, nil
+	})
+}
+
+// Internal types for parsing
+
+type line struct {
+	Timestamp string          `json:"timestamp"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+type sessionMeta struct {
+	ID  string `json:"id"`
+	Cwd string `json:"cwd"`
+	Git *struct {
+		Branch string `json:"branch"`
+	} `json:"git"`
+}
+
+type turnContext struct {
+	Cwd   string `json:"cwd"`
+	Model string `json:"model"`
+}
+
+type responseItem struct {
+	Type      string        `json:"type"`
+	Role      string        `json:"role"`
+	Content   []contentItem `json:"content"`
+	Name      string        `json:"name"`
+	Arguments string        `json:"arguments"` // function_call: JSON-encoded arguments
+	Input     string        `json:"input"`     // custom_tool_call: raw input
+	CallID    string        `json:"call_id"`
+	Output    string        `json:"output"`
+	Summary   []contentItem `json:"summary"` // reasoning: summaries of the model's thinking
+}
+
+type contentItem struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type eventMsg struct {
+	Type string `json:"type"`
+	Info *struct {
+		TotalTokenUsage tokenUsage `json:"total_token_usage"`
+	} `json:"info"`
+}
+
+type tokenUsage struct {
+	InputTokens       int `json:"input_tokens"`
+	CachedInputTokens int `json:"cached_input_tokens"`
+	OutputTokens      int `json:"output_tokens"`
+	ReasoningTokens   int `json:"reasoning_output_tokens"` // Included in output_tokens
+}
+
+// costTo prices the tokens used between u and the later cumulative total
+func (u tokenUsage) costTo(total tokenUsage, model string) float64 {
+	cached := total.CachedInputTokens - u.CachedInputTokens
+	input := total.InputTokens - u.InputTokens - cached // input_tokens includes the cached portion
+	output := total.OutputTokens - u.OutputTokens
+	if input < 0 || cached < 0 || output < 0 {
+		return 0
+	}
+	return pricing.Cost(model, input, output, cached, 0)
+}
+
+type shellArgs struct {
+	Command []string `json:"command"`
+	Workdir string   `json:"workdir"`
+}
+
+type toolOutput struct {
+	Output   string `json:"output"`
+	Metadata struct {
+		ExitCode int `json:"exit_code"`
+	} `json:"metadata"`
+}
+
+// parseFile streams a rollout file once and folds it into a Session
+func parseFile(path string) (*Session, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := &Session{
+		Path:  path,
+		ID:    sessionIDFromPath(path),
+		Stats: adapters.Stats{ToolCalls: make(map[string]int)},
+	}
+	files := make(map[string]bool)
+	models := make(map[string]bool)
+	var usage tokenUsage
+	var model string // Model of the current turn
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024) // 10MB max line
+
+	for scanner.Scan() {
+		var l line
+		if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+			continue // Skip malformed lines
+		}
+
+		switch l.Type {
+		case "session_meta":
+			var meta sessionMeta
+			if json.Unmarshal(l.Payload, &meta) == nil {
+				if meta.ID != "" {
+					s.ID = meta.ID
+				}
+				s.WorkDir = meta.Cwd
+				if meta.Git != nil {
+					s.Branch = meta.Git.Branch
+				}
+			}
+		case "turn_context":
+			var tc turnContext
+			if json.Unmarshal(l.Payload, &tc) == nil {
+				if tc.Model != "" {
+					models[tc.Model] = true
+					model = tc.Model
+				}
+				if s.WorkDir == "" {
+					s.WorkDir = tc.Cwd
+				}
+			}
+		case "event_msg":
+			var ev eventMsg
+			if json.Unmarshal(l.Payload, &ev) == nil && ev.Type == "token_count" && ev.Info != nil {
+				// Totals are cumulative; the growth since the last event
+				// is priced with the current turn's model
+				total := ev.Info.TotalTokenUsage
+				s.Stats.Cost += usage.costTo(total, model)
+				usage = total
+			}
+		case "response_item":
+			var item responseItem
+			if json.Unmarshal(l.Payload, &item) == nil {
+				s.addItem(&item, parseTimestamp(l.Timestamp), files)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// input_tokens includes the cached portion
+	s.Stats.InputTokens = usage.InputTokens - usage.CachedInputTokens
+	s.Stats.CacheRead = usage.CachedInputTokens
+	s.Stats.OutputTokens = usage.OutputTokens
+	s.Stats.ReasoningTokens = usage.ReasoningTokens
+
+	s.FilesTouched = make([]string, 0, len(files))
+	for f := range files {
+		s.FilesTouched = append(s.FilesTouched, f)
+	}
+	sort.Strings(s.FilesTouched)
+
+	for m := range models {
+		s.Models = append(s.Models, m)
+	}
+	sort.Strings(s.Models)
+
+	return s, nil
+}
+
+// addItem folds one response item into the session
+func (s *Session) addItem(item *responseItem, ts int64, files map[string]bool) {
+	switch item.Type {
+	case "message":
+		text := joinText(item.Content)
+		// Codex injects environment context and AGENTS.md as user messages
+		if item.Role == "user" && isInjectedContext(text) {
+			return
+		}
+		switch item.Role {
+		case "user":
+			s.Stats.UserMessages++
+			if s.FirstMessage == "" {
+				s.FirstMessage = text
+			}
+		case "assistant":
+			s.Stats.AssistantMessages++
+		default:
+			return
+		}
+		s.Messages = append(s.Messages, adapters.Message{
+			Role:      item.Role,
+			Content:   text,
+			Timestamp: ts,
+		})
+
+	case "reasoning":
+		// Codex only records summaries; the reasoning itself is encrypted
+		text := joinText(item.Summary)
+		if text == "" {
+			return
+		}
+		msg := s.assistantMessage(ts)
+		if msg.Thinking != "" {
+			msg.Thinking += "\n\n"
+		}
+		msg.Thinking += text
+
+	case "function_call", "custom_tool_call":
+		name, input, patch := item.Name, item.Arguments, ""
+		if item.Type == "custom_tool_call" {
+			input = item.Input
+			if name == "apply_patch" {
+				patch = item.Input
+			}
+		} else if name == "shell" {
+			// apply_patch used to be invoked through the shell tool
+			var args shellArgs
+			if json.Unmarshal([]byte(item.Arguments), &args) == nil &&
+				len(args.Command) >= 2 && args.Command[0] == "apply_patch" {
+				name = "apply_patch"
+				patch = args.Command[1]
+			}
+		}
+		if patch != "" {
+			for _, f := range patchFiles(patch) {
+				if !filepath.IsAbs(f) && s.WorkDir != "" {
+					f = filepath.Join(s.WorkDir, f)
+				}
+				files[f] = true
+			}
+		}
+		s.Stats.ToolCalls[name]++
+
+		msg := s.assistantMessage(ts)
+		msg.ToolCalls = append(msg.ToolCalls, adapters.ToolCall{
+			ID:    item.CallID,
+			Name:  name,
+			Input: input,
+		})
+
+	case "function_call_output", "custom_tool_call_output":
+		tr := adapters.ToolResult{
+			ToolUseID: item.CallID,
+			Content:   item.Output,
+			Success:   true,
+		}
+		var out toolOutput
+		if json.Unmarshal([]byte(item.Output), &out) == nil {
+			tr.Content = out.Output
+			tr.Success = out.Metadata.ExitCode == 0
+		}
+		msg := s.assistantMessage(ts)
+		msg.ToolResults = append(msg.ToolResults, tr)
+	}
+}
+
+// assistantMessage returns the trailing assistant message, starting a new
+// one when the last message came from the user
+func (s *Session) assistantMessage(ts int64) *adapters.Message {
+	if n := len(s.Messages); n > 0 && s.Messages[n-1].Role == "assistant" {
+		return &s.Messages[n-1]
+	}
+	s.Messages = append(s.Messages, adapters.Message{
+		Role:      "assistant",
+		Timestamp: ts,
+	})
+	return &s.Messages[len(s.Messages)-1]
+}
+
+// patchFiles returns the paths named in an apply_patch envelope
+func patchFiles(patch string) []string {
+	var files []string
+	for _, l := range strings.Split(patch, "\n") {
+		for _, prefix := range []string{"*** Add File: ", "*** Update File: ", "*** Delete File: ", "*** Move to: "} {
+			if strings.HasPrefix(l, prefix) {
+				files = append(files, strings.TrimSpace(strings.TrimPrefix(l, prefix)))
+			}
+		}
+	}
+	return files
+}
+
+// Helper functions
+
+func isInjectedContext(text string) bool {
+	text = strings.TrimSpace(text)
+	for _, prefix := range []string{"<environment_context>", "<user_instructions>", "# AGENTS.md instructions"} {
+		if strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinText(content []contentItem) string {
+	var texts []string
+	for _, c := range content {
+		if c.Text != "" {
+			texts = append(texts, c.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func extractProject(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	return filepath.Base(dir)
+}
+
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen-3] + "..."
+}
+
+func parseTimestamp(ts string) int64 {
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return 0
+	}
+	return t.Unix()
+}
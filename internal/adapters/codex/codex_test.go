@@ -0,0 +1,304 @@
+package codex
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+const (
+	testSessionID    = "0194a1b2-c3d4-7e5f-8a9b-0c1d2e3f4a5b"
+	minimalSessionID = "0194b2c3-d4e5-7f60-9a1b-2c3d4e5f6a7b"
+)
+
+func testDataDir(t *testing.T) string {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(wd, "testdata", "sessions")
+}
+
+func setupTestAdapter(t *testing.T) *Adapter {
+	t.Helper()
+	return New(testDataDir(t))
+}
+
+func TestNew(t *testing.T) {
+	a := New("/test/path")
+	if a.DataDir() != "/test/path" {
+		t.Errorf("DataDir() = %q, want %q", a.DataDir(), "/test/path")
+	}
+	if a.Name() != "codex" {
+		t.Errorf("Name() = %q, want %q", a.Name(), "codex")
+	}
+}
+
+func TestNew_CodexHome(t *testing.T) {
+	t.Setenv("CODEX_HOME", "/custom/codex")
+	a := New("")
+	if a.DataDir() != "/custom/codex/sessions" {
+		t.Errorf("DataDir() = %q, want %q", a.DataDir(), "/custom/codex/sessions")
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	sessions, err := a.ListSessions(context.Background())
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+
+	if len(sessions) != 2 {
+		t.Fatalf("ListSessions() returned %d sessions, want 2", len(sessions))
+	}
+
+	found := make(map[string]bool)
+	for _, s := range sessions {
+		found[s] = true
+	}
+	if !found[testSessionID] {
+		t.Errorf("ListSessions() missing %s", testSessionID)
+	}
+	if !found[minimalSessionID] {
+		t.Errorf("ListSessions() missing %s", minimalSessionID)
+	}
+}
+
+func TestGetSessionFile_CacheMiss(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	path := a.GetSessionFile(testSessionID)
+	if path == "" {
+		t.Fatal("GetSessionFile() returned empty path")
+	}
+	if filepath.Base(filepath.Dir(path)) != "20" {
+		t.Errorf("GetSessionFile() = %q, want file in dated directory", path)
+	}
+}
+
+func TestSessionIDFromPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/x/rollout-2025-01-20T10-00-00-" + testSessionID + ".jsonl", testSessionID},
+		{"/x/history.jsonl", ""},
+		{"/x/rollout-short.jsonl", ""},
+	}
+
+	for _, tt := range tests {
+		if got := sessionIDFromPath(tt.path); got != tt.want {
+			t.Errorf("sessionIDFromPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestExtractMeta(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	meta, err := a.ExtractMeta(context.Background(), testSessionID)
+	if err != nil {
+		t.Fatalf("ExtractMeta() error = %v", err)
+	}
+
+	if meta.ID != testSessionID {
+		t.Errorf("ID = %q, want %q", meta.ID, testSessionID)
+	}
+	if meta.Project != "my-app" {
+		t.Errorf("Project = %q, want %q", meta.Project, "my-app")
+	}
+	// Environment context must not be mistaken for the first prompt
+	if meta.Summary != "Add input validation to the login handler" {
+		t.Errorf("Summary = %q, want %q", meta.Summary, "Add input validation to the login handler")
+	}
+}
+
+func TestGetSessionInfo(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	info, err := a.GetSessionInfo(testSessionID)
+	if err != nil {
+		t.Fatalf("GetSessionInfo() error = %v", err)
+	}
+
+	if info.Branch != "main" {
+		t.Errorf("Branch = %q, want %q", info.Branch, "main")
+	}
+	if info.WorkDir != "/Users/test/projects/my-app" {
+		t.Errorf("WorkDir = %q, want %q", info.WorkDir, "/Users/test/projects/my-app")
+	}
+}
+
+func TestGetFilesTouched(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	files, err := a.GetFilesTouched(testSessionID)
+	if err != nil {
+		t.Fatalf("GetFilesTouched() error = %v", err)
+	}
+
+	want := []string{
+		"/Users/test/projects/my-app/src/login.ts",
+		"/Users/test/projects/my-app/src/old-login.ts",
+		"/Users/test/projects/my-app/src/validate.ts",
+	}
+	if len(files) != len(want) {
+		t.Fatalf("GetFilesTouched() = %v, want %v", files, want)
+	}
+	for i := range want {
+		if files[i] != want[i] {
+			t.Errorf("files[%d] = %q, want %q", i, files[i], want[i])
+		}
+	}
+}
+
+func TestGetModels(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	models, err := a.GetModels(testSessionID)
+	if err != nil {
+		t.Fatalf("GetModels() error = %v", err)
+	}
+
+	if len(models) != 1 || models[0] != "gpt-5-codex" {
+		t.Errorf("GetModels() = %v, want [gpt-5-codex]", models)
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	stats, err := a.GetStats(context.Background(), testSessionID)
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+
+	if stats.UserMessages != 2 {
+		t.Errorf("UserMessages = %d, want 2", stats.UserMessages)
+	}
+	if stats.AssistantMessages != 2 {
+		t.Errorf("AssistantMessages = %d, want 2", stats.AssistantMessages)
+	}
+
+	// Last cumulative token_count: 7000 input of which 4000 cached
+	if stats.InputTokens != 3000 {
+		t.Errorf("InputTokens = %d, want 3000", stats.InputTokens)
+	}
+	if stats.CacheRead != 4000 {
+		t.Errorf("CacheRead = %d, want 4000", stats.CacheRead)
+	}
+	if stats.OutputTokens != 650 {
+		t.Errorf("OutputTokens = %d, want 650", stats.OutputTokens)
+	}
+	if stats.ReasoningTokens != 128 {
+		t.Errorf("ReasoningTokens = %d, want 128", stats.ReasoningTokens)
+	}
+
+	// gpt-5-codex: 3000 input at $1.25, 4000 cached at $0.125, 650 output at $10 per 1M
+	if stats.Cost < 0.01074 || stats.Cost > 0.01076 {
+		t.Errorf("Cost = %f, want 0.01075", stats.Cost)
+	}
+
+	if stats.ToolCalls["shell"] != 1 {
+		t.Errorf("ToolCalls[shell] = %d, want 1", stats.ToolCalls["shell"])
+	}
+	if stats.ToolCalls["apply_patch"] != 2 {
+		t.Errorf("ToolCalls[apply_patch] = %d, want 2", stats.ToolCalls["apply_patch"])
+	}
+}
+
+func TestGetFirstMessage(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	msg, err := a.GetFirstMessage(minimalSessionID)
+	if err != nil {
+		t.Fatalf("GetFirstMessage() error = %v", err)
+	}
+
+	if msg != "What does this repo do?" {
+		t.Errorf("GetFirstMessage() = %q, want %q", msg, "What does this repo do?")
+	}
+}
+
+func TestExportMessages(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	messages, err := a.ExportMessages(context.Background(), testSessionID)
+	if err != nil {
+		t.Fatalf("ExportMessages() error = %v", err)
+	}
+
+	if len(messages) < 4 {
+		t.Fatalf("ExportMessages() returned %d messages, want at least 4", len(messages))
+	}
+
+	if messages[0].Role != "user" || messages[0].Content != "Add input validation to the login handler" {
+		t.Errorf("messages[0] = %+v, want first user prompt", messages[0])
+	}
+
+	var calls, results int
+	for _, m := range messages {
+		calls += len(m.ToolCalls)
+		results += len(m.ToolResults)
+	}
+	if calls != 3 {
+		t.Errorf("captured %d tool calls, want 3", calls)
+	}
+	if results != 3 {
+		t.Errorf("captured %d tool results, want 3", results)
+	}
+
+	// Tool outputs are unwrapped from their JSON envelope
+	first := messages[1]
+	if len(first.ToolResults) == 0 || first.ToolResults[0].Content != "export function login(user, pass) {}\n" {
+		t.Errorf("first tool result = %+v, want unwrapped shell output", first.ToolResults)
+	}
+	if !first.ToolResults[0].Success {
+		t.Error("tool result with exit code 0 should be successful")
+	}
+
+	// Reasoning summaries are kept with the turn they preceded
+	if first.Thinking != "**Inspecting the login handler**" {
+		t.Errorf("first.Thinking = %q, want the reasoning summary", first.Thinking)
+	}
+}
+
+func TestResumeCmd(t *testing.T) {
+	a := New("")
+	if cmd := a.ResumeCmd("abc"); cmd != "codex resume abc" {
+		t.Errorf("ResumeCmd() = %q, want %q", cmd, "codex resume abc")
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	var a adapters.Adapter = setupTestAdapter(t)
+
+	if _, ok := a.(adapters.Brancher); ok {
+		t.Error("adapter should not implement Brancher")
+	}
+	if _, err := adapters.BranchSession(a, "x"); !errors.Is(err, adapters.ErrNotSupported) {
+		t.Errorf("BranchSession() error = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestPatchFiles(t *testing.T) {
+	patch := "*** Begin Patch\n*** Update File: a.go\n*** Move to: b.go\n@@\n-x\n+y\n*** Delete File: c.go\n*** End Patch"
+	files := patchFiles(patch)
+
+	want := []string{"a.go", "b.go", "c.go"}
+	if len(files) != len(want) {
+		t.Fatalf("patchFiles() = %v, want %v", files, want)
+	}
+	for i := range want {
+		if files[i] != want[i] {
+			t.Errorf("files[%d] = %q, want %q", i, files[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,720 @@
+package gemini
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+	"github.com/Julian194/claude-sessions-tui/internal/pricing"
+)
+
+// checkpointPrefix marks chats saved with /chat save <tag>
+const checkpointPrefix = "checkpoint"
+
+// Adapter implements the Gemini CLI session adapter.
+// Gemini keeps per-project state under tmp/<sha256(project root)>/:
+// recorded chats in chats/session-*.json and saved chats in checkpoint-<tag>.json.
+type Adapter struct {
+	dataDir      string
+	cacheDir     string
+	sessionPaths map[string]string        // Cache of session ID -> full file path
+	pathsMu      sync.RWMutex             // Protects sessionPaths
+	memo         *adapters.Memo[*Session] // Parsed sessions keyed by file path
+}
+
+func init() {
+	adapters.Register(adapters.Provider{
+		Name: "gemini",
+		New:  func() adapters.Adapter { return New("") },
+	})
+}
+
+// New creates a new Gemini CLI adapter
+func New(dataDir string) *Adapter {
+	if dataDir == "" {
+		if envDir := os.Getenv("GEMINI_DIR"); envDir != "" {
+			dataDir = filepath.Join(envDir, "tmp")
+		} else {
+			home, _ := os.UserHomeDir()
+			dataDir = filepath.Join(home, ".gemini", "tmp")
+		}
+	}
+	return &Adapter{
+		dataDir:      dataDir,
+		cacheDir:     filepath.Join(dataDir, "..", ".cache"),
+		sessionPaths: make(map[string]string),
+		memo:         adapters.NewMemo[*Session](adapters.MemoBytes),
+	}
+}
+
+func (a *Adapter) Name() string {
+	return "gemini"
+}
+
+func (a *Adapter) DataDir() string {
+	return a.dataDir
+}
+
+func (a *Adapter) CacheDir() string {
+	return a.cacheDir
+}
+
+// ResumeCmd resumes a recorded chat. Saved checkpoints have no CLI flag;
+// they are restored with /chat resume <tag> inside a fresh session.
+func (a *Adapter) ResumeCmd(id string) string {
+	if isCheckpointID(id) {
+		return "gemini"
+	}
+	if s, err := a.LoadSession(id); err == nil && s.SessionID != "" {
+		return "gemini --resume " + s.SessionID
+	}
+	return "gemini --resume " + id
+}
+
+// ListSessions returns all session IDs sorted by modification time (newest first)
+func (a *Adapter) ListSessions(ctx context.Context) ([]string, error) {
+	var sessions []sessionFile
+
+	err := filepath.Walk(a.dataDir, func(path string, info os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			return nil // Skip errors
+		}
+		if info.IsDir() {
+			// Restore checkpoints (tmp/<hash>/checkpoints/) are file snapshots, not chats
+			if info.Name() == "checkpoints" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		id := a.sessionIDFromPath(path)
+		if id == "" {
+			return nil
+		}
+		sessions = append(sessions, sessionFile{
+			id:    id,
+			mtime: info.ModTime(),
+		})
+		a.pathsMu.Lock()
+		a.sessionPaths[id] = path
+		a.pathsMu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].mtime.After(sessions[j].mtime)
+	})
+
+	ids := make([]string, len(sessions))
+	for i, s := range sessions {
+		ids[i] = s.id
+	}
+	return ids, nil
+}
+
+type sessionFile struct {
+	id    string
+	mtime time.Time
+}
+
+// sessionIDFromPath derives a session ID from a file path.
+// Recorded chats use their file name (session-<time>-<short id>);
+// checkpoints are only unique per project, so they are prefixed
+// with the short project hash: <hash8>/checkpoint-<tag>.
+func (a *Adapter) sessionIDFromPath(path string) string {
+	base := filepath.Base(path)
+	if !strings.HasSuffix(base, ".json") {
+		return ""
+	}
+	name := strings.TrimSuffix(base, ".json")
+	dir := filepath.Dir(path)
+
+	if strings.HasPrefix(name, "session-") && filepath.Base(dir) == "chats" {
+		return name
+	}
+	if strings.HasPrefix(name, checkpointPrefix) && filepath.Dir(dir) == filepath.Clean(a.dataDir) {
+		return shortHash(filepath.Base(dir)) + "/" + name
+	}
+	return ""
+}
+
+func isCheckpointID(id string) bool {
+	_, name, ok := strings.Cut(id, "/")
+	return ok && strings.HasPrefix(name, checkpointPrefix)
+}
+
+// GetSessionFile returns the path to a session's JSON file
+func (a *Adapter) GetSessionFile(id string) string {
+	a.pathsMu.RLock()
+	if path, ok := a.sessionPaths[id]; ok {
+		a.pathsMu.RUnlock()
+		return path
+	}
+	a.pathsMu.RUnlock()
+
+	// Cache miss - rescan the project directories
+	var found string
+	filepath.Walk(a.dataDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if info.Name() == "checkpoints" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if a.sessionIDFromPath(path) == id {
+			found = path
+			a.pathsMu.Lock()
+			a.sessionPaths[id] = path
+			a.pathsMu.Unlock()
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found
+}
+
+// ErrNoMessages indicates a chat has no user/model messages
+var ErrNoMessages = fmt.Errorf("session has no messages")
+
+// ExtractMeta extracts metadata from a session for cache building
+func (a *Adapter) ExtractMeta(ctx context.Context, id string) (*adapters.SessionMeta, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Gemini creates a chat file before the first prompt is sent
+	if len(s.Messages) == 0 {
+		return nil, ErrNoMessages
+	}
+
+	summary := s.FirstMessage
+	if s.Tag != "" {
+		summary = "[" + s.Tag + "] " + summary
+	}
+
+	return &adapters.SessionMeta{
+		ID:      id,
+		Date:    s.Date,
+		Project: s.Project,
+		Summary: truncate(summary, 100),
+	}, nil
+}
+
+// GetSessionInfo returns detailed session information
+func (a *Adapter) GetSessionInfo(id string) (*adapters.SessionInfo, error) {
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &adapters.SessionInfo{
+		ID:      id,
+		Project: s.Project,
+		Date:    s.Date,
+		WorkDir: s.WorkDir,
+	}, nil
+}
+
+// GetFilesTouched returns files changed by successful write_file/replace calls
+func (a *Adapter) GetFilesTouched(id string) ([]string, error) {
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(s.FilesTouched), nil
+}
+
+// GetModels returns unique model names used in the session
+func (a *Adapter) GetModels(id string) ([]string, error) {
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(s.Models), nil
+}
+
+// GetStats returns session statistics
+func (a *Adapter) GetStats(ctx context.Context, id string) (*adapters.Stats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := s.Stats
+	stats.ToolCalls = make(map[string]int, len(s.Stats.ToolCalls))
+	for name, n := range s.Stats.ToolCalls {
+		stats.ToolCalls[name] = n
+	}
+	return &stats, nil
+}
+
+// GetFirstMessage returns the first user message
+func (a *Adapter) GetFirstMessage(id string) (string, error) {
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return "", err
+	}
+	return truncate(s.FirstMessage, 200), nil
+}
+
+// ExportMessages returns all messages in normalized format
+func (a *Adapter) ExportMessages(ctx context.Context, id string) ([]adapters.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s, err := a.LoadSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return adapters.CopyMessages(s.Messages), nil
+}
+
+// Session is the parsed form of a chat or checkpoint file
+type Session struct {
+	Path    string
+	ModTime time.Time
+	Size    int64
+
+	SessionID    string // Gemini's own session UUID (chats only)
+	Tag          string // Checkpoint tag (checkpoints only)
+	Date         time.Time
+	Project      string
+	WorkDir      string
+	FirstMessage string
+	FilesTouched []string
+	Models       []string
+	Stats        adapters.Stats
+	Messages     []adapters.Message
+}
+
+// LoadSession returns the parsed session, reusing the memoized parse while
+// the file is unchanged. The session is shared with other callers and
+// must not be modified.
+func (a *Adapter) LoadSession(id string) (*Session, error) {
+	path := a.GetSessionFile(id)
+	if path == "" {
+		return nil, os.ErrNotExist
+	}
+	return a.memo.Load(path, func(path string, info os.FileInfo) (*Session, error) {
+		s, err := a.parseFile(path)
+		if err != nil {
+			return nil, err
+		}
+		s.ModTime = info.ModTime()
+		s.Size = info.Size()
+		if s.Date.IsZero() {
+			s.Date = info.ModTime()
+		}
+		return s, nil
+	})
+}
+
+// Internal types for parsing
+
+// conversationRecord is a recorded chat (chats/session-*.json)
+type conversationRecord struct {
+	SessionID   string          `json:"sessionId"`
+	ProjectHash string          `json:"projectHash"`
+	StartTime   string          `json:"startTime"`
+	LastUpdated string          `json:"lastUpdated"`
+	Messages    []recordMessage `json:"messages"`
+}
+
+type recordMessage struct {
+	ID        string          `json:"id"`
+	Timestamp string          `json:"timestamp"`
+	Type      string          `json:"type"` // user, gemini, info, error, warning
+	Content   json.RawMessage `json:"content"`
+	Model     string          `json:"model"`
+	Tokens    *tokens         `json:"tokens"`
+	Thoughts  []thought       `json:"thoughts"`
+	ToolCalls []recordTool    `json:"toolCalls"`
+}
+
+// thought is a summary of the model's reasoning, as shown while it works
+type thought struct {
+	Subject     string `json:"subject"`
+	Description string `json:"description"`
+}
+
+type tokens struct {
+	Input    int `json:"input"`
+	Output   int `json:"output"`
+	Cached   int `json:"cached"`
+	Thoughts int `json:"thoughts"`
+	Tool     int `json:"tool"`
+	Total    int `json:"total"`
+}
+
+type recordTool struct {
+	ID     string                 `json:"id"`
+	Name   string                 `json:"name"`
+	Args   map[string]interface{} `json:"args"`
+	Result []part                 `json:"result"`
+	Status string                 `json:"status"`
+}
+
+// content is a checkpoint entry in Gemini API format
+type content struct {
+	Role  string `json:"role"` // user, model
+	Parts []part `json:"parts"`
+}
+
+type part struct {
+	Text         string `json:"text"`
+	Thought      bool   `json:"thought"`
+	FunctionCall *struct {
+		ID   string                 `json:"id"`
+		Name string                 `json:"name"`
+		Args map[string]interface{} `json:"args"`
+	} `json:"functionCall"`
+	FunctionResponse *struct {
+		ID       string                 `json:"id"`
+		Name     string                 `json:"name"`
+		Response map[string]interface{} `json:"response"`
+	} `json:"functionResponse"`
+}
+
+// parseFile reads a chat or checkpoint file into a Session
+func (a *Adapter) parseFile(path string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := filepath.Base(filepath.Dir(path))
+	if filepath.Base(filepath.Dir(path)) == "chats" {
+		hash = filepath.Base(filepath.Dir(filepath.Dir(path)))
+	}
+
+	s := &Session{
+		Path:  path,
+		Stats: adapters.Stats{ToolCalls: make(map[string]int)},
+	}
+	s.WorkDir = projectRoot(hash)
+	if s.WorkDir != "" {
+		s.Project = filepath.Base(s.WorkDir)
+	} else {
+		s.Project = shortHash(hash)
+	}
+
+	files := make(map[string]bool)
+	models := make(map[string]bool)
+
+	if name := strings.TrimSuffix(filepath.Base(path), ".json"); strings.HasPrefix(name, checkpointPrefix) {
+		var history []content
+		if err := json.Unmarshal(data, &history); err != nil {
+			return nil, err
+		}
+		s.Tag = strings.TrimPrefix(strings.TrimPrefix(name, checkpointPrefix), "-")
+		s.addHistory(history)
+	} else {
+		var rec conversationRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, err
+		}
+		s.SessionID = rec.SessionID
+		if t, err := time.Parse(time.RFC3339, rec.LastUpdated); err == nil {
+			s.Date = t
+		}
+		for i := range rec.Messages {
+			s.addRecord(&rec.Messages[i], files, models)
+		}
+	}
+
+	s.FilesTouched = make([]string, 0, len(files))
+	for f := range files {
+		s.FilesTouched = append(s.FilesTouched, f)
+	}
+	sort.Strings(s.FilesTouched)
+
+	for m := range models {
+		s.Models = append(s.Models, m)
+	}
+	sort.Strings(s.Models)
+
+	return s, nil
+}
+
+// addRecord folds one recorded chat message into the session
+func (s *Session) addRecord(m *recordMessage, files, models map[string]bool) {
+	ts := parseTimestamp(m.Timestamp)
+
+	switch m.Type {
+	case "user":
+		text := contentText(m.Content)
+		s.Stats.UserMessages++
+		if s.FirstMessage == "" {
+			s.FirstMessage = text
+		}
+		s.Messages = append(s.Messages, adapters.Message{
+			Role:      "user",
+			Content:   text,
+			Timestamp: ts,
+		})
+
+	case "gemini":
+		s.Stats.AssistantMessages++
+		if m.Model != "" {
+			models[m.Model] = true
+		}
+		if m.Tokens != nil {
+			// input includes the cached portion; thinking is billed as output
+			input := m.Tokens.Input - m.Tokens.Cached
+			output := m.Tokens.Output + m.Tokens.Thoughts
+			s.Stats.InputTokens += input
+			s.Stats.CacheRead += m.Tokens.Cached
+			s.Stats.OutputTokens += output
+			s.Stats.ReasoningTokens += m.Tokens.Thoughts
+			s.Stats.Cost += pricing.Cost(m.Model, input, output, m.Tokens.Cached, 0)
+		}
+
+		msg := adapters.Message{
+			Role:      "assistant",
+			Content:   contentText(m.Content),
+			Thinking:  thoughtsText(m.Thoughts),
+			Timestamp: ts,
+		}
+		for _, tc := range m.ToolCalls {
+			s.Stats.ToolCalls[tc.Name]++
+			success := tc.Status == "success"
+			if success {
+				if f := editedFile(tc.Name, tc.Args); f != "" {
+					files[f] = true
+				}
+			}
+
+			msg.ToolCalls = append(msg.ToolCalls, adapters.ToolCall{
+				ID:    tc.ID,
+				Name:  tc.Name,
+				Input: marshalArgs(tc.Args),
+			})
+			for _, p := range tc.Result {
+				if p.FunctionResponse == nil {
+					continue
+				}
+				msg.ToolResults = append(msg.ToolResults, adapters.ToolResult{
+					ToolUseID: tc.ID,
+					Content:   responseText(p.FunctionResponse.Response),
+					Success:   success,
+				})
+			}
+		}
+		s.Messages = append(s.Messages, msg)
+	}
+	// info, error and warning entries are UI notices, not conversation
+}
+
+// addHistory folds a checkpoint's API history into the session
+func (s *Session) addHistory(history []content) {
+	skipAck := false
+	for _, c := range history {
+		var texts []string
+		var calls []adapters.ToolCall
+		var results []adapters.ToolResult
+
+		for _, p := range c.Parts {
+			switch {
+			case p.FunctionCall != nil:
+				s.Stats.ToolCalls[p.FunctionCall.Name]++
+				calls = append(calls, adapters.ToolCall{
+					ID:    p.FunctionCall.ID,
+					Name:  p.FunctionCall.Name,
+					Input: marshalArgs(p.FunctionCall.Args),
+				})
+			case p.FunctionResponse != nil:
+				_, failed := p.FunctionResponse.Response["error"]
+				results = append(results, adapters.ToolResult{
+					ToolUseID: p.FunctionResponse.ID,
+					Content:   responseText(p.FunctionResponse.Response),
+					Success:   !failed,
+				})
+			case p.Text != "" && !p.Thought:
+				texts = append(texts, p.Text)
+			}
+		}
+		text := strings.Join(texts, "\n")
+
+		switch c.Role {
+		case "user":
+			// Skip the environment preamble and its acknowledgement
+			if isSetupContext(text) {
+				skipAck = true
+				continue
+			}
+			if len(results) > 0 && text == "" {
+				// Function responses travel as user turns; attach them to the call
+				if n := len(s.Messages); n > 0 && s.Messages[n-1].Role == "assistant" {
+					s.Messages[n-1].ToolResults = append(s.Messages[n-1].ToolResults, results...)
+				}
+				continue
+			}
+			s.Stats.UserMessages++
+			if s.FirstMessage == "" {
+				s.FirstMessage = text
+			}
+			s.Messages = append(s.Messages, adapters.Message{Role: "user", Content: text})
+		case "model":
+			if skipAck || (text == "" && len(calls) == 0) {
+				skipAck = false
+				continue
+			}
+			s.Stats.AssistantMessages++
+			s.Messages = append(s.Messages, adapters.Message{
+				Role:      "assistant",
+				Content:   text,
+				ToolCalls: calls,
+			})
+		}
+	}
+}
+
+// thoughtsText renders thought summaries as Markdown, one paragraph each
+func thoughtsText(thoughts []thought) string {
+	var parts []string
+	for _, t := range thoughts {
+		switch {
+		case t.Subject != "" && t.Description != "":
+			parts = append(parts, "**"+t.Subject+"**\n"+t.Description)
+		case t.Subject != "":
+			parts = append(parts, "**"+t.Subject+"**")
+		case t.Description != "":
+			parts = append(parts, t.Description)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// editedFile returns the file a mutating tool call wrote to
+func editedFile(name string, args map[string]interface{}) string {
+	switch name {
+	case "write_file", "replace":
+		return getString(args, "file_path")
+	}
+	return ""
+}
+
+// projectRoot finds the directory Gemini hashed into the project directory
+// name by checking the working directory and its parents
+func projectRoot(hash string) string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		sum := sha256.Sum256([]byte(dir))
+		if hex.EncodeToString(sum[:]) == hash {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Helper functions
+
+func isSetupContext(text string) bool {
+	return strings.HasPrefix(strings.TrimSpace(text), "This is the Gemini CLI.")
+}
+
+// contentText decodes message content, which is either a plain string
+// or a list of parts
+func contentText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var parts []part
+	if json.Unmarshal(raw, &parts) == nil {
+		var texts []string
+		for _, p := range parts {
+			if p.Text != "" && !p.Thought {
+				texts = append(texts, p.Text)
+			}
+		}
+		return strings.Join(texts, "\n")
+	}
+	return ""
+}
+
+// responseText extracts the output (or error) of a function response
+func responseText(resp map[string]interface{}) string {
+	if out := getString(resp, "output"); out != "" {
+		return out
+	}
+	if errMsg := getString(resp, "error"); errMsg != "" {
+		return errMsg
+	}
+	return marshalArgs(resp)
+}
+
+func marshalArgs(args map[string]interface{}) string {
+	if len(args) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(args)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	return hash
+}
+
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen-3] + "..."
+}
+
+func parseTimestamp(ts string) int64 {
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return 0
+	}
+	return t.Unix()
+}
+
+func getString(m map[string]interface{}, key string) string {
+	if v, ok := m[key].(string); ok {
+		return v
+	}
+	return ""
+}
@@ -0,0 +1,311 @@
+package gemini
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+const (
+	testChatID       = "session-2025-09-18T10-00-5f3c2a1b"
+	testCheckpointID = "3f7a9c2e/checkpoint-refactor"
+)
+
+func testDataDir(t *testing.T) string {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(wd, "testdata", "tmp")
+}
+
+func setupTestAdapter(t *testing.T) *Adapter {
+	t.Helper()
+	return New(testDataDir(t))
+}
+
+func TestNew(t *testing.T) {
+	a := New("/test/path")
+	if a.DataDir() != "/test/path" {
+		t.Errorf("DataDir() = %q, want %q", a.DataDir(), "/test/path")
+	}
+	if a.Name() != "gemini" {
+		t.Errorf("Name() = %q, want %q", a.Name(), "gemini")
+	}
+}
+
+func TestNew_GeminiDir(t *testing.T) {
+	t.Setenv("GEMINI_DIR", "/custom/gemini")
+	a := New("")
+	if a.DataDir() != "/custom/gemini/tmp" {
+		t.Errorf("DataDir() = %q, want %q", a.DataDir(), "/custom/gemini/tmp")
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	sessions, err := a.ListSessions(context.Background())
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+
+	// logs.json must not be listed
+	if len(sessions) != 2 {
+		t.Fatalf("ListSessions() returned %d sessions, want 2: %v", len(sessions), sessions)
+	}
+
+	found := make(map[string]bool)
+	for _, s := range sessions {
+		found[s] = true
+	}
+	if !found[testChatID] {
+		t.Errorf("ListSessions() missing %s", testChatID)
+	}
+	if !found[testCheckpointID] {
+		t.Errorf("ListSessions() missing %s", testCheckpointID)
+	}
+}
+
+func TestGetSessionFile_CacheMiss(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	path := a.GetSessionFile(testCheckpointID)
+	if filepath.Base(path) != "checkpoint-refactor.json" {
+		t.Errorf("GetSessionFile() = %q, want checkpoint-refactor.json", path)
+	}
+	if a.GetSessionFile("nonexistent") != "" {
+		t.Error("GetSessionFile() should return empty for unknown IDs")
+	}
+}
+
+func TestExtractMeta(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	meta, err := a.ExtractMeta(context.Background(), testChatID)
+	if err != nil {
+		t.Fatalf("ExtractMeta() error = %v", err)
+	}
+
+	if meta.ID != testChatID {
+		t.Errorf("ID = %q, want %q", meta.ID, testChatID)
+	}
+	// Project root is unknown outside the project, so the short hash is shown
+	if meta.Project != "3f7a9c2e" {
+		t.Errorf("Project = %q, want %q", meta.Project, "3f7a9c2e")
+	}
+	if meta.Summary != "Fix the off-by-one error in pager.go" {
+		t.Errorf("Summary = %q, want %q", meta.Summary, "Fix the off-by-one error in pager.go")
+	}
+	if got := meta.Date.UTC().Format("2006-01-02 15:04"); got != "2025-09-18 10:05" {
+		t.Errorf("Date = %q, want lastUpdated 2025-09-18 10:05", got)
+	}
+}
+
+func TestExtractMeta_Checkpoint(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	meta, err := a.ExtractMeta(context.Background(), testCheckpointID)
+	if err != nil {
+		t.Fatalf("ExtractMeta() error = %v", err)
+	}
+
+	// The setup preamble must not become the summary
+	want := "[refactor] Plan a refactor of the pager package"
+	if meta.Summary != want {
+		t.Errorf("Summary = %q, want %q", meta.Summary, want)
+	}
+}
+
+func TestGetSessionInfo_ProjectRoot(t *testing.T) {
+	projectDir := t.TempDir()
+	sum := sha256.Sum256([]byte(projectDir))
+	hash := hex.EncodeToString(sum[:])
+
+	dataDir := t.TempDir()
+	chatsDir := filepath.Join(dataDir, hash, "chats")
+	os.MkdirAll(chatsDir, 0755)
+	os.WriteFile(filepath.Join(chatsDir, "session-x.json"),
+		[]byte(`{"sessionId":"x","messages":[{"type":"user","content":"hi"}]}`), 0644)
+
+	t.Chdir(projectDir)
+	a := New(dataDir)
+
+	info, err := a.GetSessionInfo("session-x")
+	if err != nil {
+		t.Fatalf("GetSessionInfo() error = %v", err)
+	}
+	if info.WorkDir != projectDir {
+		t.Errorf("WorkDir = %q, want %q", info.WorkDir, projectDir)
+	}
+	if info.Project != filepath.Base(projectDir) {
+		t.Errorf("Project = %q, want %q", info.Project, filepath.Base(projectDir))
+	}
+}
+
+func TestGetFilesTouched(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	files, err := a.GetFilesTouched(testChatID)
+	if err != nil {
+		t.Fatalf("GetFilesTouched() error = %v", err)
+	}
+
+	// The failed replace did not change pager.go
+	if len(files) != 1 || files[0] != "/home/test/code/pager/pager_test.go" {
+		t.Errorf("GetFilesTouched() = %v, want [/home/test/code/pager/pager_test.go]", files)
+	}
+}
+
+func TestGetModels(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	models, err := a.GetModels(testChatID)
+	if err != nil {
+		t.Fatalf("GetModels() error = %v", err)
+	}
+
+	want := []string{"gemini-2.5-flash", "gemini-2.5-pro"}
+	if len(models) != len(want) {
+		t.Fatalf("GetModels() = %v, want %v", models, want)
+	}
+	for i := range want {
+		if models[i] != want[i] {
+			t.Errorf("models[%d] = %q, want %q", i, models[i], want[i])
+		}
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	stats, err := a.GetStats(context.Background(), testChatID)
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+
+	if stats.UserMessages != 2 {
+		t.Errorf("UserMessages = %d, want 2", stats.UserMessages)
+	}
+	if stats.AssistantMessages != 3 {
+		t.Errorf("AssistantMessages = %d, want 3", stats.AssistantMessages)
+	}
+
+	// 7000 input of which 4000 cached
+	if stats.InputTokens != 3000 {
+		t.Errorf("InputTokens = %d, want 3000", stats.InputTokens)
+	}
+	if stats.CacheRead != 4000 {
+		t.Errorf("CacheRead = %d, want 4000", stats.CacheRead)
+	}
+	// 180 output + 30 thinking
+	if stats.OutputTokens != 210 {
+		t.Errorf("OutputTokens = %d, want 210", stats.OutputTokens)
+	}
+	if stats.ReasoningTokens != 30 {
+		t.Errorf("ReasoningTokens = %d, want 30", stats.ReasoningTokens)
+	}
+
+	for _, name := range []string{"read_file", "replace", "write_file"} {
+		if stats.ToolCalls[name] != 1 {
+			t.Errorf("ToolCalls[%s] = %d, want 1", name, stats.ToolCalls[name])
+		}
+	}
+}
+
+func TestGetFirstMessage(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	msg, err := a.GetFirstMessage(testCheckpointID)
+	if err != nil {
+		t.Fatalf("GetFirstMessage() error = %v", err)
+	}
+
+	if msg != "Plan a refactor of the pager package" {
+		t.Errorf("GetFirstMessage() = %q, want %q", msg, "Plan a refactor of the pager package")
+	}
+}
+
+func TestExportMessages(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	messages, err := a.ExportMessages(context.Background(), testChatID)
+	if err != nil {
+		t.Fatalf("ExportMessages() error = %v", err)
+	}
+
+	// info notices are dropped
+	if len(messages) != 5 {
+		t.Fatalf("ExportMessages() returned %d messages, want 5", len(messages))
+	}
+
+	if messages[1].Thinking != "**Locating the bug**\nI should read pager.go first." {
+		t.Errorf("messages[1].Thinking = %q, want the thought summary", messages[1].Thinking)
+	}
+
+	// Part-list content is flattened to text
+	if messages[3].Role != "user" || messages[3].Content != "Looks good, thanks" {
+		t.Errorf("messages[3] = %+v, want flattened user parts", messages[3])
+	}
+
+	edit := messages[2]
+	if len(edit.ToolCalls) != 2 || len(edit.ToolResults) != 2 {
+		t.Fatalf("messages[2] has %d calls / %d results, want 2 / 2", len(edit.ToolCalls), len(edit.ToolResults))
+	}
+	if edit.ToolResults[0].Success || edit.ToolResults[0].Content != "old_string not found" {
+		t.Errorf("ToolResults[0] = %+v, want failed replace", edit.ToolResults[0])
+	}
+	if !edit.ToolResults[1].Success {
+		t.Error("ToolResults[1] should be successful")
+	}
+}
+
+func TestExportMessages_Checkpoint(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	messages, err := a.ExportMessages(context.Background(), testCheckpointID)
+	if err != nil {
+		t.Fatalf("ExportMessages() error = %v", err)
+	}
+
+	// user prompt, model + function call (with its response), model answer
+	if len(messages) != 3 {
+		t.Fatalf("ExportMessages() returned %d messages, want 3", len(messages))
+	}
+	call := messages[1]
+	if len(call.ToolCalls) != 1 || call.ToolCalls[0].Name != "list_directory" {
+		t.Errorf("messages[1].ToolCalls = %+v, want list_directory", call.ToolCalls)
+	}
+	if len(call.ToolResults) != 1 || call.ToolResults[0].Content != "pager.go\npager_test.go" {
+		t.Errorf("messages[1].ToolResults = %+v, want directory listing", call.ToolResults)
+	}
+}
+
+func TestResumeCmd(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	if cmd := a.ResumeCmd(testChatID); cmd != "gemini --resume 5f3c2a1b-7d9e-4f10-8a2b-3c4d5e6f7a8b" {
+		t.Errorf("ResumeCmd() = %q, want resume by session UUID", cmd)
+	}
+	if cmd := a.ResumeCmd(testCheckpointID); cmd != "gemini" {
+		t.Errorf("ResumeCmd(checkpoint) = %q, want %q", cmd, "gemini")
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	var a adapters.Adapter = setupTestAdapter(t)
+
+	if _, ok := a.(adapters.Brancher); ok {
+		t.Error("adapter should not implement Brancher")
+	}
+	if _, err := adapters.BranchSession(a, "x"); !errors.Is(err, adapters.ErrNotSupported) {
+		t.Errorf("BranchSession() error = %v, want ErrNotSupported", err)
+	}
+}
@@ -0,0 +1,103 @@
+package adapters
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// MemoBytes is the default bound of a Memo: the total size of the session
+// files whose parses it keeps. A preview only ever needs one session;
+// cache builds touch every session once.
+const MemoBytes = 64 << 20
+
+// Memo keeps parsed session files while they are unchanged on disk. It is
+// bounded by the size of the files rather than their number, since a parse
+// takes memory in proportion to its file. The least recently used parses
+// go first, and files larger than the bound are parsed on every load.
+//
+// Values are shared between callers, which must not modify them.
+type Memo[T any] struct {
+	limit int64
+
+	mu      sync.Mutex
+	entries map[string]*memoEntry[T]
+	total   int64 // Size of the memoized files
+	clock   int64 // Counts loads, to order entries by last use
+}
+
+type memoEntry[T any] struct {
+	modTime time.Time
+	size    int64
+	used    int64
+	value   T
+}
+
+// NewMemo creates a memo holding parses of at most limit bytes of files
+func NewMemo[T any](limit int64) *Memo[T] {
+	return &Memo[T]{limit: limit, entries: make(map[string]*memoEntry[T])}
+}
+
+// Load returns the memoized parse of path while the file's mtime and size
+// are unchanged, and otherwise parses it again
+func (m *Memo[T]) Load(path string, parse func(path string, info os.FileInfo) (T, error)) (T, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	m.mu.Lock()
+	if e, ok := m.entries[path]; ok && e.modTime.Equal(info.ModTime()) && e.size == info.Size() {
+		m.clock++
+		e.used = m.clock
+		m.mu.Unlock()
+		return e.value, nil
+	}
+	m.mu.Unlock()
+
+	v, err := parse(path, info)
+	if err != nil {
+		return v, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(path)
+	if info.Size() > m.limit {
+		return v, nil
+	}
+	for m.total+info.Size() > m.limit {
+		m.evict()
+	}
+	m.clock++
+	m.entries[path] = &memoEntry[T]{modTime: info.ModTime(), size: info.Size(), used: m.clock, value: v}
+	m.total += info.Size()
+	return v, nil
+}
+
+// Len returns the number of memoized parses
+func (m *Memo[T]) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entries)
+}
+
+func (m *Memo[T]) remove(path string) {
+	if e, ok := m.entries[path]; ok {
+		m.total -= e.size
+		delete(m.entries, path)
+	}
+}
+
+// evict drops the least recently used entry
+func (m *Memo[T]) evict() {
+	var oldest string
+	var used int64
+	for path, e := range m.entries {
+		if oldest == "" || e.used < used {
+			oldest, used = path, e.used
+		}
+	}
+	m.remove(oldest)
+}
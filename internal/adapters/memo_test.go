@@ -0,0 +1,66 @@
+package adapters
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemo(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, size int) string {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(strings.Repeat("x", size)), 0644)
+		return path
+	}
+	a, b, c := write("a", 40), write("b", 40), write("c", 40)
+	big := write("big", 200)
+
+	parses := 0
+	parse := func(path string, info os.FileInfo) (string, error) {
+		parses++
+		return filepath.Base(path), nil
+	}
+	m := NewMemo[string](100)
+
+	m.Load(a, parse)
+	m.Load(b, parse)
+	if v, _ := m.Load(a, parse); v != "a" || parses != 2 {
+		t.Fatalf("Load(a) = %q after %d parses, want the memoized a after 2", v, parses)
+	}
+
+	// c doesn't fit next to a and b, so the least recently used b goes
+	m.Load(c, parse)
+	if m.Len() != 2 {
+		t.Errorf("Len() = %d, want 2 within 100 bytes", m.Len())
+	}
+	parses = 0
+	m.Load(a, parse)
+	m.Load(c, parse)
+	if parses != 0 {
+		t.Errorf("a and c parsed %d times, want them memoized", parses)
+	}
+	m.Load(b, parse)
+	if parses != 1 {
+		t.Errorf("b parsed %d times, want it evicted", parses)
+	}
+
+	// Files larger than the bound are never kept
+	parses = 0
+	m.Load(big, parse)
+	m.Load(big, parse)
+	if parses != 2 || m.Len() != 2 {
+		t.Errorf("big parsed %d times with %d entries, want 2 parses and nothing evicted", parses, m.Len())
+	}
+
+	// A changed file is parsed again
+	parses = 0
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(c, later, later)
+	m.Load(c, parse)
+	if parses != 1 {
+		t.Errorf("changed c parsed %d times, want 1", parses)
+	}
+}
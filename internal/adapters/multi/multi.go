@@ -0,0 +1,387 @@
+package multi
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+// Separator joins a provider name and a provider-local session ID
+const Separator = ":"
+
+// Adapter merges several providers into a single session list.
+// Session IDs are namespaced as "<provider>:<id>" so every call can be
+// routed to the adapter that owns the session.
+type Adapter struct {
+	members  []adapters.Adapter
+	byName   map[string]adapters.Adapter
+	cacheDir string
+}
+
+// ProviderName is the registry name of the combined provider
+const ProviderName = "all"
+
+func init() {
+	adapters.Register(adapters.Provider{
+		Name:  ProviderName,
+		Match: "all-sessions",
+		New:   func() adapters.Adapter { return Detected() },
+		Detect: func(a adapters.Adapter) bool {
+			return len(a.(*Adapter).members) > 0
+		},
+	})
+}
+
+// Detected creates a composite adapter over every registered provider
+// whose data is present on this machine
+func Detected() *Adapter {
+	var members []adapters.Adapter
+	for _, p := range adapters.Providers() {
+		if p.Name == ProviderName {
+			continue
+		}
+		if m := p.New(); p.Detected(m) {
+			members = append(members, m)
+		}
+	}
+	return New(members...)
+}
+
+// New creates a composite adapter over the given providers
+func New(members ...adapters.Adapter) *Adapter {
+	byName := make(map[string]adapters.Adapter, len(members))
+	for _, m := range members {
+		byName[m.Name()] = m
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		home, _ := os.UserHomeDir()
+		cacheDir = filepath.Join(home, ".cache")
+	}
+
+	return &Adapter{
+		members:  members,
+		byName:   byName,
+		cacheDir: filepath.Join(cacheDir, "claude-sessions"),
+	}
+}
+
+// JoinID namespaces a provider-local session ID
+func JoinID(provider, id string) string {
+	return provider + Separator + id
+}
+
+// SplitID splits a namespaced session ID into provider and local ID.
+// ok is false when the ID carries no provider prefix.
+func SplitID(id string) (provider, local string, ok bool) {
+	provider, local, ok = strings.Cut(id, Separator)
+	if !ok || provider == "" {
+		return "", id, false
+	}
+	return provider, local, true
+}
+
+// Members returns the wrapped adapters
+func (a *Adapter) Members() []adapters.Adapter {
+	return a.members
+}
+
+// Resolve returns the adapter owning a namespaced session ID and the
+// provider-local ID to pass to it
+func (a *Adapter) Resolve(id string) (adapters.Adapter, string, error) {
+	provider, local, ok := SplitID(id)
+	if !ok {
+		return nil, "", fmt.Errorf("session ID %q has no provider prefix", id)
+	}
+	m, ok := a.byName[provider]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown provider %q", provider)
+	}
+	return m, local, nil
+}
+
+// Supports reports whether the provider owning a session can perform an
+// action, or for an empty id whether any provider can
+func (a *Adapter) Supports(id string, c adapters.Capability) bool {
+	if id == "" {
+		for _, m := range a.members {
+			if adapters.Supports(m, "", c) {
+				return true
+			}
+		}
+		return false
+	}
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return false
+	}
+	return adapters.Supports(m, local, c)
+}
+
+func (a *Adapter) Name() string {
+	return ProviderName
+}
+
+func (a *Adapter) DataDir() string {
+	dirs := make([]string, len(a.members))
+	for i, m := range a.members {
+		dirs[i] = m.DataDir()
+	}
+	return strings.Join(dirs, ", ")
+}
+
+func (a *Adapter) CacheDir() string {
+	return a.cacheDir
+}
+
+func (a *Adapter) ResumeCmd(id string) string {
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return ""
+	}
+	return m.ResumeCmd(local)
+}
+
+// ListSessions returns the sessions of every provider, newest first. A
+// single provider's order is kept as is; several are merged by the
+// modification time of their session files.
+func (a *Adapter) ListSessions(ctx context.Context) ([]string, error) {
+	type session struct {
+		id    string
+		mtime time.Time
+	}
+	var sessions []session
+	merge := len(a.members) > 1
+
+	for _, m := range a.members {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ids, err := m.ListSessions(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.Name(), err)
+		}
+		for _, id := range ids {
+			s := session{id: JoinID(m.Name(), id)}
+			if merge {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				if path := m.GetSessionFile(id); path != "" {
+					if info, err := os.Stat(path); err == nil {
+						s.mtime = info.ModTime()
+					}
+				}
+			}
+			sessions = append(sessions, s)
+		}
+	}
+
+	if merge {
+		sort.SliceStable(sessions, func(i, j int) bool {
+			return sessions[i].mtime.After(sessions[j].mtime)
+		})
+	}
+
+	ids := make([]string, len(sessions))
+	for i, s := range sessions {
+		ids[i] = s.id
+	}
+	return ids, nil
+}
+
+func (a *Adapter) GetSessionFile(id string) string {
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return ""
+	}
+	return m.GetSessionFile(local)
+}
+
+func (a *Adapter) ExtractMeta(ctx context.Context, id string) (*adapters.SessionMeta, error) {
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := m.ExtractMeta(ctx, local)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaced := *meta
+	namespaced.ID = id
+	if meta.ParentSID != "" {
+		namespaced.ParentSID = JoinID(m.Name(), meta.ParentSID)
+	}
+	return &namespaced, nil
+}
+
+func (a *Adapter) GetSessionInfo(id string) (*adapters.SessionInfo, error) {
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	info, err := m.GetSessionInfo(local)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaced := *info
+	namespaced.ID = id
+	return &namespaced, nil
+}
+
+func (a *Adapter) GetSummaries(id string) ([]string, error) {
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	return adapters.GetSummaries(m, local)
+}
+
+func (a *Adapter) GetFilesTouched(id string) ([]string, error) {
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	return adapters.GetFilesTouched(m, local)
+}
+
+func (a *Adapter) GetFileActivity(ctx context.Context, id string) ([]adapters.FileActivity, error) {
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	return adapters.GetFileActivity(ctx, m, local)
+}
+
+func (a *Adapter) GetDetails(ctx context.Context, id string) (*adapters.SessionDetails, error) {
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	return adapters.GetDetails(ctx, m, local)
+}
+
+func (a *Adapter) GetSlashCommands(id string) ([]string, error) {
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	return adapters.GetSlashCommands(m, local)
+}
+
+func (a *Adapter) GetModels(id string) ([]string, error) {
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	return adapters.GetModels(m, local)
+}
+
+func (a *Adapter) GetForks(id string) ([]adapters.Fork, error) {
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	return adapters.GetForks(m, local)
+}
+
+func (a *Adapter) GetStats(ctx context.Context, id string) (*adapters.Stats, error) {
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := adapters.GetStats(ctx, m, local)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaced := *stats
+	if stats.Subagents != nil {
+		namespaced.Subagents = make([]adapters.SubagentStats, len(stats.Subagents))
+		for i, sub := range stats.Subagents {
+			sub.ID = JoinID(m.Name(), sub.ID)
+			namespaced.Subagents[i] = sub
+		}
+	}
+	return &namespaced, nil
+}
+
+func (a *Adapter) GetFirstMessage(id string) (string, error) {
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return "", err
+	}
+	return adapters.GetFirstMessage(context.Background(), m, local)
+}
+
+func (a *Adapter) ExportMessages(ctx context.Context, id string) ([]adapters.Message, error) {
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	return m.ExportMessages(ctx, local)
+}
+
+func (a *Adapter) StreamMessages(ctx context.Context, id string, fn func(adapters.Message) error) error {
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return err
+	}
+	return adapters.StreamMessages(ctx, m, local, fn)
+}
+
+// BranchSession branches within the owning provider and returns the
+// namespaced ID of the new session. Providers that can't branch return
+// adapters.ErrNotSupported.
+func (a *Adapter) BranchSession(id string) (string, error) {
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return "", err
+	}
+	newID, err := adapters.BranchSession(m, local)
+	if err != nil {
+		return "", err
+	}
+	return JoinID(m.Name(), newID), nil
+}
+
+// BranchSessionAt branches at a message within the owning provider and
+// returns the namespaced ID of the new session
+func (a *Adapter) BranchSessionAt(id, at string) (string, error) {
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return "", err
+	}
+	newID, err := adapters.BranchSessionAt(m, local, at)
+	if err != nil {
+		return "", err
+	}
+	return JoinID(m.Name(), newID), nil
+}
+
+// SessionFiles lists the files of a session in its owning provider
+func (a *Adapter) SessionFiles(id string) ([]string, error) {
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	return adapters.SessionFiles(m, local)
+}
+
+// RenameSession renames a session in its owning provider
+func (a *Adapter) RenameSession(id, title string) error {
+	m, local, err := a.Resolve(id)
+	if err != nil {
+		return err
+	}
+	return adapters.RenameSession(m, local, title)
+}
@@ -0,0 +1,208 @@
+package multi
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+	"github.com/Julian194/claude-sessions-tui/internal/adapters/claude"
+	"github.com/Julian194/claude-sessions-tui/internal/adapters/codex"
+	"github.com/Julian194/claude-sessions-tui/internal/adapters/opencode"
+)
+
+func setupTestAdapter(t *testing.T) *Adapter {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(
+		claude.New(filepath.Join(wd, "..", "claude", "testdata")),
+		opencode.New(filepath.Join(wd, "..", "opencode", "testdata", "storage")),
+	)
+}
+
+func TestSplitID(t *testing.T) {
+	tests := []struct {
+		id       string
+		provider string
+		local    string
+		ok       bool
+	}{
+		{"claude:abc-123", "claude", "abc-123", true},
+		{"claude:parent/agent-1", "claude", "parent/agent-1", true},
+		{"opencode:ses_abc", "opencode", "ses_abc", true},
+		{"plain-id", "", "plain-id", false},
+		{":missing", "", ":missing", false},
+	}
+
+	for _, tt := range tests {
+		provider, local, ok := SplitID(tt.id)
+		if provider != tt.provider || local != tt.local || ok != tt.ok {
+			t.Errorf("SplitID(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				tt.id, provider, local, ok, tt.provider, tt.local, tt.ok)
+		}
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	sessions, err := a.ListSessions(context.Background())
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+
+	if len(sessions) != 4 {
+		t.Fatalf("ListSessions() returned %d sessions, want 4", len(sessions))
+	}
+
+	found := make(map[string]bool)
+	for _, s := range sessions {
+		found[s] = true
+	}
+	for _, want := range []string{"claude:test-session", "claude:minimal-session", "opencode:ses_abc123", "opencode:ses_subagent456"} {
+		if !found[want] {
+			t.Errorf("ListSessions() missing %q", want)
+		}
+	}
+}
+
+func TestExtractMeta_Namespaced(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	meta, err := a.ExtractMeta(context.Background(), "opencode:ses_subagent456")
+	if err != nil {
+		t.Fatalf("ExtractMeta() error = %v", err)
+	}
+
+	if meta.ID != "opencode:ses_subagent456" {
+		t.Errorf("ID = %q, want %q", meta.ID, "opencode:ses_subagent456")
+	}
+	if meta.ParentSID != "opencode:ses_abc123" {
+		t.Errorf("ParentSID = %q, want %q", meta.ParentSID, "opencode:ses_abc123")
+	}
+}
+
+func TestRoutesToOwner(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	info, err := a.GetSessionInfo("claude:test-session")
+	if err != nil {
+		t.Fatalf("GetSessionInfo() error = %v", err)
+	}
+	if info.ID != "claude:test-session" {
+		t.Errorf("ID = %q, want %q", info.ID, "claude:test-session")
+	}
+	if info.Branch != "main" {
+		t.Errorf("Branch = %q, want %q", info.Branch, "main")
+	}
+
+	messages, err := a.ExportMessages(context.Background(), "opencode:ses_abc123")
+	if err != nil {
+		t.Fatalf("ExportMessages() error = %v", err)
+	}
+	if len(messages) == 0 {
+		t.Error("ExportMessages() returned no messages")
+	}
+
+	if cmd := a.ResumeCmd("opencode:ses_abc123"); cmd != "opencode --session ses_abc123" {
+		t.Errorf("ResumeCmd() = %q, want %q", cmd, "opencode --session ses_abc123")
+	}
+}
+
+func TestResolve_Errors(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	if _, _, err := a.Resolve("test-session"); err == nil {
+		t.Error("Resolve() should error for IDs without a provider")
+	}
+	if _, _, err := a.Resolve("codex:abc"); err == nil {
+		t.Error("Resolve() should error for unknown providers")
+	}
+}
+
+func TestBranchSession_Namespaced(t *testing.T) {
+	tmpDir := t.TempDir()
+	projectDir := filepath.Join(tmpDir, "test-project")
+	os.MkdirAll(projectDir, 0755)
+	os.WriteFile(filepath.Join(projectDir, "original.jsonl"),
+		[]byte(`{"type":"user","message":{"role":"user","content":"Hello"}}`+"\n"), 0644)
+
+	a := New(claude.New(tmpDir))
+
+	newID, err := a.BranchSession("claude:original")
+	if err != nil {
+		t.Fatalf("BranchSession() error = %v", err)
+	}
+	if !strings.HasPrefix(newID, "claude:") {
+		t.Errorf("BranchSession() = %q, want claude: prefix", newID)
+	}
+	if a.GetSessionFile(newID) == "" {
+		t.Error("branched session is not resolvable")
+	}
+}
+
+func TestSupports_PerSession(t *testing.T) {
+	a := New(claude.New(t.TempDir()), codex.New(t.TempDir()))
+
+	if !a.Supports("claude:s1", adapters.CanBranch) || !a.Supports("claude:s1", adapters.CanRename) {
+		t.Error("claude sessions should support branching and renaming")
+	}
+	if a.Supports("codex:s1", adapters.CanBranch) || a.Supports("codex:s1", adapters.CanDelete) {
+		t.Error("codex sessions should support neither branching nor deleting")
+	}
+	if a.Supports("aider:s1", adapters.CanBranch) {
+		t.Error("sessions of unknown providers support nothing")
+	}
+	if !a.Supports("", adapters.CanBranch) {
+		t.Error("Supports() for any session should hold when one provider can")
+	}
+	if _, err := adapters.BranchSession(a, "codex:s1"); !errors.Is(err, adapters.ErrNotSupported) {
+		t.Errorf("BranchSession() on codex error = %v, want ErrNotSupported", err)
+	}
+
+	// Without a provider that can, the action isn't offered at all
+	if adapters.Supports(New(codex.New(t.TempDir())), "", adapters.CanRename) {
+		t.Error("codex alone should not support renaming")
+	}
+}
+
+func TestListSessions_Cancelled(t *testing.T) {
+	a := setupTestAdapter(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := a.ListSessions(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListSessions() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestGetStats_NamespacesSubagents(t *testing.T) {
+	storage := t.TempDir()
+	sessionDir := filepath.Join(storage, "session", "proj_test")
+	if err := os.MkdirAll(sessionDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(sessionDir, "ses_parent.json"), []byte(`{"id":"ses_parent","projectID":"proj_test","title":"Parent","time":{"created":1734500000000,"updated":1734500000000}}`), 0644)
+	os.WriteFile(filepath.Join(sessionDir, "ses_child.json"), []byte(`{"id":"ses_child","projectID":"proj_test","parentID":"ses_parent","title":"Look around (@general subagent)","time":{"created":1734500100000,"updated":1734500100000}}`), 0644)
+	a := New(opencode.New(storage))
+
+	stats, err := a.GetStats(context.Background(), "opencode:ses_parent")
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if len(stats.Subagents) != 1 {
+		t.Fatalf("Subagents = %+v, want ses_child", stats.Subagents)
+	}
+	if id := stats.Subagents[0].ID; id != "opencode:ses_child" {
+		t.Errorf("subagent ID = %q, want %q", id, "opencode:ses_child")
+	}
+	if _, err := a.ExtractMeta(context.Background(), stats.Subagents[0].ID); err != nil {
+		t.Errorf("ExtractMeta(%q) error = %v", stats.Subagents[0].ID, err)
+	}
+}
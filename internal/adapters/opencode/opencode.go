@@ -1,18 +1,21 @@
 package opencode
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+	"github.com/Julian194/claude-sessions-tui/internal/pricing"
 )
 
 type Adapter struct {
@@ -20,6 +23,18 @@ type Adapter struct {
 	cacheDir     string
 	sessionPaths map[string]string // Cache of session ID -> full file path
 	pathsMu      sync.RWMutex      // Protects sessionPaths
+
+	// Child sessions by session directory and parent ID, read once per
+	// listing rather than once per session
+	children   map[string]map[string][]sessionData
+	childrenMu sync.Mutex // Protects children
+}
+
+func init() {
+	adapters.Register(adapters.Provider{
+		Name: "opencode",
+		New:  func() adapters.Adapter { return New("") },
+	})
 }
 
 func New(dataDir string) *Adapter {
@@ -50,11 +65,15 @@ func (a *Adapter) ResumeCmd(id string) string {
 	return "opencode --session " + id
 }
 
-func (a *Adapter) ListSessions() ([]string, error) {
+func (a *Adapter) ListSessions(ctx context.Context) ([]string, error) {
 	var sessions []sessionFile
+	a.forgetChildren()
 
 	sessionDir := filepath.Join(a.dataDir, "session")
 	err := filepath.Walk(sessionDir, func(path string, info os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 		if err != nil {
 			return nil
 		}
@@ -120,7 +139,10 @@ func (a *Adapter) GetSessionFile(id string) string {
 	return found
 }
 
-func (a *Adapter) ExtractMeta(id string) (*adapters.SessionMeta, error) {
+func (a *Adapter) ExtractMeta(ctx context.Context, id string) (*adapters.SessionMeta, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	session, err := a.loadSession(id)
 	if err != nil {
 		return nil, err
@@ -165,27 +187,105 @@ func (a *Adapter) GetSummaries(id string) ([]string, error) {
 	return nil, nil
 }
 
+// GetFilesTouched returns files created, modified or deleted in the session
 func (a *Adapter) GetFilesTouched(id string) ([]string, error) {
-	parts, err := a.loadParts(id)
+	activity, err := a.GetFileActivity(context.Background(), id)
 	if err != nil {
 		return nil, err
 	}
+	return adapters.ChangedFiles(activity), nil
+}
 
-	fileSet := make(map[string]bool)
-	for _, part := range parts {
-		if part.Type == "tool" && (part.Tool == "edit" || part.Tool == "write") {
-			if part.State.Input.FilePath != "" {
-				fileSet[part.State.Input.FilePath] = true
+// GetFileActivity classifies the file tools the session called. Patch
+// parts add files a step changed some other way, such as through bash.
+func (a *Adapter) GetFileActivity(ctx context.Context, id string) ([]adapters.FileActivity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	messages, partsByMsg, err := a.loadConversation(id)
+	if err != nil {
+		return nil, err
+	}
+	return fileActivity(messages, partsByMsg), nil
+}
+
+// fileActivity classifies the file tools of a loaded conversation
+func fileActivity(messages []messageData, partsByMsg map[string][]part) []adapters.FileActivity {
+	var r adapters.FileRecorder
+	for turn, msg := range messages {
+		changed := make(map[string]bool)
+		for _, p := range partsByMsg[msg.ID] {
+			if p.Type != "tool" || p.State.Status != "completed" {
+				continue
+			}
+			path := p.filePath()
+			switch p.Tool {
+			case "read":
+				r.Record(path, adapters.FileRead, turn)
+			case "edit", "multiedit":
+				r.Record(path, adapters.FileModify, turn)
+				changed[path] = true
+			case "write":
+				op := adapters.FileModify
+				if exists := p.State.Metadata.Exists; (exists != nil && !*exists) || (exists == nil && !r.Seen(path)) {
+					op = adapters.FileCreate
+				}
+				r.Record(path, op, turn)
+				changed[path] = true
+			case "patch":
+				var input struct {
+					PatchText string `json:"patchText"`
+				}
+				json.Unmarshal(p.State.Input, &input)
+				for _, f := range patchOps(input.PatchText) {
+					r.Record(f.path, f.op, turn)
+					changed[f.path] = true
+				}
+			}
+		}
+		for _, p := range partsByMsg[msg.ID] {
+			if p.Type != "patch" {
+				continue
+			}
+			for _, f := range p.Files {
+				if !changed[f] {
+					r.Record(f, adapters.FileModify, turn)
+					changed[f] = true
+				}
 			}
 		}
 	}
+	return r.Activity()
+}
 
-	files := make([]string, 0, len(fileSet))
-	for f := range fileSet {
-		files = append(files, f)
+// patchOp is one file named in a patch tool's input
+type patchOp struct {
+	path string
+	op   adapters.FileOp
+}
+
+// patchHeaders maps the file headers of the apply_patch format to operations
+var patchHeaders = []struct {
+	prefix string
+	op     adapters.FileOp
+}{
+	{"*** Add File: ", adapters.FileCreate},
+	{"*** Update File: ", adapters.FileModify},
+	{"*** Delete File: ", adapters.FileDelete},
+	{"*** Move to: ", adapters.FileCreate},
+}
+
+// patchOps reads the file headers of a patch in the apply_patch format
+func patchOps(patch string) []patchOp {
+	var ops []patchOp
+	for _, l := range strings.Split(patch, "\n") {
+		for _, h := range patchHeaders {
+			if strings.HasPrefix(l, h.prefix) {
+				ops = append(ops, patchOp{strings.TrimSpace(strings.TrimPrefix(l, h.prefix)), h.op})
+			}
+		}
 	}
-	sort.Strings(files)
-	return files, nil
+	return ops
 }
 
 func (a *Adapter) GetSlashCommands(id string) ([]string, error) {
@@ -232,7 +332,11 @@ func (a *Adapter) GetModels(id string) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
+	return modelsUsed(messages), nil
+}
 
+// modelsUsed returns the unique models that answered in messages, sorted
+func modelsUsed(messages []messageData) []string {
 	modelSet := make(map[string]bool)
 	for _, msg := range messages {
 		if msg.Role == "assistant" && msg.ModelID != "" {
@@ -245,45 +349,208 @@ func (a *Adapter) GetModels(id string) ([]string, error) {
 		models = append(models, m)
 	}
 	sort.Strings(models)
-	return models, nil
+	return models
 }
 
-func (a *Adapter) GetStats(id string) (*adapters.Stats, error) {
-	messages, err := a.loadMessages(id)
+// GetStats returns the session's statistics, rolling up the child sessions
+// its subagents ran in
+func (a *Adapter) GetStats(ctx context.Context, id string) (*adapters.Stats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	messages, partsByMsg, err := a.loadConversation(id)
 	if err != nil {
 		return nil, err
 	}
+	stats := conversationStats(messages, partsByMsg)
 
-	parts, err := a.loadParts(id)
+	// Walk down through the subagents' own subagents
+	var subagents []adapters.SubagentStats
+	type pending struct {
+		id      string
+		spawned map[string]bool
+	}
+	seen := map[string]bool{id: true}
+	queue := []pending{{id, spawnedSessions(partsByMsg)}}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		for _, child := range a.childSessions(id, parent.id) {
+			if seen[child.ID] || !isSubagent(child, parent.spawned) {
+				continue
+			}
+			seen[child.ID] = true
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			messages, partsByMsg, err := a.loadConversation(child.ID)
+			if err != nil {
+				continue
+			}
+			s := conversationStats(messages, partsByMsg)
+			subagents = append(subagents, adapters.SubagentStats{
+				ID:          child.ID,
+				Description: child.Title,
+				Messages:    s.UserMessages + s.AssistantMessages,
+				Totals:      s.Self(),
+			})
+			queue = append(queue, pending{child.ID, spawnedSessions(partsByMsg)})
+		}
+	}
+	stats.Rollup(subagents)
+	return stats, nil
+}
+
+// subagentTitle ends the titles OpenCode gives the sessions its task tool
+// starts, as in "Find auth code (@explore subagent)"
+var subagentTitle = regexp.MustCompile(`\(@[^()]+ subagent\)$`)
+
+// isSubagent reports whether a child session is a subagent rather than a
+// branch: its parent's task call recorded it, or it has a subagent title.
+// Branches point at their source as well, including those made before
+// branchedAt was recorded.
+func isSubagent(child sessionData, spawned map[string]bool) bool {
+	return spawned[child.ID] || subagentTitle.MatchString(child.Title)
+}
+
+// spawnedSessions returns the sessions a conversation's task calls ran in
+func spawnedSessions(partsByMsg map[string][]part) map[string]bool {
+	spawned := make(map[string]bool)
+	for _, parts := range partsByMsg {
+		for _, p := range parts {
+			if p.Type == "tool" && p.Tool == "task" && p.State.Metadata.SessionID != "" {
+				spawned[p.State.Metadata.SessionID] = true
+			}
+		}
+	}
+	return spawned
+}
+
+// childSessions returns the sessions pointing at parent, looked up in the
+// session directory of root. The directory is read the first time it is
+// needed after each listing.
+func (a *Adapter) childSessions(root, parent string) []sessionData {
+	path := a.GetSessionFile(root)
+	if path == "" {
+		return nil
+	}
+	dir := filepath.Dir(path)
+
+	a.childrenMu.Lock()
+	defer a.childrenMu.Unlock()
+	if a.children == nil {
+		a.children = make(map[string]map[string][]sessionData)
+	}
+	byParent, ok := a.children[dir]
+	if !ok {
+		byParent = readChildren(dir)
+		a.children[dir] = byParent
+	}
+	return byParent[parent]
+}
+
+// readChildren reads the sessions in dir that have a parent, by parent
+func readChildren(dir string) map[string][]sessionData {
+	byParent := make(map[string][]sessionData)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return byParent
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		var s sessionData
+		if err := json.Unmarshal(data, &s); err != nil || s.ParentID == "" {
+			continue
+		}
+		byParent[s.ParentID] = append(byParent[s.ParentID], s)
+	}
+	return byParent
+}
+
+// forgetChildren drops the child session index after sessions were added
+// or changed
+func (a *Adapter) forgetChildren() {
+	a.childrenMu.Lock()
+	a.children = nil
+	a.childrenMu.Unlock()
+}
+
+// GetDetails returns the figures the session cache keeps, all from one
+// read of the session and its messages and parts. Child sessions are left
+// out.
+func (a *Adapter) GetDetails(ctx context.Context, id string) (*adapters.SessionDetails, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	session, err := a.loadSession(id)
+	if err != nil {
+		return nil, err
+	}
+	messages, partsByMsg, err := a.loadConversation(id)
 	if err != nil {
 		return nil, err
 	}
 
+	d := &adapters.SessionDetails{
+		WorkDir: session.Directory,
+		Models:  modelsUsed(messages),
+	}
+	d.SetStats(conversationStats(messages, partsByMsg))
+	d.CountFiles(fileActivity(messages, partsByMsg))
+	for _, msg := range messages {
+		if msg.Time.Created <= 0 {
+			continue
+		}
+		t := time.Unix(msg.Time.Created/1000, 0)
+		if d.Start.IsZero() {
+			d.Start = t
+		}
+		d.End = t
+	}
+	return d, nil
+}
+
+// conversationStats counts the messages, usage and tool calls of a loaded
+// conversation
+func conversationStats(messages []messageData, partsByMsg map[string][]part) *adapters.Stats {
 	stats := &adapters.Stats{
 		ToolCalls: make(map[string]int),
 	}
 
+	// Messages interrupted before OpenCode totalled them only have usage
+	// on their step-finish parts
 	for _, msg := range messages {
 		switch msg.Role {
 		case "user":
 			stats.UserMessages++
 		case "assistant":
 			stats.AssistantMessages++
-			stats.InputTokens += msg.Tokens.Input
-			stats.OutputTokens += msg.Tokens.Output
-			stats.CacheRead += msg.Tokens.Cache.Read
-			stats.CacheWrite += msg.Tokens.Cache.Write
-			stats.Cost += msg.Cost
+			if msg.Tokens != (tokenCounts{}) {
+				addUsage(stats, msg)
+			}
 		}
-	}
 
-	for _, part := range parts {
-		if part.Type == "tool" && part.Tool != "" {
-			stats.ToolCalls[part.Tool]++
+		for _, p := range partsByMsg[msg.ID] {
+			switch {
+			case p.Type == "tool" && p.Tool != "":
+				stats.ToolCalls[p.Tool]++
+			case p.Type == "step-finish" && p.Tokens != nil:
+				if msg.Role == "assistant" && msg.Tokens == (tokenCounts{}) {
+					step := msg
+					step.Tokens, step.Cost = *p.Tokens, p.Cost
+					addUsage(stats, step)
+				}
+			}
 		}
 	}
 
-	return stats, nil
+	return stats
 }
 
 func (a *Adapter) GetFirstMessage(id string) (string, error) {
@@ -322,47 +589,25 @@ func (a *Adapter) GetFirstMessage(id string) (string, error) {
 	return "", nil
 }
 
-func (a *Adapter) ExportMessages(id string) ([]adapters.Message, error) {
-	messages, err := a.loadMessages(id)
-	if err != nil {
+func (a *Adapter) ExportMessages(ctx context.Context, id string) ([]adapters.Message, error) {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-
-	parts, err := a.loadParts(id)
+	messages, partsByMsg, err := a.loadConversation(id)
 	if err != nil {
 		return nil, err
 	}
 
-	partsByMsg := make(map[string][]part)
-	for _, p := range parts {
-		partsByMsg[p.MessageID] = append(partsByMsg[p.MessageID], p)
-	}
-
-	sort.Slice(messages, func(i, j int) bool {
-		return messages[i].Time.Created < messages[j].Time.Created
-	})
-
 	var result []adapters.Message
 	for _, msg := range messages {
 		m := adapters.Message{
+			ID:        msg.ID,
 			Role:      msg.Role,
 			Timestamp: msg.Time.Created / 1000,
 		}
 
-		msgParts := partsByMsg[msg.ID]
-		for _, p := range msgParts {
-			if p.Type == "text" {
-				m.Content += p.Text
-			} else if p.Type == "tool" {
-				tc := adapters.ToolCall{
-					ID:   p.CallID,
-					Name: p.Tool,
-				}
-				if input, err := json.Marshal(p.State.Input); err == nil {
-					tc.Input = string(input)
-				}
-				m.ToolCalls = append(m.ToolCalls, tc)
-			}
+		for _, p := range partsByMsg[msg.ID] {
+			addPart(&m, p)
 		}
 
 		result = append(result, m)
@@ -371,7 +616,141 @@ func (a *Adapter) ExportMessages(id string) ([]adapters.Message, error) {
 	return result, nil
 }
 
+// addPart adds one part to an exported message, both as a content block
+// and to the flat fields
+func addPart(m *adapters.Message, p part) {
+	switch p.Type {
+	case "text":
+		if p.Synthetic {
+			return // Injected context such as the contents of attached files
+		}
+		m.Content += p.Text
+		m.Blocks = append(m.Blocks, adapters.ContentBlock{Kind: adapters.BlockText, Text: p.Text})
+
+	case "reasoning":
+		if p.Text == "" {
+			return
+		}
+		if m.Thinking != "" {
+			m.Thinking += "\n\n"
+		}
+		m.Thinking += p.Text
+		m.Blocks = append(m.Blocks, adapters.ContentBlock{Kind: adapters.BlockThinking, Text: p.Text})
+
+	case "tool":
+		tc := adapters.ToolCall{
+			ID:    p.CallID,
+			Name:  p.Tool,
+			Input: rawJSON(p.State.Input),
+		}
+		m.ToolCalls = append(m.ToolCalls, tc)
+		m.Blocks = append(m.Blocks, adapters.ContentBlock{
+			Kind:      adapters.BlockToolUse,
+			ToolUseID: tc.ID,
+			Name:      tc.Name,
+			Input:     tc.Input,
+		})
+
+		// OpenCode keeps the call and its result in the same part; pending
+		// and running calls have no result yet
+		var tr adapters.ToolResult
+		switch p.State.Status {
+		case "completed":
+			tr = adapters.ToolResult{ToolUseID: tc.ID, Content: rawText(p.State.Output), Success: true}
+		case "error":
+			tr = adapters.ToolResult{ToolUseID: tc.ID, Content: p.State.Error}
+		default:
+			return
+		}
+		m.ToolResults = append(m.ToolResults, tr)
+		m.Blocks = append(m.Blocks, adapters.ContentBlock{
+			Kind:      adapters.BlockToolResult,
+			ToolUseID: tr.ToolUseID,
+			Text:      tr.Content,
+			IsError:   !tr.Success,
+		})
+
+	case "file":
+		if b, ok := fileImage(p); ok {
+			m.Blocks = append(m.Blocks, b)
+			return
+		}
+		name := p.Filename
+		if name == "" {
+			name = p.URL
+		}
+		m.Blocks = append(m.Blocks, adapters.ContentBlock{Kind: adapters.BlockText, Text: "📎 " + name})
+
+	case "patch":
+		if len(p.Files) > 0 {
+			m.Blocks = append(m.Blocks, adapters.ContentBlock{Kind: adapters.BlockText, Text: "_Changed " + strings.Join(p.Files, ", ") + "_"})
+		}
+
+	case "step-start", "step-finish":
+		// Step boundaries carry snapshots and usage, which stats read
+	}
+}
+
+// fileImage converts an attached image to an image block. OpenCode stores
+// attachments as data: URLs, or file: URLs for images on disk.
+func fileImage(p part) (adapters.ContentBlock, bool) {
+	if !strings.HasPrefix(p.Mime, "image/") {
+		return adapters.ContentBlock{}, false
+	}
+	b := adapters.ContentBlock{Kind: adapters.BlockImage, MediaType: p.Mime}
+	if rest, ok := strings.CutPrefix(p.URL, "data:"); ok {
+		if _, data, ok := strings.Cut(rest, ";base64,"); ok {
+			b.Data = data
+			return b, true
+		}
+	}
+	b.URL = p.URL
+	return b, b.URL != ""
+}
+
+// rawJSON returns a JSON value as text, or "" when it is absent
+func rawJSON(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
+
+// rawText returns a JSON string unquoted and any other value as JSON
+func rawText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	return rawJSON(raw)
+}
+
+// BranchSession copies a session with all its messages and parts
 func (a *Adapter) BranchSession(id string) (string, error) {
+	return a.branch(id, nil)
+}
+
+// BranchSessionAt copies a session with the messages up to and including
+// the given one
+func (a *Adapter) BranchSessionAt(id, at string) (string, error) {
+	messages, err := a.ExportMessages(context.Background(), id)
+	if err != nil {
+		return "", err
+	}
+	n, err := adapters.FindMessage(messages, at)
+	if err != nil {
+		return "", err
+	}
+	keep := make(map[string]bool, n+1)
+	for _, m := range messages[:n+1] {
+		keep[m.ID] = true
+	}
+	return a.branch(id, keep)
+}
+
+// branch copies the session, keeping only the messages in keep unless it
+// is nil
+func (a *Adapter) branch(id string, keep map[string]bool) (string, error) {
 	sessionPath := a.GetSessionFile(id)
 	if sessionPath == "" {
 		return "", os.ErrNotExist
@@ -398,6 +777,7 @@ func (a *Adapter) BranchSession(id string) (string, error) {
 
 	session["id"] = newSessionID
 	session["parentID"] = id
+	session["branchedAt"] = now
 	if timeObj, ok := session["time"].(map[string]interface{}); ok {
 		timeObj["created"] = now
 		timeObj["updated"] = now
@@ -415,6 +795,7 @@ func (a *Adapter) BranchSession(id string) (string, error) {
 	a.pathsMu.Lock()
 	a.sessionPaths[newSessionID] = newSessionPath
 	a.pathsMu.Unlock()
+	a.forgetChildren()
 
 	msgDir := filepath.Join(a.dataDir, "message", id)
 	entries, err := os.ReadDir(msgDir)
@@ -443,6 +824,9 @@ func (a *Adapter) BranchSession(id string) (string, error) {
 		}
 
 		oldMsgID, _ := msg["id"].(string)
+		if keep != nil && !keep[oldMsgID] {
+			continue
+		}
 		newMsgID := generateID("msg")
 		msgIDMap[oldMsgID] = newMsgID
 
@@ -497,6 +881,70 @@ func (a *Adapter) BranchSession(id string) (string, error) {
 	return newSessionID, nil
 }
 
+// RenameSession sets the session title. Other fields are kept as they
+// are, and so is the file's modification time.
+func (a *Adapter) RenameSession(id, title string) error {
+	path := a.GetSessionFile(id)
+	if path == "" {
+		return os.ErrNotExist
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var session map[string]interface{}
+	if err := json.Unmarshal(data, &session); err != nil {
+		return err
+	}
+	session["title"] = title
+
+	newBytes, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, newBytes, info.Mode().Perm()); err != nil {
+		return err
+	}
+	a.forgetChildren()
+	return os.Chtimes(path, info.ModTime(), info.ModTime())
+}
+
+// SessionFiles returns the session file, its message directory and the
+// part directory of every message
+func (a *Adapter) SessionFiles(id string) ([]string, error) {
+	path := a.GetSessionFile(id)
+	if path == "" {
+		return nil, os.ErrNotExist
+	}
+	files := []string{path}
+
+	msgDir := filepath.Join(a.dataDir, "message", id)
+	if entries, err := os.ReadDir(msgDir); err == nil {
+		files = append(files, msgDir)
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+				continue
+			}
+			partDir := filepath.Join(a.dataDir, "part", strings.TrimSuffix(entry.Name(), ".json"))
+			if _, err := os.Stat(partDir); err == nil {
+				files = append(files, partDir)
+			}
+		}
+	}
+
+	// Newer OpenCode versions keep a per-session diff summary
+	diff := filepath.Join(a.dataDir, "session_diff", id+".json")
+	if _, err := os.Stat(diff); err == nil {
+		files = append(files, diff)
+	}
+	return files, nil
+}
+
 func generateID(prefix string) string {
 	b := make([]byte, 8)
 	rand.Read(b)
@@ -504,12 +952,13 @@ func generateID(prefix string) string {
 }
 
 type sessionData struct {
-	ID        string `json:"id"`
-	ProjectID string `json:"projectID"`
-	Directory string `json:"directory"`
-	ParentID  string `json:"parentID,omitempty"`
-	Title     string `json:"title"`
-	Time      struct {
+	ID         string `json:"id"`
+	ProjectID  string `json:"projectID"`
+	Directory  string `json:"directory"`
+	ParentID   string `json:"parentID,omitempty"`
+	BranchedAt int64  `json:"branchedAt,omitempty"` // Set on copies made by BranchSession
+	Title      string `json:"title"`
+	Time       struct {
 		Created int64 `json:"created"`
 		Updated int64 `json:"updated"`
 	} `json:"time"`
@@ -523,17 +972,19 @@ type messageData struct {
 		Created   int64 `json:"created"`
 		Completed int64 `json:"completed"`
 	} `json:"time"`
-	ModelID string  `json:"modelID"`
-	Cost    float64 `json:"cost"`
-	Tokens  struct {
-		Input     int `json:"input"`
-		Output    int `json:"output"`
-		Reasoning int `json:"reasoning"`
-		Cache     struct {
-			Read  int `json:"read"`
-			Write int `json:"write"`
-		} `json:"cache"`
-	} `json:"tokens"`
+	ModelID string      `json:"modelID"`
+	Cost    float64     `json:"cost"`
+	Tokens  tokenCounts `json:"tokens"`
+}
+
+type tokenCounts struct {
+	Input     int `json:"input"`
+	Output    int `json:"output"`
+	Reasoning int `json:"reasoning"`
+	Cache     struct {
+		Read  int `json:"read"`
+		Write int `json:"write"`
+	} `json:"cache"`
 }
 
 type part struct {
@@ -542,17 +993,40 @@ type part struct {
 	MessageID string `json:"messageID"`
 	Type      string `json:"type"`
 	Text      string `json:"text,omitempty"`
+	Synthetic bool   `json:"synthetic,omitempty"` // text: added by OpenCode, not typed
 	CallID    string `json:"callID,omitempty"`
 	Tool      string `json:"tool,omitempty"`
 	State     struct {
-		Status string `json:"status"`
-		Input  struct {
-			FilePath  string `json:"filePath,omitempty"`
-			OldString string `json:"oldString,omitempty"`
-			NewString string `json:"newString,omitempty"`
-		} `json:"input"`
-		Output string `json:"output"`
+		Status   string          `json:"status"` // pending, running, completed or error
+		Input    json.RawMessage `json:"input"`
+		Output   json.RawMessage `json:"output"`
+		Error    string          `json:"error,omitempty"`
+		Metadata struct {
+			Exists    *bool  `json:"exists"`    // write: whether the file existed before
+			SessionID string `json:"sessionId"` // task: the session the subagent ran in
+		} `json:"metadata"`
 	} `json:"state,omitempty"`
+
+	// file parts
+	Mime     string `json:"mime,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	URL      string `json:"url,omitempty"`
+
+	// patch parts: files the step changed
+	Files []string `json:"files,omitempty"`
+
+	// step-finish parts: usage of the step
+	Cost   float64      `json:"cost,omitempty"`
+	Tokens *tokenCounts `json:"tokens,omitempty"`
+}
+
+// filePath returns the file a tool call named in its input
+func (p part) filePath() string {
+	var input struct {
+		FilePath string `json:"filePath"`
+	}
+	json.Unmarshal(p.State.Input, &input)
+	return input.FilePath
 }
 
 func (a *Adapter) loadSession(id string) (*sessionData, error) {
@@ -606,12 +1080,34 @@ func (a *Adapter) loadMessages(sessionID string) ([]messageData, error) {
 	return messages, nil
 }
 
+// loadConversation returns the messages in export order and their parts
+func (a *Adapter) loadConversation(id string) ([]messageData, map[string][]part, error) {
+	messages, err := a.loadMessages(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	partsByMsg := make(map[string][]part)
+	for _, p := range a.partsOf(messages) {
+		partsByMsg[p.MessageID] = append(partsByMsg[p.MessageID], p)
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].Time.Created < messages[j].Time.Created
+	})
+	return messages, partsByMsg, nil
+}
+
 func (a *Adapter) loadParts(sessionID string) ([]part, error) {
 	messages, err := a.loadMessages(sessionID)
 	if err != nil {
 		return nil, err
 	}
+	return a.partsOf(messages), nil
+}
 
+// partsOf reads the parts of messages
+func (a *Adapter) partsOf(messages []messageData) []part {
 	var parts []part
 	for _, msg := range messages {
 		partDir := filepath.Join(a.dataDir, "part", msg.ID)
@@ -639,7 +1135,30 @@ func (a *Adapter) loadParts(sessionID string) ([]part, error) {
 		}
 	}
 
-	return parts, nil
+	return parts
+}
+
+// addUsage adds a message's tokens and cost to stats
+func addUsage(stats *adapters.Stats, msg messageData) {
+	stats.InputTokens += msg.Tokens.Input
+	// OpenCode counts reasoning apart from output, but it is billed as
+	// output and the other providers include it there; ReasoningTokens
+	// breaks it out
+	stats.OutputTokens += msg.Tokens.Output + msg.Tokens.Reasoning
+	stats.ReasoningTokens += msg.Tokens.Reasoning
+	stats.CacheRead += msg.Tokens.Cache.Read
+	stats.CacheWrite += msg.Tokens.Cache.Write
+	stats.Cost += msgCost(msg)
+}
+
+// msgCost returns the cost OpenCode recorded for a message, or prices its
+// tokens when it recorded none (subscription providers report zero)
+func msgCost(msg messageData) float64 {
+	if msg.Cost != 0 {
+		return msg.Cost
+	}
+	t := msg.Tokens
+	return pricing.Cost(msg.ModelID, t.Input, t.Output+t.Reasoning, t.Cache.Read, t.Cache.Write)
 }
 
 func extractProject(dir string) string {
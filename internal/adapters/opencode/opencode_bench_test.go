@@ -1,6 +1,7 @@
 package opencode
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -12,7 +13,7 @@ func BenchmarkListSessions(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := a.ListSessions()
+		_, err := a.ListSessions(context.Background())
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -24,7 +25,7 @@ func BenchmarkGetSessionFile(b *testing.B) {
 	a := New(benchDataDir(b))
 
 	// Get a session ID from the test data
-	sessions, _ := a.ListSessions()
+	sessions, _ := a.ListSessions(context.Background())
 	if len(sessions) == 0 {
 		b.Skip("No test sessions found")
 	}
@@ -43,7 +44,7 @@ func BenchmarkGetSessionFile(b *testing.B) {
 func BenchmarkExtractMeta(b *testing.B) {
 	a := New(benchDataDir(b))
 
-	sessions, _ := a.ListSessions()
+	sessions, _ := a.ListSessions(context.Background())
 	if len(sessions) == 0 {
 		b.Skip("No test sessions found")
 	}
@@ -51,7 +52,7 @@ func BenchmarkExtractMeta(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := a.ExtractMeta(sessionID)
+		_, err := a.ExtractMeta(context.Background(), sessionID)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -80,7 +81,7 @@ func BenchmarkRealWorld_ListSessions(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		sessions, err := a.ListSessions()
+		sessions, err := a.ListSessions(context.Background())
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -98,7 +99,7 @@ func BenchmarkRealWorld_GetSessionFile(b *testing.B) {
 
 	a := New(dataDir)
 
-	sessions, err := a.ListSessions()
+	sessions, err := a.ListSessions(context.Background())
 	if err != nil || len(sessions) == 0 {
 		b.Skip("No sessions found")
 	}
@@ -123,7 +124,7 @@ func BenchmarkRealWorld_ExtractMeta(b *testing.B) {
 
 	a := New(dataDir)
 
-	sessions, err := a.ListSessions()
+	sessions, err := a.ListSessions(context.Background())
 	if err != nil || len(sessions) == 0 {
 		b.Skip("No sessions found")
 	}
@@ -131,7 +132,7 @@ func BenchmarkRealWorld_ExtractMeta(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := a.ExtractMeta(sessionID)
+		_, err := a.ExtractMeta(context.Background(), sessionID)
 		if err != nil {
 			b.Fatal(err)
 		}
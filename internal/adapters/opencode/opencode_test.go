@@ -1,11 +1,16 @@
 package opencode
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
 )
 
 func testDataDir(t *testing.T) string {
@@ -35,7 +40,7 @@ func TestNew(t *testing.T) {
 func TestListSessions(t *testing.T) {
 	a := setupTestAdapter(t)
 
-	sessions, err := a.ListSessions()
+	sessions, err := a.ListSessions(context.Background())
 	if err != nil {
 		t.Fatalf("ListSessions() error = %v", err)
 	}
@@ -72,7 +77,7 @@ func TestGetSessionFile(t *testing.T) {
 func TestExtractMeta(t *testing.T) {
 	a := setupTestAdapter(t)
 
-	meta, err := a.ExtractMeta("ses_abc123")
+	meta, err := a.ExtractMeta(context.Background(), "ses_abc123")
 	if err != nil {
 		t.Fatalf("ExtractMeta() error = %v", err)
 	}
@@ -94,7 +99,7 @@ func TestExtractMeta(t *testing.T) {
 func TestExtractMeta_Subagent(t *testing.T) {
 	a := setupTestAdapter(t)
 
-	meta, err := a.ExtractMeta("ses_subagent456")
+	meta, err := a.ExtractMeta(context.Background(), "ses_subagent456")
 	if err != nil {
 		t.Fatalf("ExtractMeta() error = %v", err)
 	}
@@ -165,7 +170,7 @@ func TestGetFilesTouched(t *testing.T) {
 func TestGetStats(t *testing.T) {
 	a := setupTestAdapter(t)
 
-	stats, err := a.GetStats("ses_abc123")
+	stats, err := a.GetStats(context.Background(), "ses_abc123")
 	if err != nil {
 		t.Fatalf("GetStats() error = %v", err)
 	}
@@ -183,10 +188,13 @@ func TestGetStats(t *testing.T) {
 		t.Errorf("InputTokens = %d, want %d", stats.InputTokens, expectedInput)
 	}
 
-	expectedOutput := 250 + 150 // msg_asst1 + msg_asst2
+	expectedOutput := 250 + 40 + 150 // msg_asst1 with its reasoning + msg_asst2
 	if stats.OutputTokens != expectedOutput {
 		t.Errorf("OutputTokens = %d, want %d", stats.OutputTokens, expectedOutput)
 	}
+	if stats.ReasoningTokens != 40 {
+		t.Errorf("ReasoningTokens = %d, want 40", stats.ReasoningTokens)
+	}
 
 	// Check tool calls
 	if stats.ToolCalls["edit"] != 1 {
@@ -194,6 +202,94 @@ func TestGetStats(t *testing.T) {
 	}
 }
 
+func TestGetStats_Subagents(t *testing.T) {
+	tmpDir := t.TempDir()
+	copyDir(t, testDataDir(t), tmpDir)
+	sessionDir := filepath.Join(tmpDir, "session", "proj_test123")
+
+	// The parent's task call records the session its subagent ran in
+	os.WriteFile(filepath.Join(tmpDir, "part", "msg_asst2", "prt_asst2_task.json"), []byte(`{
+  "id": "prt_asst2_task", "sessionID": "ses_abc123", "messageID": "msg_asst2",
+  "type": "tool", "callID": "toolu_002", "tool": "task",
+  "state": {"status": "completed", "input": {"description": "Finding auth patterns"}, "output": "Found them", "metadata": {"sessionId": "ses_subagent456"}}
+}`), 0644)
+	// A subagent of the subagent, known by its title only
+	os.WriteFile(filepath.Join(sessionDir, "ses_nested789.json"), []byte(`{"id":"ses_nested789","projectID":"proj_test123","parentID":"ses_subagent456","title":"Read the tests (@general subagent)","time":{"created":1734500300000,"updated":1734500300000}}`), 0644)
+	// A branch made before branchedAt was recorded
+	os.WriteFile(filepath.Join(sessionDir, "ses_oldbranch.json"), []byte(`{"id":"ses_oldbranch","projectID":"proj_test123","parentID":"ses_abc123","title":"Refactoring authentication module","time":{"created":1734500700000,"updated":1734500700000}}`), 0644)
+
+	a := New(tmpDir)
+
+	// A branch points at its source too, but isn't a subagent
+	if _, err := a.BranchSession("ses_abc123"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := a.GetStats(context.Background(), "ses_abc123")
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if len(stats.Subagents) != 2 || stats.Subagents[1].ID != "ses_nested789" {
+		t.Fatalf("Subagents = %+v, want ses_subagent456 and ses_nested789", stats.Subagents)
+	}
+	sub := stats.Subagents[0]
+	if sub.ID != "ses_subagent456" || sub.Description != "Background: Explore: Finding auth patterns" || sub.Messages != 2 {
+		t.Errorf("subagent = %+v", sub)
+	}
+	if sub.InputTokens != 400 || sub.CacheRead != 1000 || sub.Cost != 0.004 {
+		t.Errorf("subagent usage = %+v, want its own messages' usage", sub.Totals)
+	}
+
+	if stats.InputTokens != 1500+800 {
+		t.Errorf("InputTokens = %d, want the session's own tokens", stats.InputTokens)
+	}
+	if stats.Inclusive.InputTokens != 1500+800+400 {
+		t.Errorf("Inclusive.InputTokens = %d, want %d", stats.Inclusive.InputTokens, 1500+800+400)
+	}
+
+	// The nested subagent has no subagents
+	stats, err = a.GetStats(context.Background(), "ses_nested789")
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if len(stats.Subagents) != 0 || stats.Inclusive != stats.Self() {
+		t.Errorf("subagent stats = %+v, want no rollup", stats)
+	}
+}
+
+func TestGetDetails(t *testing.T) {
+	a := setupTestAdapter(t)
+	ctx := context.Background()
+
+	d, err := a.GetDetails(ctx, "ses_abc123")
+	if err != nil {
+		t.Fatalf("GetDetails() error = %v", err)
+	}
+	stats, _ := a.GetStats(ctx, "ses_abc123")
+	if d.UserMessages != stats.UserMessages || d.AssistantMessages != stats.AssistantMessages || d.Usage != stats.Self() {
+		t.Errorf("GetDetails() = %+v, want the counts and usage of GetStats", d)
+	}
+	info, _ := a.GetSessionInfo("ses_abc123")
+	if d.WorkDir != info.WorkDir {
+		t.Errorf("WorkDir = %q, want %q", d.WorkDir, info.WorkDir)
+	}
+	models, _ := a.GetModels("ses_abc123")
+	if strings.Join(d.Models, ",") != strings.Join(models, ",") {
+		t.Errorf("Models = %v, want %v", d.Models, models)
+	}
+	activity, _ := a.GetFileActivity(ctx, "ses_abc123")
+	if d.FilesRead+d.FilesChanged != len(activity) || d.FilesChanged != len(adapters.ChangedFiles(activity)) {
+		t.Errorf("FilesRead, FilesChanged = %d, %d, want them to cover %+v", d.FilesRead, d.FilesChanged, activity)
+	}
+
+	messages, _ := a.ExportMessages(ctx, "ses_abc123")
+	var want adapters.SessionDetails
+	want.SetSpan(messages)
+	if d.Start.IsZero() || !d.Start.Equal(want.Start) || !d.End.Equal(want.End) {
+		t.Errorf("Start, End = %v, %v, want %v, %v", d.Start, d.End, want.Start, want.End)
+	}
+}
+
 func TestGetFirstMessage(t *testing.T) {
 	a := setupTestAdapter(t)
 
@@ -211,7 +307,7 @@ func TestGetFirstMessage(t *testing.T) {
 func TestExportMessages(t *testing.T) {
 	a := setupTestAdapter(t)
 
-	messages, err := a.ExportMessages("ses_abc123")
+	messages, err := a.ExportMessages(context.Background(), "ses_abc123")
 	if err != nil {
 		t.Fatalf("ExportMessages() error = %v", err)
 	}
@@ -239,6 +335,199 @@ func TestExportMessages(t *testing.T) {
 	if !foundToolCall {
 		t.Error("ExportMessages() did not capture any tool calls")
 	}
+
+	// Reasoning parts become the message's thinking, not its content
+	if messages[1].Thinking != "The user wants a refactor. I should read auth.ts before changing anything." {
+		t.Errorf("messages[1].Thinking = %q", messages[1].Thinking)
+	}
+	if strings.Contains(messages[1].Content, "I should read auth.ts") {
+		t.Errorf("messages[1].Content = %q, should not include the reasoning", messages[1].Content)
+	}
+
+	// Parts keep their order, and tool parts carry their result
+	var kinds []adapters.BlockKind
+	for _, b := range messages[1].Blocks {
+		kinds = append(kinds, b.Kind)
+	}
+	want := []adapters.BlockKind{adapters.BlockThinking, adapters.BlockText, adapters.BlockToolUse, adapters.BlockToolResult}
+	if len(kinds) != len(want) {
+		t.Fatalf("block kinds = %v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Errorf("block kinds = %v, want %v", kinds, want)
+			break
+		}
+	}
+	if r := messages[1].Blocks[3]; r.Text != "File edited successfully" || r.IsError || r.ToolUseID != "toolu_001" {
+		t.Errorf("result block = %+v", r)
+	}
+}
+
+// setupPartsSession writes a session whose assistant message has one part
+// of every kind, and an interrupted message with usage only on its steps
+func setupPartsSession(t *testing.T) *Adapter {
+	t.Helper()
+	return writeStorage(t, map[string]string{
+		"session/proj_p/ses_parts.json": `{"id":"ses_parts","projectID":"proj_p","directory":"/w/app","title":"Parts","time":{"created":1,"updated":2}}`,
+
+		"message/ses_parts/msg_1.json": `{"id":"msg_1","sessionID":"ses_parts","role":"user","time":{"created":1000}}`,
+		"part/msg_1/prt_1a.json":       `{"id":"prt_1a","messageID":"msg_1","type":"text","text":"What does this show?"}`,
+		"part/msg_1/prt_1b.json":       `{"id":"prt_1b","messageID":"msg_1","type":"file","mime":"image/png","filename":"shot.png","url":"data:image/png;base64,iVBORw0KGgo="}`,
+		"part/msg_1/prt_1c.json":       `{"id":"prt_1c","messageID":"msg_1","type":"text","text":"Called the Read tool","synthetic":true}`,
+		"message/ses_parts/msg_2.json": `{"id":"msg_2","sessionID":"ses_parts","role":"assistant","modelID":"claude-sonnet-4","time":{"created":2000},"cost":0.01,"tokens":{"input":100,"output":20,"reasoning":0,"cache":{"read":0,"write":0}}}`,
+		"part/msg_2/prt_2a.json":       `{"id":"prt_2a","messageID":"msg_2","type":"step-start","snapshot":"abc"}`,
+		"part/msg_2/prt_2b.json":       `{"id":"prt_2b","messageID":"msg_2","type":"tool","callID":"c1","tool":"bash","state":{"status":"completed","input":{"command":"go test ./...","description":"Run tests"},"output":"ok  app 0.1s"}}`,
+		"part/msg_2/prt_2c.json":       `{"id":"prt_2c","messageID":"msg_2","type":"tool","callID":"c2","tool":"grep","state":{"status":"error","input":{"pattern":"TODO"},"error":"ripgrep not found"}}`,
+		"part/msg_2/prt_2d.json":       `{"id":"prt_2d","messageID":"msg_2","type":"tool","callID":"c3","tool":"read","state":{"status":"running","input":{"filePath":"/w/app/main.go"}}}`,
+		"part/msg_2/prt_2e.json":       `{"id":"prt_2e","messageID":"msg_2","type":"patch","hash":"def","files":["/w/app/gen.go"]}`,
+		"part/msg_2/prt_2f.json":       `{"id":"prt_2f","messageID":"msg_2","type":"step-finish","reason":"tool-calls","cost":0.01,"tokens":{"input":100,"output":20,"reasoning":0,"cache":{"read":0,"write":0}}}`,
+		"message/ses_parts/msg_3.json": `{"id":"msg_3","sessionID":"ses_parts","role":"assistant","modelID":"claude-sonnet-4","time":{"created":3000},"cost":0,"tokens":{"input":0,"output":0,"reasoning":0,"cache":{"read":0,"write":0}}}`,
+		"part/msg_3/prt_3a.json":       `{"id":"prt_3a","messageID":"msg_3","type":"step-finish","reason":"stop","cost":0.002,"tokens":{"input":50,"output":5,"reasoning":0,"cache":{"read":0,"write":0}}}`,
+	})
+}
+
+// writeStorage creates an OpenCode storage directory from relative paths
+// and file contents
+func writeStorage(t *testing.T, files map[string]string) *Adapter {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return New(dir)
+}
+
+func TestExportMessages_Parts(t *testing.T) {
+	a := setupPartsSession(t)
+
+	messages, err := a.ExportMessages(context.Background(), "ses_parts")
+	if err != nil {
+		t.Fatalf("ExportMessages() error = %v", err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("ExportMessages() returned %d messages, want 3", len(messages))
+	}
+
+	user := messages[0]
+	if user.Content != "What does this show?" {
+		t.Errorf("user Content = %q, want only the typed text", user.Content)
+	}
+	if len(user.Blocks) != 2 || user.Blocks[1].Kind != adapters.BlockImage || user.Blocks[1].Data != "iVBORw0KGgo=" {
+		t.Errorf("user Blocks = %+v, want text then the attached image", user.Blocks)
+	}
+
+	asst := messages[1]
+	if len(asst.ToolCalls) != 3 {
+		t.Fatalf("ToolCalls = %+v, want 3", asst.ToolCalls)
+	}
+	if asst.ToolCalls[0].Input != `{"command":"go test ./...","description":"Run tests"}` {
+		t.Errorf("bash input = %q, want the raw JSON", asst.ToolCalls[0].Input)
+	}
+	if asst.ToolCalls[1].Input != `{"pattern":"TODO"}` {
+		t.Errorf("grep input = %q, want the raw JSON", asst.ToolCalls[1].Input)
+	}
+
+	// The running call has no result yet
+	if len(asst.ToolResults) != 2 {
+		t.Fatalf("ToolResults = %+v, want 2", asst.ToolResults)
+	}
+	if r := asst.ToolResults[0]; r.ToolUseID != "c1" || r.Content != "ok  app 0.1s" || !r.Success {
+		t.Errorf("completed result = %+v", r)
+	}
+	if r := asst.ToolResults[1]; r.ToolUseID != "c2" || r.Content != "ripgrep not found" || r.Success {
+		t.Errorf("failed result = %+v", r)
+	}
+
+	last := asst.Blocks[len(asst.Blocks)-1]
+	if last.Kind != adapters.BlockText || !strings.Contains(last.Text, "/w/app/gen.go") {
+		t.Errorf("last block = %+v, want the patch's changed files", last)
+	}
+}
+
+func TestGetStats_StepFinish(t *testing.T) {
+	a := setupPartsSession(t)
+
+	stats, err := a.GetStats(context.Background(), "ses_parts")
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	// msg_2's own totals, plus msg_3's which only its step recorded
+	if stats.InputTokens != 150 || stats.OutputTokens != 25 {
+		t.Errorf("tokens = %d in / %d out, want 150 / 25", stats.InputTokens, stats.OutputTokens)
+	}
+	if stats.Cost < 0.0119 || stats.Cost > 0.0121 {
+		t.Errorf("Cost = %f, want 0.012", stats.Cost)
+	}
+	if stats.ToolCalls["bash"] != 1 || stats.ToolCalls["grep"] != 1 {
+		t.Errorf("ToolCalls = %v", stats.ToolCalls)
+	}
+}
+
+func TestGetFilesTouched_Patch(t *testing.T) {
+	a := setupPartsSession(t)
+
+	files, err := a.GetFilesTouched("ses_parts")
+	if err != nil {
+		t.Fatalf("GetFilesTouched() error = %v", err)
+	}
+	if len(files) != 1 || files[0] != "/w/app/gen.go" {
+		t.Errorf("GetFilesTouched() = %v, want the patched file", files)
+	}
+}
+
+func TestGetFileActivity(t *testing.T) {
+	a := writeStorage(t, map[string]string{
+		"session/proj_f/ses_files.json": `{"id":"ses_files","projectID":"proj_f","directory":"/w","title":"Files","time":{"created":1,"updated":2}}`,
+		"message/ses_files/msg_1.json":  `{"id":"msg_1","sessionID":"ses_files","role":"user","time":{"created":1000}}`,
+		"message/ses_files/msg_2.json":  `{"id":"msg_2","sessionID":"ses_files","role":"assistant","time":{"created":2000}}`,
+		"part/msg_2/prt_a.json":         `{"id":"prt_a","messageID":"msg_2","type":"tool","tool":"read","state":{"status":"completed","input":{"filePath":"/w/main.go"}}}`,
+		"part/msg_2/prt_b.json":         `{"id":"prt_b","messageID":"msg_2","type":"tool","tool":"write","state":{"status":"completed","input":{"filePath":"/w/new.go"},"metadata":{"exists":false}}}`,
+		"part/msg_2/prt_c.json":         `{"id":"prt_c","messageID":"msg_2","type":"tool","tool":"edit","state":{"status":"error","input":{"filePath":"/w/bad.go"},"error":"no match"}}`,
+		"part/msg_2/prt_d.json":         `{"id":"prt_d","messageID":"msg_2","type":"patch","files":["/w/new.go","/w/gen.go"]}`,
+		"message/ses_files/msg_3.json":  `{"id":"msg_3","sessionID":"ses_files","role":"assistant","time":{"created":3000}}`,
+		"part/msg_3/prt_e.json":         `{"id":"prt_e","messageID":"msg_3","type":"tool","tool":"multiedit","state":{"status":"completed","input":{"filePath":"/w/main.go"}}}`,
+		"part/msg_3/prt_f.json":         `{"id":"prt_f","messageID":"msg_3","type":"tool","tool":"patch","state":{"status":"completed","input":{"patchText":"*** Begin Patch\n*** Delete File: /w/old.go\n*** Update File: /w/main.go\n@@\n*** End Patch"}}}`,
+	})
+
+	activity, err := a.GetFileActivity(context.Background(), "ses_files")
+	if err != nil {
+		t.Fatalf("GetFileActivity() error = %v", err)
+	}
+	got := make(map[string]adapters.FileActivity)
+	for _, f := range activity {
+		got[f.Path] = f
+	}
+	if len(got) != 4 {
+		t.Fatalf("GetFileActivity() = %+v, want 4 files", activity)
+	}
+
+	main := got["/w/main.go"]
+	if main.Ops[adapters.FileRead] != 1 || main.Ops[adapters.FileModify] != 2 || main.FirstTurn != 1 || main.LastTurn != 2 {
+		t.Errorf("main.go = %+v, want read once and modified twice over turns 1-2", main)
+	}
+	if f := got["/w/new.go"]; f.Ops[adapters.FileCreate] != 1 || f.Ops[adapters.FileModify] != 0 {
+		t.Errorf("new.go = %+v, want created once; the patch part repeats the write", f)
+	}
+	if f := got["/w/gen.go"]; f.Ops[adapters.FileModify] != 1 {
+		t.Errorf("gen.go = %+v, want modified through the patch part", f)
+	}
+	if f := got["/w/old.go"]; f.Ops[adapters.FileDelete] != 1 || f.FirstTurn != 2 {
+		t.Errorf("old.go = %+v, want deleted by the patch tool", f)
+	}
+
+	files, err := a.GetFilesTouched("ses_files")
+	if err != nil {
+		t.Fatalf("GetFilesTouched() error = %v", err)
+	}
+	want := []string{"/w/gen.go", "/w/main.go", "/w/new.go", "/w/old.go"}
+	if strings.Join(files, ",") != strings.Join(want, ",") {
+		t.Errorf("GetFilesTouched() = %v, want %v", files, want)
+	}
 }
 
 func TestResumeCmd(t *testing.T) {
@@ -327,6 +616,45 @@ func TestBranchSession(t *testing.T) {
 	}
 }
 
+func TestBranchSessionAt(t *testing.T) {
+	tmpDir := t.TempDir()
+	copyDir(t, testDataDir(t), tmpDir)
+	a := New(tmpDir)
+
+	original, err := a.ExportMessages(context.Background(), "ses_abc123")
+	if err != nil {
+		t.Fatalf("ExportMessages() error = %v", err)
+	}
+
+	// By message ID and by index
+	for _, at := range []string{original[1].ID, "1"} {
+		newID, err := a.BranchSessionAt("ses_abc123", at)
+		if err != nil {
+			t.Fatalf("BranchSessionAt(%q) error = %v", at, err)
+		}
+
+		messages, err := a.ExportMessages(context.Background(), newID)
+		if err != nil {
+			t.Fatalf("ExportMessages() error = %v", err)
+		}
+		if len(messages) != 2 {
+			t.Fatalf("branch at %q has %d messages, want 2", at, len(messages))
+		}
+		for i, m := range messages {
+			if m.Role != original[i].Role || m.Content != original[i].Content {
+				t.Errorf("message %d = %+v, want a copy of %+v", i, m, original[i])
+			}
+			if m.ID == original[i].ID {
+				t.Errorf("message %d kept its original ID %s", i, m.ID)
+			}
+		}
+	}
+
+	if _, err := a.BranchSessionAt("ses_abc123", "msg_nope"); !errors.Is(err, adapters.ErrMessageNotFound) {
+		t.Errorf("BranchSessionAt(unknown) error = %v, want ErrMessageNotFound", err)
+	}
+}
+
 func copyDir(t *testing.T, src, dst string) {
 	t.Helper()
 	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
@@ -355,7 +683,7 @@ func copyDir(t *testing.T, src, dst string) {
 func TestListSessions_PopulatesPathCache(t *testing.T) {
 	a := setupTestAdapter(t)
 
-	sessions, err := a.ListSessions()
+	sessions, err := a.ListSessions(context.Background())
 	if err != nil {
 		t.Fatalf("ListSessions() error = %v", err)
 	}
@@ -374,7 +702,7 @@ func TestGetSessionFile_UsesCache(t *testing.T) {
 	a := setupTestAdapter(t)
 
 	// Populate cache
-	sessions, _ := a.ListSessions()
+	sessions, _ := a.ListSessions(context.Background())
 	if len(sessions) == 0 {
 		t.Skip("No test sessions")
 	}
@@ -392,7 +720,7 @@ func TestGetSessionFile_UsesCache(t *testing.T) {
 func TestPathCache_ThreadSafety(t *testing.T) {
 	a := setupTestAdapter(t)
 
-	sessions, _ := a.ListSessions()
+	sessions, _ := a.ListSessions(context.Background())
 	if len(sessions) == 0 {
 		t.Skip("No sessions for concurrency test")
 	}
@@ -409,3 +737,64 @@ func TestPathCache_ThreadSafety(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestSessionFiles(t *testing.T) {
+	a := setupTestAdapter(t)
+	dataDir := testDataDir(t)
+
+	files, err := a.SessionFiles("ses_abc123")
+	if err != nil {
+		t.Fatalf("SessionFiles() error = %v", err)
+	}
+
+	want := map[string]bool{
+		filepath.Join(dataDir, "session", "proj_test123", "ses_abc123.json"): true,
+		filepath.Join(dataDir, "message", "ses_abc123"):                      true,
+		filepath.Join(dataDir, "part", "msg_user1"):                          true,
+		filepath.Join(dataDir, "part", "msg_asst1"):                          true,
+		filepath.Join(dataDir, "part", "msg_user2"):                          true,
+		filepath.Join(dataDir, "part", "msg_asst2"):                          true,
+	}
+	if len(files) != len(want) {
+		t.Fatalf("SessionFiles() = %v, want %d paths", files, len(want))
+	}
+	for _, f := range files {
+		if !want[f] {
+			t.Errorf("SessionFiles() returned unexpected path %s", f)
+		}
+	}
+}
+
+func TestRenameSession(t *testing.T) {
+	dataDir := t.TempDir()
+	sessionDir := filepath.Join(dataDir, "session", "proj_test123")
+	os.MkdirAll(sessionDir, 0755)
+
+	original, err := os.ReadFile(filepath.Join(testDataDir(t), "session", "proj_test123", "ses_abc123.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(sessionDir, "ses_abc123.json")
+	os.WriteFile(path, original, 0644)
+
+	a := New(dataDir)
+	before, err := a.ExtractMeta(context.Background(), "ses_abc123")
+	if err != nil {
+		t.Fatalf("ExtractMeta() error = %v", err)
+	}
+
+	if err := a.RenameSession("ses_abc123", "Renamed session"); err != nil {
+		t.Fatalf("RenameSession() error = %v", err)
+	}
+
+	after, err := a.ExtractMeta(context.Background(), "ses_abc123")
+	if err != nil {
+		t.Fatalf("ExtractMeta() error = %v", err)
+	}
+	if after.Summary != "Renamed session" {
+		t.Errorf("Summary = %q, want %q", after.Summary, "Renamed session")
+	}
+	if !after.Date.Equal(before.Date) || after.Project != before.Project {
+		t.Errorf("ExtractMeta() = %+v, want other fields unchanged from %+v", after, before)
+	}
+}
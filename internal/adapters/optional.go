@@ -0,0 +1,252 @@
+package adapters
+
+// Helpers for the optional capability interfaces. Each one uses the
+// provider's implementation when present and otherwise falls back to
+// something derived from the core interface, so callers never need to
+// special-case a provider.
+
+import (
+	"context"
+	"errors"
+)
+
+// errStop ends a stream early once a helper has what it needs
+var errStop = errors.New("stop streaming")
+
+// Supports reports whether the adapter can perform an action on session
+// id, or on some session when id is empty
+func Supports(a Adapter, id string, c Capability) bool {
+	if s, ok := a.(CapabilityChecker); ok {
+		return s.Supports(id, c)
+	}
+	var ok bool
+	switch c {
+	case CanBranch:
+		_, ok = a.(Brancher)
+	case CanBranchAt:
+		_, ok = a.(PointBrancher)
+	case CanDelete:
+		_, ok = a.(Deleter)
+	case CanRename:
+		_, ok = a.(Renamer)
+	}
+	return ok
+}
+
+// BranchSession branches a session, or returns ErrNotSupported
+func BranchSession(a Adapter, id string) (string, error) {
+	b, ok := a.(Brancher)
+	if !ok || !Supports(a, id, CanBranch) {
+		return "", ErrNotSupported
+	}
+	return b.BranchSession(id)
+}
+
+// BranchSessionAt branches a session at a message, or returns
+// ErrNotSupported
+func BranchSessionAt(a Adapter, id, at string) (string, error) {
+	b, ok := a.(PointBrancher)
+	if !ok || !Supports(a, id, CanBranchAt) {
+		return "", ErrNotSupported
+	}
+	return b.BranchSessionAt(id, at)
+}
+
+// SessionFiles lists the files that make up a session, or returns
+// ErrNotSupported
+func SessionFiles(a Adapter, id string) ([]string, error) {
+	d, ok := a.(Deleter)
+	if !ok || !Supports(a, id, CanDelete) {
+		return nil, ErrNotSupported
+	}
+	return d.SessionFiles(id)
+}
+
+// RenameSession stores a custom title, or returns ErrNotSupported
+func RenameSession(a Adapter, id, title string) error {
+	r, ok := a.(Renamer)
+	if !ok || !Supports(a, id, CanRename) {
+		return ErrNotSupported
+	}
+	return r.RenameSession(id, title)
+}
+
+// StreamMessages calls fn for every message in order, falling back to a
+// full export for providers that cannot stream
+func StreamMessages(ctx context.Context, a Adapter, id string, fn func(Message) error) error {
+	if s, ok := a.(Streamer); ok {
+		return s.StreamMessages(ctx, id, fn)
+	}
+	messages, err := a.ExportMessages(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, m := range messages {
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetForks returns the conversation paths inside a session, or nil when
+// the provider stores a single path per session
+func GetForks(a Adapter, id string) ([]Fork, error) {
+	if g, ok := a.(ForksGetter); ok {
+		return g.GetForks(id)
+	}
+	return nil, nil
+}
+
+// GetSummaries returns topic summaries, or nil when the provider has none
+func GetSummaries(a Adapter, id string) ([]string, error) {
+	if g, ok := a.(SummariesGetter); ok {
+		return g.GetSummaries(id)
+	}
+	return nil, nil
+}
+
+// GetFilesTouched returns modified files, or nil when the provider can't tell
+func GetFilesTouched(a Adapter, id string) ([]string, error) {
+	if g, ok := a.(FilesTouchedGetter); ok {
+		return g.GetFilesTouched(id)
+	}
+	if g, ok := a.(FileActivityGetter); ok {
+		activity, err := g.GetFileActivity(context.Background(), id)
+		if err != nil {
+			return nil, err
+		}
+		return ChangedFiles(activity), nil
+	}
+	return nil, nil
+}
+
+// GetFileActivity returns what the session did to each file. Providers that
+// only report modified files get each of them as a single modify with
+// unknown turns; nil means the provider can't tell.
+func GetFileActivity(ctx context.Context, a Adapter, id string) ([]FileActivity, error) {
+	if g, ok := a.(FileActivityGetter); ok {
+		return g.GetFileActivity(ctx, id)
+	}
+	files, err := GetFilesTouched(a, id)
+	if err != nil || files == nil {
+		return nil, err
+	}
+	var r FileRecorder
+	for _, f := range files {
+		r.Record(f, FileModify, -1)
+	}
+	return r.Activity(), nil
+}
+
+// GetSlashCommands returns slash commands, or nil when the provider has none
+func GetSlashCommands(a Adapter, id string) ([]string, error) {
+	if g, ok := a.(SlashCommandsGetter); ok {
+		return g.GetSlashCommands(id)
+	}
+	return nil, nil
+}
+
+// GetModels returns model names, or nil when the provider doesn't record them
+func GetModels(a Adapter, id string) ([]string, error) {
+	if g, ok := a.(ModelsGetter); ok {
+		return g.GetModels(id)
+	}
+	return nil, nil
+}
+
+// GetStats returns session statistics. Without provider support, message
+// and tool call counts are derived from the exported messages.
+func GetStats(ctx context.Context, a Adapter, id string) (*Stats, error) {
+	if g, ok := a.(StatsGetter); ok {
+		stats, err := g.GetStats(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		// Providers without subagents may leave the inclusive totals unset
+		if stats.Subagents == nil && stats.Inclusive == (Totals{}) {
+			stats.Inclusive = stats.Self()
+		}
+		return stats, nil
+	}
+
+	stats := &Stats{ToolCalls: make(map[string]int)}
+	err := StreamMessages(ctx, a, id, func(m Message) error {
+		switch m.Role {
+		case "user":
+			stats.UserMessages++
+		case "assistant":
+			stats.AssistantMessages++
+		}
+		for _, tc := range m.ToolCalls {
+			stats.ToolCalls[tc.Name]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetDetails returns the figures the session cache keeps. Without provider
+// support they come from the other optional lookups and a single export.
+func GetDetails(ctx context.Context, a Adapter, id string) (*SessionDetails, error) {
+	if g, ok := a.(DetailsGetter); ok {
+		return g.GetDetails(ctx, id)
+	}
+
+	messages, err := a.ExportMessages(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	d := &SessionDetails{}
+	d.SetSpan(messages)
+	if info, err := a.GetSessionInfo(id); err == nil && info != nil {
+		d.WorkDir = info.WorkDir
+		d.Branch = info.Branch
+	}
+	d.Models, _ = GetModels(a, id)
+	if g, ok := a.(StatsGetter); ok {
+		if s, err := g.GetStats(ctx, id); err == nil {
+			d.SetStats(s)
+		}
+	} else {
+		for _, m := range messages {
+			switch m.Role {
+			case "user":
+				d.UserMessages++
+			case "assistant":
+				d.AssistantMessages++
+			}
+		}
+	}
+	if activity, err := GetFileActivity(ctx, a, id); err == nil {
+		d.CountFiles(activity)
+	}
+	return d, nil
+}
+
+// GetFirstMessage returns the first user prompt, falling back to the first
+// user message in the export. ctx only bounds the fallback.
+func GetFirstMessage(ctx context.Context, a Adapter, id string) (string, error) {
+	if g, ok := a.(FirstMessageGetter); ok {
+		return g.GetFirstMessage(id)
+	}
+
+	var first string
+	err := StreamMessages(ctx, a, id, func(m Message) error {
+		if m.Role == "user" && m.Content != "" {
+			first = m.Content
+			return errStop
+		}
+		return nil
+	})
+	if err != nil && err != errStop {
+		return "", err
+	}
+	if len(first) > 200 {
+		first = first[:197] + "..."
+	}
+	return first, nil
+}
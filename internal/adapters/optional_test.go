@@ -0,0 +1,275 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// coreAdapter implements only the core interface
+type coreAdapter struct {
+	Adapter
+	messages []Message
+}
+
+func (c *coreAdapter) ExportMessages(ctx context.Context, id string) ([]Message, error) {
+	return c.messages, nil
+}
+
+func newCoreAdapter() *coreAdapter {
+	return &coreAdapter{messages: []Message{
+		{Role: "assistant", Content: "Hi, what are we building?"},
+		{Role: "user", Content: "A parser"},
+		{Role: "assistant", ToolCalls: []ToolCall{{Name: "Read"}, {Name: "Edit"}, {Name: "Read"}}},
+		{Role: "user", Content: "Thanks"},
+	}}
+}
+
+func TestGetStats_Fallback(t *testing.T) {
+	stats, err := GetStats(context.Background(), newCoreAdapter(), "x")
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+
+	if stats.UserMessages != 2 || stats.AssistantMessages != 2 {
+		t.Errorf("messages = %d user / %d assistant, want 2 / 2", stats.UserMessages, stats.AssistantMessages)
+	}
+	if stats.ToolCalls["Read"] != 2 || stats.ToolCalls["Edit"] != 1 {
+		t.Errorf("ToolCalls = %v, want Read:2 Edit:1", stats.ToolCalls)
+	}
+}
+
+func TestGetStats_InclusiveDefaultsToSelf(t *testing.T) {
+	a := &statsAdapter{newCoreAdapter()}
+	stats, err := GetStats(context.Background(), a, "s1")
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if stats.Inclusive != stats.Self() {
+		t.Errorf("Inclusive = %+v, want the session's own usage %+v", stats.Inclusive, stats.Self())
+	}
+}
+
+// statsAdapter reports usage but knows nothing about subagents
+type statsAdapter struct{ *coreAdapter }
+
+func (s *statsAdapter) GetStats(ctx context.Context, id string) (*Stats, error) {
+	return &Stats{InputTokens: 42, Cost: 0.1}, nil
+}
+
+func TestGetFirstMessage_Fallback(t *testing.T) {
+	msg, err := GetFirstMessage(context.Background(), newCoreAdapter(), "x")
+	if err != nil {
+		t.Fatalf("GetFirstMessage() error = %v", err)
+	}
+	if msg != "A parser" {
+		t.Errorf("GetFirstMessage() = %q, want %q", msg, "A parser")
+	}
+}
+
+func TestOptionalGetters_Fallback(t *testing.T) {
+	a := newCoreAdapter()
+
+	if s, err := GetSummaries(a, "x"); s != nil || err != nil {
+		t.Errorf("GetSummaries() = %v, %v; want nil, nil", s, err)
+	}
+	if f, err := GetFilesTouched(a, "x"); f != nil || err != nil {
+		t.Errorf("GetFilesTouched() = %v, %v; want nil, nil", f, err)
+	}
+	if f, err := GetFileActivity(context.Background(), a, "x"); f != nil || err != nil {
+		t.Errorf("GetFileActivity() = %v, %v; want nil, nil", f, err)
+	}
+	if c, err := GetSlashCommands(a, "x"); c != nil || err != nil {
+		t.Errorf("GetSlashCommands() = %v, %v; want nil, nil", c, err)
+	}
+	if m, err := GetModels(a, "x"); m != nil || err != nil {
+		t.Errorf("GetModels() = %v, %v; want nil, nil", m, err)
+	}
+}
+
+type filesAdapter struct{ *coreAdapter }
+
+func (f *filesAdapter) GetFilesTouched(id string) ([]string, error) {
+	return []string{"/a.go"}, nil
+}
+
+type activityAdapter struct{ *coreAdapter }
+
+func (f *activityAdapter) GetFileActivity(ctx context.Context, id string) ([]FileActivity, error) {
+	var r FileRecorder
+	r.Record("/b.go", FileRead, 0)
+	r.Record("/a.go", FileModify, 2)
+	return r.Activity(), nil
+}
+
+func TestGetFileActivity_FromFilesTouched(t *testing.T) {
+	activity, err := GetFileActivity(context.Background(), &filesAdapter{newCoreAdapter()}, "x")
+	if err != nil {
+		t.Fatalf("GetFileActivity() error = %v", err)
+	}
+	if len(activity) != 1 || activity[0].Path != "/a.go" || activity[0].Ops[FileModify] != 1 || activity[0].FirstTurn != -1 {
+		t.Errorf("GetFileActivity() = %+v, want /a.go modified at an unknown turn", activity)
+	}
+}
+
+func TestGetFilesTouched_FromActivity(t *testing.T) {
+	files, err := GetFilesTouched(&activityAdapter{newCoreAdapter()}, "x")
+	if err != nil {
+		t.Fatalf("GetFilesTouched() error = %v", err)
+	}
+	if len(files) != 1 || files[0] != "/a.go" {
+		t.Errorf("GetFilesTouched() = %v, want only the modified file", files)
+	}
+}
+
+// detailsAdapter has session info and file activity but no stats
+type detailsAdapter struct{ activityAdapter }
+
+func (d *detailsAdapter) GetSessionInfo(id string) (*SessionInfo, error) {
+	return &SessionInfo{ID: id, WorkDir: "/w", Branch: "main"}, nil
+}
+
+func TestGetDetails_Fallback(t *testing.T) {
+	core := newCoreAdapter()
+	core.messages[0].Timestamp = 1736932320
+	core.messages[3].Timestamp = 1736935080
+
+	d, err := GetDetails(context.Background(), &detailsAdapter{activityAdapter{core}}, "x")
+	if err != nil {
+		t.Fatalf("GetDetails() error = %v", err)
+	}
+	if d.WorkDir != "/w" || d.Branch != "main" {
+		t.Errorf("WorkDir, Branch = %q, %q, want the session info", d.WorkDir, d.Branch)
+	}
+	if d.UserMessages != 2 || d.AssistantMessages != 2 {
+		t.Errorf("messages = %d user / %d assistant, want 2 / 2", d.UserMessages, d.AssistantMessages)
+	}
+	if d.FilesRead != 1 || d.FilesChanged != 1 {
+		t.Errorf("FilesRead, FilesChanged = %d, %d, want 1, 1", d.FilesRead, d.FilesChanged)
+	}
+	if d.Start.Unix() != 1736932320 || d.End.Unix() != 1736935080 {
+		t.Errorf("Start, End = %v, %v", d.Start, d.End)
+	}
+}
+
+func TestFileRecorder(t *testing.T) {
+	var r FileRecorder
+	r.Record("/b.go", FileModify, 5)
+	r.Record("/a.go", FileRead, 3)
+	r.Record("/b.go", FileRead, 1)
+	r.Record("/b.go", FileModify, 7)
+	r.Record("", FileRead, 0)
+
+	if !r.Seen("/a.go") || r.Seen("/c.go") {
+		t.Error("Seen() should report recorded paths only")
+	}
+
+	activity := r.Activity()
+	if len(activity) != 2 || activity[0].Path != "/a.go" {
+		t.Fatalf("Activity() = %+v, want a.go then b.go", activity)
+	}
+	b := activity[1]
+	if b.Ops[FileModify] != 2 || b.Ops[FileRead] != 1 || b.FirstTurn != 1 || b.LastTurn != 7 {
+		t.Errorf("b.go = %+v, want 2 modifies and a read over turns 1-7", b)
+	}
+	if activity[0].Changed() || !b.Changed() || b.Op() != FileModify || activity[0].Op() != FileRead {
+		t.Errorf("Changed()/Op() misclassify %+v", activity)
+	}
+}
+
+func TestUnsupportedOperations(t *testing.T) {
+	a := newCoreAdapter()
+
+	if _, err := BranchSession(a, "x"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("BranchSession() error = %v, want ErrNotSupported", err)
+	}
+	if _, err := BranchSessionAt(a, "x", "0"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("BranchSessionAt() error = %v, want ErrNotSupported", err)
+	}
+	if err := RenameSession(a, "x", "title"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("RenameSession() error = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestStreamMessages_Fallback(t *testing.T) {
+	stop := errors.New("stop")
+	var seen int
+	err := StreamMessages(context.Background(), newCoreAdapter(), "x", func(m Message) error {
+		seen++
+		if seen == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("StreamMessages() error = %v, want callback error", err)
+	}
+	if seen != 2 {
+		t.Errorf("callback ran %d times, want 2", seen)
+	}
+}
+
+func TestStatsRollup(t *testing.T) {
+	s := &Stats{InputTokens: 100, OutputTokens: 10, Cost: 1}
+	s.Rollup([]SubagentStats{
+		{ID: "cheap", Totals: Totals{InputTokens: 5, Cost: 0.5}},
+		{ID: "costly", Totals: Totals{OutputTokens: 20, CacheRead: 7, Cost: 2}},
+	})
+
+	want := Totals{InputTokens: 105, OutputTokens: 30, CacheRead: 7, Cost: 3.5}
+	if s.Inclusive != want {
+		t.Errorf("Inclusive = %+v, want %+v", s.Inclusive, want)
+	}
+	if s.Self() != (Totals{InputTokens: 100, OutputTokens: 10, Cost: 1}) {
+		t.Errorf("Self() = %+v, want the session's own usage", s.Self())
+	}
+	if s.Subagents[0].ID != "costly" {
+		t.Errorf("Subagents = %+v, want the most expensive first", s.Subagents)
+	}
+}
+
+func TestMessageBlocks(t *testing.T) {
+	kept := Message{Blocks: []ContentBlock{{Kind: BlockText, Text: "as recorded"}}, Content: "flat"}
+	if b := MessageBlocks(kept); len(b) != 1 || b[0].Text != "as recorded" {
+		t.Errorf("MessageBlocks() = %+v, want the recorded blocks", b)
+	}
+
+	flat := Message{
+		Content:     "Done",
+		Thinking:    "Plan",
+		ToolCalls:   []ToolCall{{ID: "t1", Name: "Read"}},
+		ToolResults: []ToolResult{{ToolUseID: "t1", Content: "boom"}},
+	}
+	b := MessageBlocks(flat)
+	if len(b) != 4 || b[0].Kind != BlockThinking || b[1].Kind != BlockText || b[2].Kind != BlockToolUse || b[3].Kind != BlockToolResult {
+		t.Fatalf("MessageBlocks() = %+v, want thinking, text, tool_use, tool_result", b)
+	}
+	if !b[3].IsError {
+		t.Error("an unsuccessful tool result should become an error block")
+	}
+}
+
+func TestFindMessage(t *testing.T) {
+	messages := []Message{{ID: "m1"}, {ID: "m2"}, {}}
+	tests := []struct {
+		at      string
+		want    int
+		wantErr bool
+	}{
+		{"m2", 1, false},
+		{"0", 0, false},
+		{"2", 2, false},
+		{"3", -1, true},
+		{"-1", -1, true},
+		{"m9", -1, true},
+	}
+	for _, tt := range tests {
+		got, err := FindMessage(messages, tt.at)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("FindMessage(%q) = %d, %v; want %d", tt.at, got, err, tt.want)
+		}
+		if tt.wantErr && !errors.Is(err, ErrMessageNotFound) {
+			t.Errorf("FindMessage(%q) error = %v, want ErrMessageNotFound", tt.at, err)
+		}
+	}
+}
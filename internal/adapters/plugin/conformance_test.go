@@ -0,0 +1,169 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+// The conformance tests run a plugin binary through the proxy. By default
+// they build the reference plugin; set SESSIONS_ADAPTER_PLUGIN to the path
+// of another plugin to check it instead:
+//
+//	SESSIONS_ADAPTER_PLUGIN=$(which sessions-adapter-acme) go test ./internal/adapters/plugin -run Conformance
+
+// pluginUnderTest returns the plugin binary to check and whether it is the
+// reference plugin serving testdata
+func pluginUnderTest(t *testing.T) (string, bool) {
+	t.Helper()
+	if path := os.Getenv("SESSIONS_ADAPTER_PLUGIN"); path != "" {
+		return path, false
+	}
+
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available to build the reference plugin")
+	}
+	bin := filepath.Join(t.TempDir(), Prefix+"example")
+	build := exec.Command("go", "build", "-o", bin, "../../../cmd/sessions-adapter-example")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("building reference plugin: %v\n%s", err, out)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EXAMPLE_SESSIONS_DIR", filepath.Join(wd, "testdata", "sessions"))
+	return bin, true
+}
+
+func TestConformance(t *testing.T) {
+	path, reference := pluginUnderTest(t)
+	p := NewProxy("conformance", path)
+	t.Cleanup(func() { p.Close() })
+	ctx := context.Background()
+
+	if err := p.connect(); err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	if p.hello.Name == "" {
+		t.Error("handshake must report a name")
+	}
+	if p.DataDir() == "" {
+		t.Error("handshake must report a data dir")
+	}
+	for _, c := range p.hello.Capabilities {
+		switch c {
+		case MethodGetSummaries, MethodGetFilesTouched, MethodGetFileActivity, MethodGetSlashCmds, MethodGetModels,
+			MethodGetForks, MethodGetStats, MethodGetFirstMessage, MethodBranchSession, MethodBranchAt,
+			MethodSessionFiles, MethodRenameSession:
+		default:
+			t.Errorf("capability %q is not an optional method", c)
+		}
+	}
+
+	ids, err := p.ListSessions(ctx)
+	if err != nil {
+		t.Fatalf("list_sessions: %v", err)
+	}
+
+	extracted := 0
+	for _, id := range ids {
+		if p.GetSessionFile(id) == "" {
+			t.Errorf("get_session_file(%q) is empty for a listed session", id)
+		}
+
+		meta, err := p.ExtractMeta(ctx, id)
+		if err != nil {
+			continue // Sessions without messages may be skipped
+		}
+		extracted++
+		if meta.ID != id {
+			t.Errorf("extract_meta(%q).id = %q", id, meta.ID)
+		}
+		if meta.Date.IsZero() {
+			t.Errorf("extract_meta(%q).date is zero", id)
+		}
+
+		if _, err := p.GetSessionInfo(id); err != nil {
+			t.Errorf("get_session_info(%q): %v", id, err)
+		}
+
+		messages, err := p.ExportMessages(ctx, id)
+		if err != nil {
+			t.Errorf("export_messages(%q): %v", id, err)
+		}
+		for i, m := range messages {
+			if m.Role != "user" && m.Role != "assistant" {
+				t.Errorf("export_messages(%q)[%d].role = %q", id, i, m.Role)
+			}
+		}
+	}
+
+	// Unknown sessions must fail rather than return empty results
+	if _, err := p.ExtractMeta(ctx, "no-such-session"); err == nil {
+		t.Error("extract_meta of an unknown session should fail")
+	}
+
+	// Methods outside the capability list must answer not_supported
+	for _, method := range []string{MethodBranchSession, MethodBranchAt, MethodSessionFiles, MethodRenameSession} {
+		if p.Has(method) {
+			continue
+		}
+		err := p.conn.call(ctx, method, Params{ID: "x"}, nil)
+		if !errors.Is(err, adapters.ErrNotSupported) {
+			t.Errorf("%s without the capability: error = %v, want not_supported", method, err)
+		}
+	}
+
+	if err := p.conn.call(ctx, "no_such_method", nil, nil); err == nil {
+		t.Error("unknown methods should fail")
+	}
+
+	if reference {
+		if len(ids) != 3 {
+			t.Errorf("list_sessions returned %d sessions, want 3", len(ids))
+		}
+		if extracted != 2 {
+			t.Errorf("extracted %d sessions, want 2 (one has no messages)", extracted)
+		}
+		if !p.Has(MethodGetFirstMessage) {
+			t.Error("reference plugin should advertise get_first_message")
+		}
+	}
+}
+
+func TestConformance_Reference(t *testing.T) {
+	path, reference := pluginUnderTest(t)
+	if !reference {
+		t.Skip("checks the reference plugin's testdata")
+	}
+	p := NewProxy("example", path)
+	t.Cleanup(func() { p.Close() })
+
+	meta, err := p.ExtractMeta(context.Background(), "7c1e9a")
+	if err != nil {
+		t.Fatalf("ExtractMeta() error = %v", err)
+	}
+	if meta.Project != "parser" || meta.Summary != "Add a test for the empty input case" {
+		t.Errorf("ExtractMeta() = %+v", meta)
+	}
+
+	// Stats come from the core fallback since the plugin has none
+	stats, err := adapters.GetStats(context.Background(), p, "7c1e9a")
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if stats.UserMessages != 2 || stats.AssistantMessages != 2 {
+		t.Errorf("GetStats() = %+v, want 2 user / 2 assistant", stats)
+	}
+
+	if cmd := p.ResumeCmd("7c1e9a"); cmd != "example-agent --resume 7c1e9a" {
+		t.Errorf("ResumeCmd() = %q", cmd)
+	}
+}
@@ -0,0 +1,77 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+// Plugin is an adapter executable found on PATH
+type Plugin struct {
+	Name string // Provider name, the part after Prefix
+	Path string
+}
+
+// Discover returns the plugins in the directories of a PATH-style list,
+// sorted by name. As with command lookup, the first directory wins when
+// a name appears twice.
+func Discover(pathList string) []Plugin {
+	seen := make(map[string]bool)
+	var plugins []Plugin
+
+	for _, dir := range filepath.SplitList(pathList) {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name := strings.TrimSuffix(e.Name(), ".exe")
+			if !strings.HasPrefix(name, Prefix) || len(name) == len(Prefix) {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			info, err := os.Stat(path) // Follows symlinks
+			if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+				continue
+			}
+			name = strings.TrimPrefix(name, Prefix)
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			plugins = append(plugins, Plugin{Name: name, Path: path})
+		}
+	}
+
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].Name < plugins[j].Name
+	})
+	return plugins
+}
+
+// Register adds every plugin on the PATH-style list to the provider
+// registry. Built-in providers keep their names; a plugin with the same
+// name is ignored. Plugins count as detected, so listing providers never
+// starts them.
+func Register(pathList string) {
+	for _, p := range Discover(pathList) {
+		if _, taken := adapters.Lookup(p.Name); taken {
+			continue
+		}
+		path := p.Path
+		name := p.Name
+		adapters.Register(adapters.Provider{
+			Name:   name,
+			New:    func() adapters.Adapter { return NewProxy(name, path) },
+			Plugin: path,
+			// Asking for the data dir would start the plugin
+			Detect: func(adapters.Adapter) bool { return true },
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+// fakeAdapter implements the core interface, StatsGetter and PointBrancher
+type fakeAdapter struct {
+	adapters.Adapter
+	block chan struct{} // ExtractMeta waits on it when set
+}
+
+func (f *fakeAdapter) Name() string     { return "fake" }
+func (f *fakeAdapter) DataDir() string  { return "/fake/data" }
+func (f *fakeAdapter) CacheDir() string { return "" }
+
+func (f *fakeAdapter) ListSessions(ctx context.Context) ([]string, error) {
+	return []string{"s1", "s2"}, nil
+}
+
+func (f *fakeAdapter) ExtractMeta(ctx context.Context, id string) (*adapters.SessionMeta, error) {
+	if f.block != nil {
+		<-f.block
+	}
+	if id != "s1" && id != "s2" {
+		return nil, os.ErrNotExist
+	}
+	return &adapters.SessionMeta{ID: id, Project: "proj", Summary: "Summary of " + id}, nil
+}
+
+func (f *fakeAdapter) ExportMessages(ctx context.Context, id string) ([]adapters.Message, error) {
+	return []adapters.Message{
+		{Role: "user", Content: "Hello"},
+		{Role: "assistant", Content: "Hi", ToolCalls: []adapters.ToolCall{{ID: "t1", Name: "Read"}}},
+	}, nil
+}
+
+func (f *fakeAdapter) GetStats(ctx context.Context, id string) (*adapters.Stats, error) {
+	return &adapters.Stats{UserMessages: 7, ToolCalls: map[string]int{"Read": 3}}, nil
+}
+
+func (f *fakeAdapter) BranchSessionAt(id, at string) (string, error) {
+	return id + "-at-" + at, nil
+}
+
+// pipeProxy connects a proxy to Serve running in-process
+func pipeProxy(t *testing.T, a adapters.Adapter) (*Proxy, io.Closer) {
+	t.Helper()
+	reqR, reqW := io.Pipe()
+	respR, respW := io.Pipe()
+
+	go func() {
+		Serve(a, reqR, respW)
+		respW.Close()
+	}()
+
+	p := &Proxy{name: "fake"}
+	p.open = func() (io.Reader, io.WriteCloser, error) {
+		return respR, reqW, nil
+	}
+	t.Cleanup(func() { reqW.Close() })
+	return p, reqW
+}
+
+func TestProxy_Handshake(t *testing.T) {
+	p, _ := pipeProxy(t, &fakeAdapter{})
+
+	if p.Name() != "fake" {
+		t.Errorf("Name() = %q, want %q", p.Name(), "fake")
+	}
+	if p.DataDir() != "/fake/data" {
+		t.Errorf("DataDir() = %q, want %q", p.DataDir(), "/fake/data")
+	}
+	// The plugin reported no cache dir, so the proxy picks one
+	if filepath.Base(p.CacheDir()) != "fake" {
+		t.Errorf("CacheDir() = %q, want a directory named after the plugin", p.CacheDir())
+	}
+	if !p.Has(MethodGetStats) {
+		t.Error("Has(get_stats) = false, want true")
+	}
+	if p.Has(MethodBranchSession) {
+		t.Error("Has(branch_session) = true, want false")
+	}
+
+	// Actions are offered as declared, not because the proxy has the methods
+	if !adapters.Supports(p, "s1", adapters.CanBranchAt) {
+		t.Error("Supports(branch-at) = false, want true")
+	}
+	if adapters.Supports(p, "s1", adapters.CanBranch) || adapters.Supports(p, "", adapters.CanRename) {
+		t.Error("Supports() = true for actions the plugin didn't declare")
+	}
+}
+
+func TestProxy_CoreMethods(t *testing.T) {
+	p, _ := pipeProxy(t, &fakeAdapter{})
+	ctx := context.Background()
+
+	ids, err := p.ListSessions(ctx)
+	if err != nil {
+		t.Fatalf("ListSessions() error = %v", err)
+	}
+	if len(ids) != 2 || ids[0] != "s1" {
+		t.Errorf("ListSessions() = %v, want [s1 s2]", ids)
+	}
+
+	meta, err := p.ExtractMeta(ctx, "s2")
+	if err != nil {
+		t.Fatalf("ExtractMeta() error = %v", err)
+	}
+	if meta.ID != "s2" || meta.Summary != "Summary of s2" {
+		t.Errorf("ExtractMeta() = %+v", meta)
+	}
+
+	messages, err := p.ExportMessages(ctx, "s1")
+	if err != nil {
+		t.Fatalf("ExportMessages() error = %v", err)
+	}
+	if len(messages) != 2 || messages[1].ToolCalls[0].Name != "Read" {
+		t.Errorf("ExportMessages() = %+v", messages)
+	}
+}
+
+func TestProxy_OptionalMethods(t *testing.T) {
+	p, _ := pipeProxy(t, &fakeAdapter{})
+
+	stats, err := p.GetStats(context.Background(), "s1")
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if stats.UserMessages != 7 || stats.ToolCalls["Read"] != 3 {
+		t.Errorf("GetStats() = %+v, want the plugin's stats", stats)
+	}
+
+	// Unsupported getters fall back to the exported messages
+	msg, err := p.GetFirstMessage("s1")
+	if err != nil {
+		t.Fatalf("GetFirstMessage() error = %v", err)
+	}
+	if msg != "Hello" {
+		t.Errorf("GetFirstMessage() = %q, want %q", msg, "Hello")
+	}
+
+	newID, err := p.BranchSessionAt("s1", "3")
+	if err != nil || newID != "s1-at-3" {
+		t.Errorf("BranchSessionAt() = %q, %v; want the params passed through", newID, err)
+	}
+
+	// Unsupported actions are reported as such
+	if _, err := p.BranchSession("s1"); !errors.Is(err, adapters.ErrNotSupported) {
+		t.Errorf("BranchSession() error = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestProxy_Errors(t *testing.T) {
+	p, _ := pipeProxy(t, &fakeAdapter{})
+
+	_, err := p.ExtractMeta(context.Background(), "missing")
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ExtractMeta() error = %v, want os.ErrNotExist", err)
+	}
+
+	p.connect()
+	err = p.conn.call(context.Background(), "bogus", nil, nil)
+	var perr *Error
+	if !errors.As(err, &perr) || perr.Code != CodeUnknownMethod {
+		t.Errorf("call(bogus) error = %v, want %s", err, CodeUnknownMethod)
+	}
+}
+
+func TestProxy_Cancel(t *testing.T) {
+	a := &fakeAdapter{block: make(chan struct{})}
+	defer close(a.block)
+	p, _ := pipeProxy(t, a)
+	p.connect()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.ExtractMeta(ctx, "s1")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ExtractMeta() error = %v, want DeadlineExceeded", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("ExtractMeta() did not return promptly on cancellation")
+	}
+}
+
+func TestProxy_CallTimeout(t *testing.T) {
+	a := &fakeAdapter{block: make(chan struct{})}
+	defer close(a.block)
+	p, _ := pipeProxy(t, a)
+	p.timeout = 50 * time.Millisecond
+
+	// Callers without a deadline of their own still get one
+	start := time.Now()
+	_, err := p.ExtractMeta(context.Background(), "s1")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ExtractMeta() error = %v, want DeadlineExceeded", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("ExtractMeta() did not time out promptly")
+	}
+}
+
+func TestProxy_PluginExits(t *testing.T) {
+	p, stdin := pipeProxy(t, &fakeAdapter{})
+	p.connect()
+	stdin.Close()
+
+	if _, err := p.ListSessions(context.Background()); err == nil {
+		t.Error("ListSessions() should fail once the plugin has exited")
+	}
+}
+
+func TestConn_WriteTimeout(t *testing.T) {
+	// The plugin reads nothing, so the request can't be written
+	reqR, reqW := io.Pipe()
+	respR, respW := io.Pipe()
+	defer reqR.Close()
+	defer respW.Close()
+	c := newConn(respR, reqW)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.call(ctx, MethodListSessions, Params{}, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("call() error = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestProxy_HandshakeFailureStopsPlugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script plugin")
+	}
+	path := filepath.Join(t.TempDir(), Prefix+"old")
+	script := "#!/bin/sh\nread line\necho '{\"id\":1,\"result\":{\"protocol\":0}}'\nexec sleep 30\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewProxy("old", path)
+	start := time.Now()
+	if err := p.connect(); err == nil {
+		t.Fatal("connect() should fail on a protocol mismatch")
+	}
+	if p.cmd.ProcessState == nil || time.Since(start) > 10*time.Second {
+		t.Error("the plugin was left running after the failed handshake")
+	}
+}
+
+func TestRegister_DoesNotStartPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script plugin")
+	}
+	dir := t.TempDir()
+	started := filepath.Join(dir, "started")
+	script := "#!/bin/sh\ntouch " + started + "\n"
+	if err := os.WriteFile(filepath.Join(dir, Prefix+"lazy"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	Register(dir)
+	p, ok := adapters.Lookup("lazy")
+	if !ok || p.Plugin == "" {
+		t.Fatalf("Lookup(lazy) = %+v, want the plugin registered", p)
+	}
+	if !p.Detected(p.New()) {
+		t.Error("Detected() = false, want discovered plugins to count")
+	}
+	if _, err := os.Stat(started); !os.IsNotExist(err) {
+		t.Error("listing providers started the plugin")
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	first := t.TempDir()
+	second := t.TempDir()
+
+	write := func(dir, name string, mode os.FileMode) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(first, "sessions-adapter-acme", 0755)
+	write(first, "sessions-adapter-notexec", 0644)
+	write(first, "sessions-adapter-", 0755)
+	write(first, "unrelated", 0755)
+	write(second, "sessions-adapter-acme", 0755)
+	write(second, "sessions-adapter-beta", 0755)
+	os.Mkdir(filepath.Join(second, "sessions-adapter-dir"), 0755)
+
+	plugins := Discover(first + string(os.PathListSeparator) + second)
+
+	if len(plugins) != 2 {
+		t.Fatalf("Discover() = %+v, want acme and beta", plugins)
+	}
+	if plugins[0].Name != "acme" || filepath.Dir(plugins[0].Path) != first {
+		t.Errorf("plugins[0] = %+v, want acme from the first directory", plugins[0])
+	}
+	if plugins[1].Name != "beta" {
+		t.Errorf("plugins[1] = %+v, want beta", plugins[1])
+	}
+}
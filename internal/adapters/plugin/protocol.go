@@ -0,0 +1,136 @@
+// Package plugin lets session providers live outside this repository.
+//
+// A plugin is an executable named sessions-adapter-<name> on PATH. It is
+// started once per process and speaks JSON over stdin/stdout: each request
+// is one JSON object on its own line and each response carries the ID of
+// the request it answers.
+//
+//	→ {"id":1,"method":"list_sessions"}
+//	← {"id":1,"result":["a1","b2"]}
+//	→ {"id":2,"method":"get_stats","params":{"id":"a1"}}
+//	← {"id":2,"error":{"code":"not_supported","message":"..."}}
+//
+// The first request is always "handshake". Its result names the protocol
+// version, the provider's directories and the optional methods the plugin
+// implements. Every other method mirrors an adapters.Adapter method and
+// takes the session ID in params. Results use the JSON encoding of the
+// adapters types.
+//
+// Plugins may answer requests out of order. The caller gives up on a
+// request after 30 seconds at most. A plugin should exit when stdin is
+// closed; responses to requests the caller gave up on are discarded.
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+// ProtocolVersion is the version reported in the handshake
+const ProtocolVersion = 1
+
+// Prefix is the executable name prefix that marks a plugin
+const Prefix = "sessions-adapter-"
+
+// Method names
+const (
+	MethodHandshake       = "handshake"
+	MethodResumeCmd       = "resume_cmd"
+	MethodListSessions    = "list_sessions"
+	MethodGetSessionFile  = "get_session_file"
+	MethodExtractMeta     = "extract_meta"
+	MethodGetSessionInfo  = "get_session_info"
+	MethodExportMessages  = "export_messages"
+	MethodGetSummaries    = "get_summaries"
+	MethodGetFilesTouched = "get_files_touched"
+	MethodGetFileActivity = "get_file_activity"
+	MethodGetSlashCmds    = "get_slash_commands"
+	MethodGetModels       = "get_models"
+	MethodGetForks        = "get_forks"
+	MethodGetStats        = "get_stats"
+	MethodGetFirstMessage = "get_first_message"
+	MethodBranchSession   = "branch_session"
+	MethodBranchAt        = "branch_session_at"
+	MethodSessionFiles    = "session_files"
+	MethodRenameSession   = "rename_session"
+)
+
+// Error codes
+const (
+	CodeNotSupported  = "not_supported"  // Optional method the plugin doesn't implement
+	CodeNotFound      = "not_found"      // Unknown session
+	CodeUnknownMethod = "unknown_method" // Method not in this protocol version
+	CodeInvalidParams = "invalid_params"
+	CodeError         = "error" // Anything else
+)
+
+// Request is a single call to the plugin
+type Request struct {
+	ID     int64           `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Response answers the request with the same ID. Exactly one of Result
+// and Error is set.
+type Response struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *Error          `json:"error,omitempty"`
+}
+
+// Params carries the arguments of every method except handshake
+type Params struct {
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"` // rename_session only
+	At    string `json:"at,omitempty"`    // branch_session_at only
+}
+
+// Hello is the handshake result
+type Hello struct {
+	Protocol int    `json:"protocol"`
+	Name     string `json:"name"`
+	DataDir  string `json:"data_dir"`
+	CacheDir string `json:"cache_dir,omitempty"`
+	// Capabilities lists the optional methods the plugin implements
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// Error is a failed call. It matches adapters.ErrNotSupported and
+// os.ErrNotExist with errors.Is so callers can treat plugin errors like
+// those of built-in providers.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Is(target error) bool {
+	switch e.Code {
+	case CodeNotSupported:
+		return target == adapters.ErrNotSupported
+	case CodeNotFound:
+		return target == os.ErrNotExist
+	}
+	return false
+}
+
+// errorFor converts an adapter error to its wire form
+func errorFor(err error) *Error {
+	var e *Error
+	switch {
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, adapters.ErrNotSupported):
+		return &Error{Code: CodeNotSupported, Message: err.Error()}
+	case errors.Is(err, os.ErrNotExist):
+		return &Error{Code: CodeNotFound, Message: err.Error()}
+	}
+	return &Error{Code: CodeError, Message: err.Error()}
+}
@@ -0,0 +1,460 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+// handshakeTimeout bounds how long a plugin may take to start
+const handshakeTimeout = 10 * time.Second
+
+// callTimeout bounds each request, so a hung plugin can't stall the
+// caller; callers may set a shorter deadline on the context
+const callTimeout = 30 * time.Second
+
+// Proxy is an adapter backed by a plugin process. The process is started
+// on first use and lives until Close or until this process exits.
+//
+// Proxy implements every optional interface. Getters the plugin lacks
+// fall back to values derived from the core methods; actions it lacks
+// return adapters.ErrNotSupported, and Supports reports them as missing.
+type Proxy struct {
+	name    string
+	open    func() (io.Reader, io.WriteCloser, error)
+	timeout time.Duration // Per request; callTimeout when zero
+
+	once  sync.Once
+	conn  *conn
+	hello Hello
+	err   error
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+}
+
+// NewProxy creates an adapter for the plugin executable at path
+func NewProxy(name, path string) *Proxy {
+	p := &Proxy{name: name}
+	p.open = func() (io.Reader, io.WriteCloser, error) {
+		cmd := exec.Command(path)
+		cmd.Stderr = os.Stderr
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, nil, err
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, nil, err
+		}
+		p.cmd = cmd
+		return stdout, stdin, nil
+	}
+	return p
+}
+
+// connect starts the plugin and performs the handshake once
+func (p *Proxy) connect() error {
+	p.once.Do(func() {
+		r, w, err := p.open()
+		if err != nil {
+			p.err = fmt.Errorf("plugin %s: %w", p.name, err)
+			return
+		}
+		p.stdin = w
+		p.conn = newConn(r, w)
+
+		ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+		defer cancel()
+		if err := p.conn.call(ctx, MethodHandshake, nil, &p.hello); err != nil {
+			p.err = fmt.Errorf("plugin %s: handshake: %w", p.name, err)
+		} else if p.hello.Protocol != ProtocolVersion {
+			p.err = fmt.Errorf("plugin %s speaks protocol %d, want %d", p.name, p.hello.Protocol, ProtocolVersion)
+		}
+		if p.err != nil {
+			p.kill()
+		}
+	})
+	return p.err
+}
+
+// kill stops a plugin that failed to connect; it is never used again
+func (p *Proxy) kill() {
+	p.stdin.Close()
+	if p.cmd != nil {
+		p.cmd.Process.Kill()
+		p.cmd.Wait()
+	}
+}
+
+// call performs one request once the plugin is running, giving up after
+// the proxy's timeout
+func (p *Proxy) call(ctx context.Context, method string, params Params, result any) error {
+	if err := p.connect(); err != nil {
+		return err
+	}
+	timeout := p.timeout
+	if timeout == 0 {
+		timeout = callTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return p.conn.call(ctx, method, params, result)
+}
+
+// Has reports whether the plugin implements an optional method
+func (p *Proxy) Has(method string) bool {
+	if p.connect() != nil {
+		return false
+	}
+	for _, c := range p.hello.Capabilities {
+		if c == method {
+			return true
+		}
+	}
+	return false
+}
+
+// actionMethods maps the optional actions to the methods providing them
+var actionMethods = map[adapters.Capability]string{
+	adapters.CanBranch:   MethodBranchSession,
+	adapters.CanBranchAt: MethodBranchAt,
+	adapters.CanDelete:   MethodSessionFiles,
+	adapters.CanRename:   MethodRenameSession,
+}
+
+// Supports reports whether the plugin declared the method behind an
+// action. Plugins answer for all their sessions alike.
+func (p *Proxy) Supports(id string, c adapters.Capability) bool {
+	method, ok := actionMethods[c]
+	return ok && p.Has(method)
+}
+
+// Close stops the plugin process
+func (p *Proxy) Close() error {
+	if p.stdin == nil {
+		return nil
+	}
+	p.stdin.Close()
+	if p.cmd != nil {
+		return p.cmd.Wait()
+	}
+	return nil
+}
+
+func (p *Proxy) Name() string {
+	return p.name
+}
+
+func (p *Proxy) DataDir() string {
+	if p.connect() != nil {
+		return ""
+	}
+	return p.hello.DataDir
+}
+
+func (p *Proxy) CacheDir() string {
+	if p.connect() == nil && p.hello.CacheDir != "" {
+		return p.hello.CacheDir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "claude-sessions", p.name)
+}
+
+func (p *Proxy) ResumeCmd(id string) string {
+	var cmd string
+	p.call(context.Background(), MethodResumeCmd, Params{ID: id}, &cmd)
+	return cmd
+}
+
+func (p *Proxy) ListSessions(ctx context.Context) ([]string, error) {
+	var ids []string
+	if err := p.call(ctx, MethodListSessions, Params{}, &ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (p *Proxy) GetSessionFile(id string) string {
+	var path string
+	p.call(context.Background(), MethodGetSessionFile, Params{ID: id}, &path)
+	return path
+}
+
+func (p *Proxy) ExtractMeta(ctx context.Context, id string) (*adapters.SessionMeta, error) {
+	var meta adapters.SessionMeta
+	if err := p.call(ctx, MethodExtractMeta, Params{ID: id}, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+func (p *Proxy) GetSessionInfo(id string) (*adapters.SessionInfo, error) {
+	var info adapters.SessionInfo
+	if err := p.call(context.Background(), MethodGetSessionInfo, Params{ID: id}, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (p *Proxy) ExportMessages(ctx context.Context, id string) ([]adapters.Message, error) {
+	var messages []adapters.Message
+	if err := p.call(ctx, MethodExportMessages, Params{ID: id}, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// core hides the proxy's optional methods so the adapters helpers use
+// their fallbacks
+type core struct {
+	adapters.Adapter
+}
+
+func (p *Proxy) GetSummaries(id string) ([]string, error) {
+	if !p.Has(MethodGetSummaries) {
+		return adapters.GetSummaries(core{p}, id)
+	}
+	var summaries []string
+	err := p.call(context.Background(), MethodGetSummaries, Params{ID: id}, &summaries)
+	return summaries, err
+}
+
+func (p *Proxy) GetFilesTouched(id string) ([]string, error) {
+	if !p.Has(MethodGetFilesTouched) {
+		if p.Has(MethodGetFileActivity) {
+			activity, err := p.GetFileActivity(context.Background(), id)
+			return adapters.ChangedFiles(activity), err
+		}
+		return adapters.GetFilesTouched(core{p}, id)
+	}
+	var files []string
+	err := p.call(context.Background(), MethodGetFilesTouched, Params{ID: id}, &files)
+	return files, err
+}
+
+func (p *Proxy) GetFileActivity(ctx context.Context, id string) ([]adapters.FileActivity, error) {
+	if !p.Has(MethodGetFileActivity) {
+		return adapters.GetFileActivity(ctx, core{p}, id)
+	}
+	var activity []adapters.FileActivity
+	err := p.call(ctx, MethodGetFileActivity, Params{ID: id}, &activity)
+	return activity, err
+}
+
+func (p *Proxy) GetSlashCommands(id string) ([]string, error) {
+	if !p.Has(MethodGetSlashCmds) {
+		return adapters.GetSlashCommands(core{p}, id)
+	}
+	var cmds []string
+	err := p.call(context.Background(), MethodGetSlashCmds, Params{ID: id}, &cmds)
+	return cmds, err
+}
+
+func (p *Proxy) GetModels(id string) ([]string, error) {
+	if !p.Has(MethodGetModels) {
+		return adapters.GetModels(core{p}, id)
+	}
+	var models []string
+	err := p.call(context.Background(), MethodGetModels, Params{ID: id}, &models)
+	return models, err
+}
+
+func (p *Proxy) GetForks(id string) ([]adapters.Fork, error) {
+	if !p.Has(MethodGetForks) {
+		return nil, nil
+	}
+	var forks []adapters.Fork
+	err := p.call(context.Background(), MethodGetForks, Params{ID: id}, &forks)
+	return forks, err
+}
+
+func (p *Proxy) GetStats(ctx context.Context, id string) (*adapters.Stats, error) {
+	if !p.Has(MethodGetStats) {
+		return adapters.GetStats(ctx, core{p}, id)
+	}
+	var stats adapters.Stats
+	if err := p.call(ctx, MethodGetStats, Params{ID: id}, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func (p *Proxy) GetFirstMessage(id string) (string, error) {
+	if !p.Has(MethodGetFirstMessage) {
+		return adapters.GetFirstMessage(context.Background(), core{p}, id)
+	}
+	var msg string
+	err := p.call(context.Background(), MethodGetFirstMessage, Params{ID: id}, &msg)
+	return msg, err
+}
+
+func (p *Proxy) BranchSession(id string) (string, error) {
+	if !p.Has(MethodBranchSession) {
+		return "", adapters.ErrNotSupported
+	}
+	var newID string
+	err := p.call(context.Background(), MethodBranchSession, Params{ID: id}, &newID)
+	return newID, err
+}
+
+func (p *Proxy) BranchSessionAt(id, at string) (string, error) {
+	if !p.Has(MethodBranchAt) {
+		return "", adapters.ErrNotSupported
+	}
+	var newID string
+	err := p.call(context.Background(), MethodBranchAt, Params{ID: id, At: at}, &newID)
+	return newID, err
+}
+
+func (p *Proxy) SessionFiles(id string) ([]string, error) {
+	if !p.Has(MethodSessionFiles) {
+		return nil, adapters.ErrNotSupported
+	}
+	var files []string
+	err := p.call(context.Background(), MethodSessionFiles, Params{ID: id}, &files)
+	return files, err
+}
+
+func (p *Proxy) RenameSession(id, title string) error {
+	if !p.Has(MethodRenameSession) {
+		return adapters.ErrNotSupported
+	}
+	return p.call(context.Background(), MethodRenameSession, Params{ID: id, Title: title}, nil)
+}
+
+// conn multiplexes requests over a plugin's stdin/stdout
+type conn struct {
+	writeMu sync.Mutex
+	enc     *json.Encoder
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan Response
+	err     error // Set once the plugin's output ends
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	c := &conn{
+		enc:     json.NewEncoder(w),
+		pending: make(map[int64]chan Response),
+	}
+	go c.readLoop(json.NewDecoder(r))
+	return c
+}
+
+// readLoop routes responses to their callers until the plugin's output ends
+func (c *conn) readLoop(dec *json.Decoder) {
+	for {
+		var resp Response
+		if err := dec.Decode(&resp); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("plugin exited")
+			}
+			c.mu.Lock()
+			c.err = err
+			for id, ch := range c.pending {
+				close(ch)
+				delete(c.pending, id)
+			}
+			c.mu.Unlock()
+			return
+		}
+
+		c.mu.Lock()
+		ch, ok := c.pending[resp.ID]
+		delete(c.pending, resp.ID)
+		c.mu.Unlock()
+		if ok {
+			ch <- resp
+		}
+	}
+}
+
+// call sends a request and waits for its response, giving up on both the
+// write and the wait when ctx ends
+func (c *conn) call(ctx context.Context, method string, params, result any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	req := Request{Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		req.Params = raw
+	}
+
+	ch := make(chan Response, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	req.ID = c.nextID
+	c.pending[req.ID] = ch
+	c.mu.Unlock()
+
+	// A plugin that stopped reading blocks the write, so it waits on ctx
+	// too. The writer is left behind until the pipe closes.
+	written := make(chan error, 1)
+	go func() {
+		c.writeMu.Lock()
+		defer c.writeMu.Unlock()
+		written <- c.enc.Encode(req)
+	}()
+	select {
+	case err := <-written:
+		if err != nil {
+			c.forget(req.ID)
+			return err
+		}
+	case <-ctx.Done():
+		c.forget(req.ID)
+		return ctx.Err()
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return c.err
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	case <-ctx.Done():
+		c.forget(req.ID)
+		return ctx.Err()
+	}
+}
+
+// forget drops a pending request; a late response is discarded
+func (c *conn) forget(id int64) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
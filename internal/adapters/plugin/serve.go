@@ -0,0 +1,154 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+// Serve answers protocol requests from r with the given adapter until r
+// is closed. It is all a Go plugin's main needs:
+//
+//	plugin.Serve(myadapter.New(), os.Stdin, os.Stdout)
+func Serve(a adapters.Adapter, r io.Reader, w io.Writer) error {
+	dec := json.NewDecoder(r)
+	enc := json.NewEncoder(w)
+	ctx := context.Background()
+
+	for {
+		var req Request
+		if err := dec.Decode(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("read request: %w", err)
+		}
+
+		resp := Response{ID: req.ID}
+		result, err := dispatch(ctx, a, req)
+		if err == nil {
+			resp.Result, err = json.Marshal(result)
+		}
+		if err != nil {
+			resp.Result = nil
+			resp.Error = errorFor(err)
+		}
+
+		if err := enc.Encode(resp); err != nil {
+			return fmt.Errorf("write response: %w", err)
+		}
+	}
+}
+
+// dispatch calls the adapter method named by the request
+func dispatch(ctx context.Context, a adapters.Adapter, req Request) (any, error) {
+	if req.Method == MethodHandshake {
+		return Hello{
+			Protocol:     ProtocolVersion,
+			Name:         a.Name(),
+			DataDir:      a.DataDir(),
+			CacheDir:     a.CacheDir(),
+			Capabilities: capabilities(a),
+		}, nil
+	}
+
+	var p Params
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+		}
+	}
+
+	switch req.Method {
+	case MethodResumeCmd:
+		return a.ResumeCmd(p.ID), nil
+	case MethodListSessions:
+		return a.ListSessions(ctx)
+	case MethodGetSessionFile:
+		return a.GetSessionFile(p.ID), nil
+	case MethodExtractMeta:
+		return a.ExtractMeta(ctx, p.ID)
+	case MethodGetSessionInfo:
+		return a.GetSessionInfo(p.ID)
+	case MethodExportMessages:
+		return a.ExportMessages(ctx, p.ID)
+	case MethodGetSummaries:
+		if g, ok := a.(adapters.SummariesGetter); ok {
+			return g.GetSummaries(p.ID)
+		}
+	case MethodGetFilesTouched:
+		if g, ok := a.(adapters.FilesTouchedGetter); ok {
+			return g.GetFilesTouched(p.ID)
+		}
+	case MethodGetFileActivity:
+		if g, ok := a.(adapters.FileActivityGetter); ok {
+			return g.GetFileActivity(ctx, p.ID)
+		}
+	case MethodGetSlashCmds:
+		if g, ok := a.(adapters.SlashCommandsGetter); ok {
+			return g.GetSlashCommands(p.ID)
+		}
+	case MethodGetModels:
+		if g, ok := a.(adapters.ModelsGetter); ok {
+			return g.GetModels(p.ID)
+		}
+	case MethodGetForks:
+		if g, ok := a.(adapters.ForksGetter); ok {
+			return g.GetForks(p.ID)
+		}
+	case MethodGetStats:
+		if g, ok := a.(adapters.StatsGetter); ok {
+			return g.GetStats(ctx, p.ID)
+		}
+	case MethodGetFirstMessage:
+		if g, ok := a.(adapters.FirstMessageGetter); ok {
+			return g.GetFirstMessage(p.ID)
+		}
+	case MethodBranchSession:
+		return adapters.BranchSession(a, p.ID)
+	case MethodBranchAt:
+		return adapters.BranchSessionAt(a, p.ID, p.At)
+	case MethodSessionFiles:
+		return adapters.SessionFiles(a, p.ID)
+	case MethodRenameSession:
+		return nil, adapters.RenameSession(a, p.ID, p.Title)
+	default:
+		return nil, &Error{Code: CodeUnknownMethod, Message: "unknown method " + req.Method}
+	}
+	return nil, adapters.ErrNotSupported
+}
+
+// capabilities lists the optional methods the adapter implements
+func capabilities(a adapters.Adapter) []string {
+	var caps []string
+	add := func(ok bool, method string) {
+		if ok {
+			caps = append(caps, method)
+		}
+	}
+	_, ok := a.(adapters.SummariesGetter)
+	add(ok, MethodGetSummaries)
+	_, ok = a.(adapters.FilesTouchedGetter)
+	add(ok, MethodGetFilesTouched)
+	_, ok = a.(adapters.FileActivityGetter)
+	add(ok, MethodGetFileActivity)
+	_, ok = a.(adapters.SlashCommandsGetter)
+	add(ok, MethodGetSlashCmds)
+	_, ok = a.(adapters.ModelsGetter)
+	add(ok, MethodGetModels)
+	_, ok = a.(adapters.ForksGetter)
+	add(ok, MethodGetForks)
+	_, ok = a.(adapters.StatsGetter)
+	add(ok, MethodGetStats)
+	_, ok = a.(adapters.FirstMessageGetter)
+	add(ok, MethodGetFirstMessage)
+	// Actions go through Supports, which wrapping adapters answer for
+	for _, c := range []adapters.Capability{adapters.CanBranch, adapters.CanBranchAt, adapters.CanDelete, adapters.CanRename} {
+		add(adapters.Supports(a, "", c), actionMethods[c])
+	}
+	return caps
+}
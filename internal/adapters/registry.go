@@ -0,0 +1,125 @@
+package adapters
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultProvider is used when nothing selects a provider explicitly
+const DefaultProvider = "claude"
+
+// Provider describes a registered session provider
+type Provider struct {
+	// Name is the value accepted by --provider and SESSIONS_PROVIDER
+	Name string
+	// Match is the substring that selects the provider from the binary
+	// name; defaults to Name
+	Match string
+	// New constructs the adapter with its default data location
+	New func() Adapter
+	// Detect reports whether the provider's data is present on this
+	// machine; defaults to checking that DataDir exists
+	Detect func(a Adapter) bool
+	// Plugin is the executable of a provider discovered on PATH, empty
+	// for built-in ones
+	Plugin string
+}
+
+// Detected reports whether the provider has data on this machine
+func (p Provider) Detected(a Adapter) bool {
+	if p.Detect != nil {
+		return p.Detect(a)
+	}
+	info, err := os.Stat(a.DataDir())
+	return err == nil && info.IsDir()
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Provider)
+)
+
+// Register makes a provider available by name. It is meant to be called
+// from the provider package's init and panics on duplicates.
+func Register(p Provider) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if p.Name == "" || p.New == nil {
+		panic("adapters: Register requires a name and constructor")
+	}
+	if _, dup := registry[p.Name]; dup {
+		panic("adapters: Register called twice for provider " + p.Name)
+	}
+	if p.Match == "" {
+		p.Match = p.Name
+	}
+	registry[p.Name] = p
+}
+
+// Lookup returns the provider registered under name
+func Lookup(name string) (Provider, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	p, ok := registry[name]
+	return p, ok
+}
+
+// Providers returns every registered provider sorted by name
+func Providers() []Provider {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	providers := make([]Provider, 0, len(registry))
+	for _, p := range registry {
+		providers = append(providers, p)
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i].Name < providers[j].Name
+	})
+	return providers
+}
+
+// Select picks a provider by precedence: the --provider flag, then the
+// SESSIONS_PROVIDER environment variable, then the binary name, then
+// DefaultProvider.
+func Select(flag, env, binaryName string) (Provider, error) {
+	for _, name := range []string{flag, env} {
+		if name == "" {
+			continue
+		}
+		p, ok := Lookup(name)
+		if !ok {
+			return Provider{}, fmt.Errorf("unknown provider %q (available: %s)", name, strings.Join(names(), ", "))
+		}
+		return p, nil
+	}
+
+	// Longest match wins so "all-sessions" is not taken for a provider
+	// whose name happens to be a substring
+	var best Provider
+	for _, p := range Providers() {
+		if strings.Contains(binaryName, p.Match) && len(p.Match) > len(best.Match) {
+			best = p
+		}
+	}
+	if best.Name != "" {
+		return best, nil
+	}
+
+	if p, ok := Lookup(DefaultProvider); ok {
+		return p, nil
+	}
+	return Provider{}, fmt.Errorf("no provider registered")
+}
+
+func names() []string {
+	var list []string
+	for _, p := range Providers() {
+		list = append(list, p.Name)
+	}
+	return list
+}
@@ -0,0 +1,103 @@
+package adapters
+
+import (
+	"strings"
+	"testing"
+)
+
+// fakeAdapter implements just enough of Adapter for registry tests
+type fakeAdapter struct {
+	Adapter
+	name    string
+	dataDir string
+}
+
+func (f *fakeAdapter) Name() string    { return f.name }
+func (f *fakeAdapter) DataDir() string { return f.dataDir }
+
+func registerFake(name, match, dataDir string) {
+	Register(Provider{
+		Name:  name,
+		Match: match,
+		New:   func() Adapter { return &fakeAdapter{name: name, dataDir: dataDir} },
+	})
+}
+
+func init() {
+	registerFake(DefaultProvider, "", "/nonexistent")
+	registerFake("opencode", "", "/nonexistent")
+	registerFake("all", "all-sessions", "/nonexistent")
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		name       string
+		flag       string
+		env        string
+		binaryName string
+		want       string
+	}{
+		{"flag wins", "opencode", "all", "claude-sessions", "opencode"},
+		{"env over binary name", "", "opencode", "all-sessions", "opencode"},
+		{"binary name", "", "", "opencode-sessions-dev", "opencode"},
+		{"longest match", "", "", "all-sessions", "all"},
+		{"default", "", "", "sessions", DefaultProvider},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Select(tt.flag, tt.env, tt.binaryName)
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			if p.Name != tt.want {
+				t.Errorf("Select() = %q, want %q", p.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelect_Unknown(t *testing.T) {
+	_, err := Select("nope", "", "claude-sessions")
+	if err == nil {
+		t.Fatal("Select() should error for unknown providers")
+	}
+	if !strings.Contains(err.Error(), "opencode") {
+		t.Errorf("error %q should list the available providers", err)
+	}
+}
+
+func TestRegister_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Register() should panic on duplicate names")
+		}
+	}()
+	registerFake("opencode", "", "")
+}
+
+func TestProviders_Sorted(t *testing.T) {
+	providers := Providers()
+	for i := 1; i < len(providers); i++ {
+		if providers[i-1].Name > providers[i].Name {
+			t.Errorf("Providers() not sorted: %q before %q", providers[i-1].Name, providers[i].Name)
+		}
+	}
+}
+
+func TestDetected(t *testing.T) {
+	dir := t.TempDir()
+	p := Provider{Name: "x", New: func() Adapter { return nil }}
+
+	if !p.Detected(&fakeAdapter{dataDir: dir}) {
+		t.Error("Detected() = false for an existing data dir")
+	}
+	if p.Detected(&fakeAdapter{dataDir: dir + "/missing"}) {
+		t.Error("Detected() = true for a missing data dir")
+	}
+
+	p.Detect = func(Adapter) bool { return false }
+	if p.Detected(&fakeAdapter{dataDir: dir}) {
+		t.Error("Detected() should defer to the provider's probe")
+	}
+}
@@ -2,17 +2,31 @@ package cache
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+	"github.com/Julian194/claude-sessions-tui/internal/pricing"
+	"github.com/Julian194/claude-sessions-tui/internal/safefile"
+	"github.com/Julian194/claude-sessions-tui/internal/search"
 )
 
+// Version is the cache file format Write produces. Files from before
+// versioning (version 1) are still read, but their entries have no
+// details, so the next BuildIncremental extracts them again.
+const Version = 2
+
+// versionPrefix starts the first line of a versioned cache file
+const versionPrefix = "#sessions-cache\t"
+
 // Entry represents a single cache entry
 type Entry struct {
 	SessionID string
@@ -20,6 +34,12 @@ type Entry struct {
 	Project   string
 	Summary   string
 	ParentSID string // Parent session ID for branches
+
+	// Details gathered while caching, so listings and reports don't need
+	// to parse session files. What a provider can't tell is left zero.
+	adapters.SessionDetails
+
+	legacy bool // Read from a version 1 file, so the details are missing
 }
 
 // Deprecated: ID is deprecated, use SessionID instead
@@ -27,6 +47,19 @@ func (e Entry) ID() string {
 	return e.SessionID
 }
 
+// Tokens returns the session's input, output and cache tokens
+func (e Entry) Tokens() int {
+	return e.Usage.InputTokens + e.Usage.OutputTokens + e.Usage.CacheRead + e.Usage.CacheWrite
+}
+
+// Duration returns the time between the first and last message
+func (e Entry) Duration() time.Duration {
+	if e.Start.IsZero() || e.End.IsZero() {
+		return 0
+	}
+	return e.End.Sub(e.Start)
+}
+
 // Cache manages the session cache file
 type Cache struct {
 	path string
@@ -47,46 +80,74 @@ func (c *Cache) Write(entries []Entry) error {
 	return Write(c.path, entries)
 }
 
-// Write writes entries to a cache file in TSV format (standalone function)
+// Write writes entries to a cache file in TSV format (standalone function).
+// The file is replaced atomically while holding the cache dir's lock.
+// Builds that started from the file's entries write through Commit.
 func Write(path string, entries []Entry) error {
-	// Ensure directory exists
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
-
-	f, err := os.Create(path)
+	unlock, err := safefile.Lock(filepath.Dir(path))
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	defer unlock()
+	return write(path, time.Time{}, entries)
+}
+
+// write replaces the cache file, dated mtime unless that is zero. The
+// caller holds the lock.
+func write(path string, mtime time.Time, entries []Entry) error {
+	return safefile.WriteWithModTime(path, mtime, func(w *bufio.Writer) error {
+		return writeEntries(w, entries)
+	})
+}
 
+func writeEntries(w *bufio.Writer, entries []Entry) error {
+	fmt.Fprintf(w, "%s%d\n", versionPrefix, Version)
 	for _, e := range entries {
-		// Escape special characters in fields
-		summary := escapeTSV(e.Summary)
-		project := escapeTSV(e.Project)
-
-		// TSV format: sid, date, project, summary, mtime, parent_sid, full_date
-		parentSID := e.ParentSID
-		if parentSID == "" {
-			parentSID = "-"
-		}
+		w.WriteString(formatEntry(e))
+		w.WriteByte('\n')
+	}
+	return nil // bufio keeps the first error for Flush
+}
 
-		line := fmt.Sprintf("%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
-			e.SessionID,
-			e.Date.Format("15:04"),
-			project,
-			summary,
-			e.Date.Unix(),
-			parentSID,
-			e.Date.Format("2006-01-02"),
-		)
-		if _, err := f.WriteString(line); err != nil {
-			return err
-		}
+// formatEntry returns the TSV line of an entry, without the newline
+func formatEntry(e Entry) string {
+	parentSID := e.ParentSID
+	if parentSID == "" {
+		parentSID = "-"
+	}
+	models := strings.Join(e.Models, ",")
+	if models == "" {
+		models = "-"
 	}
 
-	return nil
+	// TSV format: sid, date, project, summary, mtime, parent_sid, full_date,
+	// then work_dir, branch, models, user_msgs, assistant_msgs, input,
+	// output, cache_read, cache_write, cost, start, end, files_read,
+	// files_changed
+	fields := []string{
+		e.SessionID,
+		e.Date.Format("15:04"),
+		escapeTSV(e.Project),
+		escapeTSV(e.Summary),
+		strconv.FormatInt(e.Date.Unix(), 10),
+		parentSID,
+		e.Date.Format("2006-01-02"),
+		escapeTSV(e.WorkDir),
+		escapeTSV(e.Branch),
+		escapeTSV(models),
+		strconv.Itoa(e.UserMessages),
+		strconv.Itoa(e.AssistantMessages),
+		strconv.Itoa(e.Usage.InputTokens),
+		strconv.Itoa(e.Usage.OutputTokens),
+		strconv.Itoa(e.Usage.CacheRead),
+		strconv.Itoa(e.Usage.CacheWrite),
+		strconv.FormatFloat(e.Usage.Cost, 'f', -1, 64),
+		formatUnix(e.Start),
+		formatUnix(e.End),
+		strconv.Itoa(e.FilesRead),
+		strconv.Itoa(e.FilesChanged),
+	}
+	return strings.Join(fields, "\t")
 }
 
 // Read reads entries from the cache file
@@ -94,7 +155,8 @@ func (c *Cache) Read() ([]Entry, error) {
 	return Read(c.path)
 }
 
-// Read reads entries from a cache file (standalone function)
+// Read reads entries from a cache file (standalone function). Version 1
+// files are migrated on the fly.
 func Read(path string) ([]Entry, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -104,43 +166,102 @@ func Read(path string) ([]Entry, error) {
 
 	var entries []Entry
 	scanner := bufio.NewScanner(f)
+	version := 1
 
-	for scanner.Scan() {
+	for first := true; scanner.Scan(); first = false {
 		line := scanner.Text()
-		parts := strings.Split(line, "\t")
-		if len(parts) < 4 {
-			continue // Skip malformed lines
+		if first && strings.HasPrefix(line, versionPrefix) {
+			version, _ = strconv.Atoi(strings.TrimPrefix(line, versionPrefix))
+			if version > Version {
+				return nil, fmt.Errorf("cache format version %d is newer than this build supports (%d)", version, Version)
+			}
+			continue
 		}
 
-		// Parse mtime if available (column 5)
-		var date time.Time
-		if len(parts) >= 5 {
-			var mtime int64
-			fmt.Sscanf(parts[4], "%d", &mtime)
-			date = time.Unix(mtime, 0)
-		} else {
-			// Fallback to parsing time from column 2
-			date, _ = time.Parse("15:04", parts[1])
+		parts := strings.Split(line, "\t")
+		if version == 1 {
+			if e, ok := parseLegacy(parts); ok {
+				entries = append(entries, e)
+			}
+			continue
+		}
+		if len(parts) < 21 {
+			continue // Skip malformed lines
 		}
 
-		// Parse parent_sid if available (column 6)
 		parentSID := ""
-		if len(parts) >= 6 && parts[5] != "-" {
+		if parts[5] != "-" {
 			parentSID = parts[5]
 		}
+		var models []string
+		if m := unescapeTSV(parts[9]); m != "-" {
+			models = strings.Split(m, ",")
+		}
+		cost, _ := strconv.ParseFloat(parts[16], 64)
 
 		entries = append(entries, Entry{
 			SessionID: parts[0],
-			Date:      date,
+			Date:      parseUnix(parts[4]),
 			Project:   unescapeTSV(parts[2]),
 			Summary:   unescapeTSV(parts[3]),
 			ParentSID: parentSID,
+			SessionDetails: adapters.SessionDetails{
+				WorkDir:           unescapeTSV(parts[7]),
+				Branch:            unescapeTSV(parts[8]),
+				Models:            models,
+				UserMessages:      atoi(parts[10]),
+				AssistantMessages: atoi(parts[11]),
+				Usage: adapters.Totals{
+					InputTokens:  atoi(parts[12]),
+					OutputTokens: atoi(parts[13]),
+					CacheRead:    atoi(parts[14]),
+					CacheWrite:   atoi(parts[15]),
+					Cost:         cost,
+				},
+				Start:        parseUnix(parts[17]),
+				End:          parseUnix(parts[18]),
+				FilesRead:    atoi(parts[19]),
+				FilesChanged: atoi(parts[20]),
+			},
 		})
 	}
 
 	return entries, scanner.Err()
 }
 
+// parseLegacy parses a line of a version 1 cache file: sid, date,
+// project, summary, mtime, parent_sid, full_date
+func parseLegacy(parts []string) (Entry, bool) {
+	if len(parts) < 4 {
+		return Entry{}, false // Skip malformed lines
+	}
+
+	// Parse mtime if available (column 5)
+	var date time.Time
+	if len(parts) >= 5 {
+		date = parseUnix(parts[4])
+	} else {
+		// Fallback to parsing time from column 2
+		date, _ = time.Parse("15:04", parts[1])
+	}
+
+	// Parse parent_sid if available (column 6)
+	parentSID := ""
+	if len(parts) >= 6 && parts[5] != "-" {
+		parentSID = parts[5]
+	}
+
+	// Version 1 replaced tabs and newlines with spaces and had no escapes
+	return Entry{
+		SessionID: parts[0],
+		Date:      date,
+		Project:   parts[2],
+		Summary:   parts[3],
+		ParentSID: parentSID,
+		legacy:    true,
+	}, true
+}
+
 // Exists checks if the cache file exists
 func (c *Cache) Exists() bool {
 	_, err := os.Stat(c.path)
@@ -164,9 +285,129 @@ func (c *Cache) Clear() error {
 	return os.Remove(c.path)
 }
 
+// Commit writes the entries of a build to a cache file and returns what
+// it wrote. existing is the cache as it was read before the build, which
+// started at started. Other processes may have edited the file since, so
+// it is read again under the lock and their edits win: sessions removed
+// from it stay removed, and entries changed in it are kept over the built
+// ones. The file is dated started, so sessions that changed during the
+// build are extracted again by the next one.
+func Commit(path string, existing, built []Entry, started time.Time) ([]Entry, error) {
+	unlock, err := safefile.Lock(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	before := make(map[string]string, len(existing))
+	for _, e := range existing {
+		before[e.SessionID] = formatEntry(e)
+	}
+	current, err := Read(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	now := make(map[string]Entry, len(current))
+	for _, e := range current {
+		now[e.SessionID] = e
+	}
+
+	entries := make([]Entry, 0, len(built))
+	for _, e := range built {
+		line, cached := before[e.SessionID]
+		c, ok := now[e.SessionID]
+		switch {
+		case cached && !ok:
+			continue // Removed meanwhile
+		case ok && formatEntry(c) != line:
+			e = c // Edited meanwhile
+		}
+		entries = append(entries, e)
+	}
+	return entries, write(path, started, entries)
+}
+
+// Remove drops sessions from the cache file
+func (c *Cache) Remove(ids ...string) error {
+	return Remove(c.path, ids...)
+}
+
+// Remove drops sessions from a cache file (standalone function)
+func Remove(path string, ids ...string) error {
+	drop := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
+	return rewrite(path, func(entries []Entry) ([]Entry, bool) {
+		kept := entries[:0]
+		for _, e := range entries {
+			if !drop[e.SessionID] {
+				kept = append(kept, e)
+			}
+		}
+		return kept, len(kept) != len(entries)
+	})
+}
+
+// Update applies fn to a session's cache entry in place
+func (c *Cache) Update(id string, fn func(*Entry)) error {
+	return Update(c.path, id, fn)
+}
+
+// Update applies fn to a session's entry in a cache file (standalone
+// function). Sessions that aren't cached are left alone.
+func Update(path, id string, fn func(*Entry)) error {
+	return rewrite(path, func(entries []Entry) ([]Entry, bool) {
+		for i := range entries {
+			if entries[i].SessionID == id {
+				fn(&entries[i])
+				return entries, true
+			}
+		}
+		return entries, false
+	})
+}
+
+// rewrite edits a cache file. The file's modification time is
+// kept so incremental builds still pick up sessions that changed since
+// the last build.
+func rewrite(path string, edit func([]Entry) ([]Entry, bool)) error {
+	// Hold the lock from reading to replacing, so concurrent edits and
+	// builds don't undo each other
+	unlock, err := safefile.Lock(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	entries, err := Read(path)
+	if err != nil {
+		return err
+	}
+
+	entries, changed := edit(entries)
+	if !changed {
+		return nil
+	}
+	mtime := info.ModTime()
+	if len(entries) > 0 && entries[0].legacy {
+		// The file is now versioned but its entries still lack details;
+		// dating it back makes the next build extract them all
+		mtime = time.Unix(0, 0)
+	}
+	return write(path, mtime, entries)
+}
+
 // BuildFrom builds the cache from an adapter
-func (c *Cache) BuildFrom(adapter adapters.Adapter) error {
-	entries, err := BuildFrom(adapter)
+func (c *Cache) BuildFrom(ctx context.Context, adapter adapters.Adapter) error {
+	entries, err := BuildFrom(ctx, adapter)
 	if err != nil {
 		return err
 	}
@@ -174,12 +415,20 @@ func (c *Cache) BuildFrom(adapter adapters.Adapter) error {
 }
 
 // BuildFrom builds cache entries from an adapter (standalone function)
-func BuildFrom(adapter adapters.Adapter) ([]Entry, error) {
-	return BuildIncremental(adapter, "", nil)
+func BuildFrom(ctx context.Context, adapter adapters.Adapter) ([]Entry, error) {
+	return BuildIncremental(ctx, adapter, "", nil, nil)
 }
 
-// BuildIncremental builds cache entries incrementally, only processing files newer than cache
-func BuildIncremental(adapter adapters.Adapter, cachePath string, existing []Entry) ([]Entry, error) {
+// ProgressFunc is called as metadata extraction advances. done counts
+// sessions extracted so far out of total sessions that needed it;
+// sessions reused from the existing cache are not counted.
+type ProgressFunc func(done, total int)
+
+// BuildIncremental builds cache entries incrementally, only processing files newer than cache.
+// With a cachePath it also updates the full-text search index next to it.
+// Cancelling ctx stops the workers after their current session and returns ctx.Err().
+// progress may be nil; it is always called from a single goroutine.
+func BuildIncremental(ctx context.Context, adapter adapters.Adapter, cachePath string, existing []Entry, progress ProgressFunc) ([]Entry, error) {
 	// Get cache mtime for incremental check
 	var cacheMtime time.Time
 	if cachePath != "" {
@@ -187,6 +436,11 @@ func BuildIncremental(adapter adapters.Adapter, cachePath string, existing []Ent
 			cacheMtime = info.ModTime()
 		}
 	}
+	// Costs are priced as sessions are extracted, so changed prices mean
+	// extracting them all again
+	if info, err := os.Stat(pricing.Path()); err == nil && info.ModTime().After(cacheMtime) {
+		cacheMtime = time.Time{}
+	}
 
 	// Build lookup of existing entries
 	existingMap := make(map[string]Entry)
@@ -195,7 +449,7 @@ func BuildIncremental(adapter adapters.Adapter, cachePath string, existing []Ent
 	}
 
 	// Step 1: ListSessions fills the path cache in the adapter
-	sessions, err := adapter.ListSessions()
+	sessions, err := adapter.ListSessions(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -220,9 +474,10 @@ func BuildIncremental(adapter adapters.Adapter, cachePath string, existing []Ent
 			continue
 		}
 
-		// If cache exists and file is older, use existing entry
+		// If cache exists and file is older, use existing entry, unless
+		// it was migrated from a cache without details
 		if !cacheMtime.IsZero() && info.ModTime().Before(cacheMtime) {
-			if existing, ok := existingMap[id]; ok {
+			if existing, ok := existingMap[id]; ok && !existing.legacy {
 				reusableEntries = append(reusableEntries, existing)
 				continue
 			}
@@ -232,6 +487,11 @@ func BuildIncremental(adapter adapters.Adapter, cachePath string, existing []Ent
 		jobsToProcess = append(jobsToProcess, job{id: id})
 	}
 
+	total := len(jobsToProcess)
+	if progress != nil {
+		progress(0, total)
+	}
+
 	// Step 2: Parallel extraction with worker pool
 	type metaResult struct {
 		entry Entry
@@ -250,25 +510,29 @@ func BuildIncremental(adapter adapters.Adapter, cachePath string, existing []Ent
 		go func() {
 			defer wg.Done()
 			for j := range jobs {
-				meta, err := adapter.ExtractMeta(j.id)
+				// Leave the remaining jobs unprocessed once cancelled
+				if ctx.Err() != nil {
+					return
+				}
+				meta, err := adapter.ExtractMeta(ctx, j.id)
 				if err != nil {
 					results <- metaResult{err: err}
 					continue
 				}
 				results <- metaResult{
-					entry: Entry{
+					entry: withDetails(ctx, adapter, Entry{
 						SessionID: meta.ID,
 						Date:      meta.Date,
 						Project:   meta.Project,
 						Summary:   meta.Summary,
 						ParentSID: meta.ParentSID,
-					},
+					}),
 				}
 			}
 		}()
 	}
 
-	// Send jobs
+	// Send jobs; the channel is buffered for all of them so this never blocks
 	for _, j := range jobsToProcess {
 		jobs <- j
 	}
@@ -282,31 +546,119 @@ func BuildIncremental(adapter adapters.Adapter, cachePath string, existing []Ent
 
 	// Collect results
 	var extractedEntries []Entry
+	done := 0
 	for result := range results {
+		done++
+		if progress != nil {
+			progress(done, total)
+		}
 		if result.err == nil {
 			extractedEntries = append(extractedEntries, result.entry)
 		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// Combine reusable and extracted entries
 	allEntries := make([]Entry, 0, len(reusableEntries)+len(extractedEntries))
 	allEntries = append(allEntries, reusableEntries...)
 	allEntries = append(allEntries, extractedEntries...)
 
+	// Keep the full-text index next to the cache in step with it
+	if cachePath != "" {
+		ids := make([]string, len(allEntries))
+		for i, e := range allEntries {
+			ids[i] = e.SessionID
+		}
+		if _, err := search.Update(ctx, adapter, filepath.Join(filepath.Dir(cachePath), search.IndexName), ids); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			return nil, fmt.Errorf("updating search index: %w", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
 	return allEntries, nil
 }
 
+// withDetails fills in the details of an entry from the adapter. Providers
+// that can gather them in one pass do; the others fall back to the
+// optional lookups they support.
+func withDetails(ctx context.Context, adapter adapters.Adapter, e Entry) Entry {
+	if d, err := adapters.GetDetails(ctx, adapter, e.SessionID); err == nil && d != nil {
+		e.SessionDetails = *d
+	}
+	return e
+}
+
 // Helper functions
 
+// escapeTSV escapes backslashes, tabs and line breaks so any text fits in
+// a field and reads back unchanged
 func escapeTSV(s string) string {
-	// Replace tabs and newlines with spaces
-	s = strings.ReplaceAll(s, "\t", " ")
-	s = strings.ReplaceAll(s, "\n", " ")
-	s = strings.ReplaceAll(s, "\r", " ")
-	return s
+	return tsvEscaper.Replace(s)
 }
 
 func unescapeTSV(s string) string {
-	// Currently no escaping needed for reading
-	return s
+	return tsvUnescaper.Replace(s)
+}
+
+var (
+	tsvEscaper   = strings.NewReplacer("\\", `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+	tsvUnescaper = strings.NewReplacer(`\\`, "\\", `\t`, "\t", `\n`, "\n", `\r`, "\r")
+)
+
+func formatUnix(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+// parseUnix parses a Unix time field; 0 reads as the zero time
+func parseUnix(s string) time.Time {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(n, 0)
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// SortKeys are the orders Sort accepts
+var SortKeys = []string{"date", "cost", "tokens", "messages", "files", "duration"}
+
+// Sort orders entries by one of SortKeys, largest or newest first, using
+// only the cached details
+func Sort(entries []Entry, by string) error {
+	var key func(Entry) float64
+	switch by {
+	case "date":
+		key = func(e Entry) float64 { return float64(e.Date.Unix()) }
+	case "cost":
+		key = func(e Entry) float64 { return e.Usage.Cost }
+	case "tokens":
+		key = func(e Entry) float64 { return float64(e.Tokens()) }
+	case "messages":
+		key = func(e Entry) float64 { return float64(e.UserMessages + e.AssistantMessages) }
+	case "files":
+		key = func(e Entry) float64 { return float64(e.FilesChanged) }
+	case "duration":
+		key = func(e Entry) float64 { return e.Duration().Seconds() }
+	default:
+		return fmt.Errorf("unknown sort key %q (one of: %s)", by, strings.Join(SortKeys, ", "))
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return key(entries[i]) > key(entries[j])
+	})
+	return nil
 }
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -99,7 +100,7 @@ func BenchmarkBuildIncremental_Mock(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := BuildIncremental(mock, cachePath, nil)
+		_, err := BuildIncremental(context.Background(), mock, cachePath, nil, nil)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -124,7 +125,7 @@ func BenchmarkRealWorld_BuildIncremental(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		entries, err := BuildIncremental(adapter, cachePath, existing)
+		entries, err := BuildIncremental(context.Background(), adapter, cachePath, existing, nil)
 		if err != nil {
 			b.Fatal(err)
 		}
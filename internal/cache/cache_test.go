@@ -1,14 +1,20 @@
 package cache
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+	"github.com/Julian194/claude-sessions-tui/internal/search"
 )
 
 // mockAdapter implements adapters.Adapter for testing
@@ -16,15 +22,20 @@ type mockAdapter struct {
 	sessions    []string
 	sessionFile map[string]string
 	metas       map[string]*adapters.SessionMeta
+	onExtract   func(ctx context.Context, id string) // Called before each ExtractMeta
+	messages    map[string][]adapters.Message
 }
 
-func (m *mockAdapter) Name() string                    { return "mock" }
-func (m *mockAdapter) DataDir() string                 { return "/mock/data" }
-func (m *mockAdapter) CacheDir() string                { return "/mock/cache" }
-func (m *mockAdapter) ResumeCmd(id string) string      { return "mock resume " + id }
-func (m *mockAdapter) ListSessions() ([]string, error) { return m.sessions, nil }
-func (m *mockAdapter) GetSessionFile(id string) string { return m.sessionFile[id] }
-func (m *mockAdapter) ExtractMeta(id string) (*adapters.SessionMeta, error) {
+func (m *mockAdapter) Name() string                                       { return "mock" }
+func (m *mockAdapter) DataDir() string                                    { return "/mock/data" }
+func (m *mockAdapter) CacheDir() string                                   { return "/mock/cache" }
+func (m *mockAdapter) ResumeCmd(id string) string                         { return "mock resume " + id }
+func (m *mockAdapter) ListSessions(ctx context.Context) ([]string, error) { return m.sessions, nil }
+func (m *mockAdapter) GetSessionFile(id string) string                    { return m.sessionFile[id] }
+func (m *mockAdapter) ExtractMeta(ctx context.Context, id string) (*adapters.SessionMeta, error) {
+	if m.onExtract != nil {
+		m.onExtract(ctx, id)
+	}
 	if meta, ok := m.metas[id]; ok {
 		return meta, nil
 	}
@@ -35,10 +46,14 @@ func (m *mockAdapter) GetSummaries(id string) ([]string, error)                {
 func (m *mockAdapter) GetFilesTouched(id string) ([]string, error)             { return nil, nil }
 func (m *mockAdapter) GetSlashCommands(id string) ([]string, error)            { return nil, nil }
 func (m *mockAdapter) GetModels(id string) ([]string, error)                   { return nil, nil }
-func (m *mockAdapter) GetStats(id string) (*adapters.Stats, error)             { return nil, nil }
-func (m *mockAdapter) GetFirstMessage(id string) (string, error)               { return "", nil }
-func (m *mockAdapter) ExportMessages(id string) ([]adapters.Message, error)    { return nil, nil }
-func (m *mockAdapter) BranchSession(id string) (string, error)                 { return "", nil }
+func (m *mockAdapter) GetStats(ctx context.Context, id string) (*adapters.Stats, error) {
+	return &adapters.Stats{}, nil
+}
+func (m *mockAdapter) GetFirstMessage(id string) (string, error) { return "", nil }
+func (m *mockAdapter) ExportMessages(ctx context.Context, id string) ([]adapters.Message, error) {
+	return m.messages[id], nil
+}
+func (m *mockAdapter) BranchSession(id string) (string, error) { return "", nil }
 
 func TestWriteAndRead(t *testing.T) {
 	// Create temp directory
@@ -109,7 +124,7 @@ func TestEscapeSpecialChars(t *testing.T) {
 			SessionID: "session-special",
 			Date:      time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC),
 			Project:   "project",
-			Summary:   "Summary with\ttab and\nnewline",
+			Summary:   "Summary with\ttab and\nnewline, C:\\path\\n",
 		},
 	}
 
@@ -123,10 +138,9 @@ func TestEscapeSpecialChars(t *testing.T) {
 		t.Fatalf("Read() error = %v", err)
 	}
 
-	// Tabs and newlines should be escaped to spaces
-	expected := "Summary with tab and newline"
-	if got[0].Summary != expected {
-		t.Errorf("Summary = %q, want %q", got[0].Summary, expected)
+	// Tabs and newlines are escaped and read back unchanged
+	if got[0].Summary != entries[0].Summary {
+		t.Errorf("Summary = %q, want %q", got[0].Summary, entries[0].Summary)
 	}
 }
 
@@ -249,10 +263,11 @@ func TestEscapeTSV(t *testing.T) {
 		want  string
 	}{
 		{"hello", "hello"},
-		{"with\ttab", "with tab"},
-		{"with\nnewline", "with newline"},
-		{"with\rcarriage", "with carriage"},
-		{"multiple\t\n\rchars", "multiple   chars"},
+		{"with\ttab", `with\ttab`},
+		{"with\nnewline", `with\nnewline`},
+		{"with\rcarriage", `with\rcarriage`},
+		{`back\slash\n`, `back\\slash\\n`},
+		{"multiple\t\n\rchars", `multiple\t\n\rchars`},
 	}
 
 	for _, tt := range tests {
@@ -260,6 +275,9 @@ func TestEscapeTSV(t *testing.T) {
 		if got != tt.want {
 			t.Errorf("escapeTSV(%q) = %q, want %q", tt.input, got, tt.want)
 		}
+		if back := unescapeTSV(got); back != tt.input {
+			t.Errorf("unescapeTSV(%q) = %q, want %q", got, back, tt.input)
+		}
 	}
 }
 
@@ -282,7 +300,7 @@ func TestBuildFrom(t *testing.T) {
 		},
 	}
 
-	entries, err := BuildFrom(mock)
+	entries, err := BuildFrom(context.Background(), mock)
 	if err != nil {
 		t.Fatalf("BuildFrom() error = %v", err)
 	}
@@ -343,7 +361,7 @@ func TestBuildIncremental(t *testing.T) {
 	time.Sleep(10 * time.Millisecond)
 	os.Chtimes(session2File, time.Now(), time.Now())
 
-	entries, err := BuildIncremental(mock, cachePath, existing)
+	entries, err := BuildIncremental(context.Background(), mock, cachePath, existing, nil)
 	if err != nil {
 		t.Fatalf("BuildIncremental() error = %v", err)
 	}
@@ -353,6 +371,106 @@ func TestBuildIncremental(t *testing.T) {
 	}
 }
 
+func TestBuildIncremental_PricingChanged(t *testing.T) {
+	tmpDir := t.TempDir()
+	cachePath := filepath.Join(tmpDir, "cache.tsv")
+	pricingFile := filepath.Join(tmpDir, "pricing.json")
+	t.Setenv("SESSIONS_PRICING_FILE", pricingFile)
+
+	sessionFile := filepath.Join(tmpDir, "s1.jsonl")
+	os.WriteFile(sessionFile, []byte("{}"), 0644)
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes(sessionFile, past, past)
+	mock := &mockAdapter{
+		sessions:    []string{"s1"},
+		sessionFile: map[string]string{"s1": sessionFile},
+		metas:       map[string]*adapters.SessionMeta{"s1": {ID: "s1", Date: past, Summary: "Extracted"}},
+	}
+	existing := []Entry{{SessionID: "s1", Date: past, Summary: "Cached"}}
+	Write(cachePath, existing)
+
+	entries, _ := BuildIncremental(context.Background(), mock, cachePath, existing, nil)
+	if len(entries) != 1 || entries[0].Summary != "Cached" {
+		t.Fatalf("BuildIncremental() = %+v, want the cached entry", entries)
+	}
+
+	// Prices edited after the cache was written
+	os.WriteFile(pricingFile, []byte("{}"), 0644)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(pricingFile, future, future)
+	entries, _ = BuildIncremental(context.Background(), mock, cachePath, existing, nil)
+	if len(entries) != 1 || entries[0].Summary != "Extracted" {
+		t.Errorf("BuildIncremental() = %+v, want the session extracted again", entries)
+	}
+}
+
+func TestBuildIncremental_UpdatesSearchIndex(t *testing.T) {
+	tmpDir := t.TempDir()
+	cachePath := filepath.Join(tmpDir, "cache.tsv")
+	indexPath := filepath.Join(tmpDir, search.IndexName)
+
+	files := map[string]string{}
+	metas := map[string]*adapters.SessionMeta{}
+	for _, id := range []string{"s1", "s2"} {
+		files[id] = filepath.Join(tmpDir, id+".jsonl")
+		os.WriteFile(files[id], []byte("{}"), 0644)
+		metas[id] = &adapters.SessionMeta{ID: id, Date: time.Now()}
+	}
+	mock := &mockAdapter{
+		sessions:    []string{"s1", "s2"},
+		sessionFile: files,
+		metas:       metas,
+		messages: map[string][]adapters.Message{
+			"s1": {{Role: "user", Content: "fix the flaky scheduler test"}},
+			"s2": {{Role: "user", Content: "write release notes"}},
+		},
+	}
+
+	entries, err := BuildIncremental(context.Background(), mock, cachePath, nil, nil)
+	if err != nil {
+		t.Fatalf("BuildIncremental() error = %v", err)
+	}
+	Write(cachePath, entries)
+	ix, err := search.Load(indexPath)
+	if err != nil {
+		t.Fatalf("search index not written: %v", err)
+	}
+	if got := ix.Search(search.ParseQuery("scheduler")); len(got) != 1 || got[0].SessionID != "s1" {
+		t.Errorf("Search(scheduler) = %v, want s1", got)
+	}
+
+	// A changed session is indexed again, a vanished one dropped
+	time.Sleep(10 * time.Millisecond)
+	os.Chtimes(files["s2"], time.Now(), time.Now())
+	mock.messages["s2"] = []adapters.Message{{Role: "user", Content: "tune the scheduler"}}
+	mock.sessions = []string{"s2"}
+	if _, err := BuildIncremental(context.Background(), mock, cachePath, entries, nil); err != nil {
+		t.Fatalf("BuildIncremental() error = %v", err)
+	}
+	ix, _ = search.Load(indexPath)
+	if got := ix.Search(search.ParseQuery("scheduler")); len(got) != 1 || got[0].SessionID != "s2" {
+		t.Errorf("Search(scheduler) after update = %v, want s2", got)
+	}
+}
+
+func TestBuildIncremental_SearchIndexError(t *testing.T) {
+	tmpDir := t.TempDir()
+	sessionFile := filepath.Join(tmpDir, "s1.jsonl")
+	os.WriteFile(sessionFile, []byte("{}"), 0644)
+	// A directory in the index's place can't be replaced
+	os.Mkdir(filepath.Join(tmpDir, search.IndexName), 0755)
+
+	mock := &mockAdapter{
+		sessions:    []string{"s1"},
+		sessionFile: map[string]string{"s1": sessionFile},
+		metas:       map[string]*adapters.SessionMeta{"s1": {ID: "s1", Date: time.Now()}},
+	}
+	entries, err := BuildIncremental(context.Background(), mock, filepath.Join(tmpDir, "cache.tsv"), nil, nil)
+	if err == nil {
+		t.Errorf("BuildIncremental() = %v, nil; want the index error", entries)
+	}
+}
+
 func TestCacheBuildFrom(t *testing.T) {
 	tmpDir := t.TempDir()
 	cachePath := filepath.Join(tmpDir, "cache.tsv")
@@ -374,7 +492,7 @@ func TestCacheBuildFrom(t *testing.T) {
 	}
 
 	c := New(cachePath)
-	err := c.BuildFrom(mock)
+	err := c.BuildFrom(context.Background(), mock)
 	if err != nil {
 		t.Fatalf("Cache.BuildFrom() error = %v", err)
 	}
@@ -442,6 +560,98 @@ func TestParentSIDRoundTrip(t *testing.T) {
 	}
 }
 
+func TestDetailsRoundTrip(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "cache.tsv")
+	want := Entry{
+		SessionID: "s1",
+		Date:      time.Unix(1736935200, 0), // Read returns local times
+		Project:   "project",
+		Summary:   "Summary",
+		SessionDetails: adapters.SessionDetails{
+			WorkDir:           "/home/me/my\tproject",
+			Branch:            "feature/cache",
+			Models:            []string{"claude-sonnet-4", "claude-haiku-4"},
+			UserMessages:      3,
+			AssistantMessages: 7,
+			Usage:             adapters.Totals{InputTokens: 1200, OutputTokens: 340, CacheRead: 5000, CacheWrite: 800, Cost: 0.0425},
+			Start:             time.Unix(1736932320, 0),
+			End:               time.Unix(1736935080, 0),
+			FilesRead:         4,
+			FilesChanged:      2,
+		},
+	}
+	if err := Write(cachePath, []Entry{want, {SessionID: "s2", Date: want.Date}}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	got, err := Read(cachePath)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Read() returned %d entries, want 2", len(got))
+	}
+	if fmt.Sprintf("%+v", got[0]) != fmt.Sprintf("%+v", want) {
+		t.Errorf("Read() = %+v\nwant %+v", got[0], want)
+	}
+	if e := got[1]; e.Models != nil || !e.Start.IsZero() || !e.End.IsZero() || e.Usage.Cost != 0 {
+		t.Errorf("entry without details = %+v, want zero details", e)
+	}
+}
+
+func TestReadLegacy(t *testing.T) {
+	tmpDir := t.TempDir()
+	cachePath := filepath.Join(tmpDir, "cache.tsv")
+	os.WriteFile(cachePath, []byte("s1\t10:00\tproject\tC:\\new folder\t1705312800\t-\t2025-01-15\n"), 0644)
+
+	entries, err := Read(cachePath)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(entries) != 1 || !entries[0].legacy || entries[0].Summary != `C:\new folder` {
+		t.Fatalf("Read() = %+v, want one legacy entry with its summary as written", entries)
+	}
+
+	// Migrated entries are extracted again even though the session file
+	// is older than the cache
+	sessionFile := filepath.Join(tmpDir, "s1.jsonl")
+	os.WriteFile(sessionFile, []byte("{}"), 0644)
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes(sessionFile, past, past)
+	mock := &mockAdapter{
+		sessions:    []string{"s1"},
+		sessionFile: map[string]string{"s1": sessionFile},
+		metas:       map[string]*adapters.SessionMeta{"s1": {ID: "s1", Date: past, Summary: "Extracted"}},
+	}
+	built, err := BuildIncremental(context.Background(), mock, cachePath, entries, nil)
+	if err != nil {
+		t.Fatalf("BuildIncremental() error = %v", err)
+	}
+	if len(built) != 1 || built[0].Summary != "Extracted" || built[0].legacy {
+		t.Errorf("BuildIncremental() = %+v, want the entry extracted again", built)
+	}
+
+	// Editing a legacy cache in place versions it and dates it back
+	if err := Update(cachePath, "s1", func(e *Entry) { e.Summary = "Renamed" }); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	data, _ := os.ReadFile(cachePath)
+	if !strings.HasPrefix(string(data), fmt.Sprintf("#sessions-cache\t%d\n", Version)) {
+		t.Errorf("updated cache starts with %q, want a version line", strings.SplitN(string(data), "\n", 2)[0])
+	}
+	if info, _ := os.Stat(cachePath); info.ModTime().After(past) {
+		t.Errorf("updated legacy cache mtime = %v, want it before every session", info.ModTime())
+	}
+}
+
+func TestReadNewerVersion(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "cache.tsv")
+	os.WriteFile(cachePath, []byte(fmt.Sprintf("#sessions-cache\t%d\n", Version+1)), 0644)
+	if _, err := Read(cachePath); err == nil {
+		t.Error("Read() of a newer format should fail")
+	}
+}
+
 // Test that BuildIncremental processes all sessions correctly with parallelization
 func TestBuildIncremental_Parallel(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -474,7 +684,7 @@ func TestBuildIncremental_Parallel(t *testing.T) {
 		metas:       metas,
 	}
 
-	entries, err := BuildIncremental(mock, cachePath, nil)
+	entries, err := BuildIncremental(context.Background(), mock, cachePath, nil, nil)
 	if err != nil {
 		t.Fatalf("BuildIncremental() error = %v", err)
 	}
@@ -529,7 +739,7 @@ func TestBuildIncremental_Deterministic(t *testing.T) {
 	// Run multiple times
 	var counts []int
 	for i := 0; i < 5; i++ {
-		entries, err := BuildIncremental(mock, cachePath, nil)
+		entries, err := BuildIncremental(context.Background(), mock, cachePath, nil, nil)
 		if err != nil {
 			t.Fatalf("BuildIncremental() error = %v", err)
 		}
@@ -572,7 +782,7 @@ func TestBuildIncremental_ThreadSafety(t *testing.T) {
 		go func(n int) {
 			defer wg.Done()
 			cachePath := filepath.Join(tmpDir, fmt.Sprintf("cache-%d.tsv", n))
-			_, err := BuildIncremental(mock, cachePath, nil)
+			_, err := BuildIncremental(context.Background(), mock, cachePath, nil, nil)
 			if err != nil {
 				t.Errorf("Concurrent BuildIncremental error: %v", err)
 			}
@@ -580,3 +790,217 @@ func TestBuildIncremental_ThreadSafety(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+// newMockSessions creates n sessions with files and metadata
+func newMockSessions(t *testing.T, n int) *mockAdapter {
+	t.Helper()
+	tmpDir := t.TempDir()
+	mock := &mockAdapter{
+		sessionFile: make(map[string]string),
+		metas:       make(map[string]*adapters.SessionMeta),
+	}
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("session-%03d", i)
+		sessionFile := filepath.Join(tmpDir, id+".jsonl")
+		os.WriteFile(sessionFile, []byte(`{"type":"test"}`), 0644)
+		mock.sessions = append(mock.sessions, id)
+		mock.sessionFile[id] = sessionFile
+		mock.metas[id] = &adapters.SessionMeta{ID: id, Date: time.Now(), Project: "test", Summary: "Test"}
+	}
+	return mock
+}
+
+// Test that cancelling stops the worker pool before it drains the queue
+func TestBuildIncremental_Cancel(t *testing.T) {
+	mock := newMockSessions(t, 100)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls atomic.Int32
+	mock.onExtract = func(ctx context.Context, id string) {
+		calls.Add(1)
+		cancel()
+	}
+
+	entries, err := BuildIncremental(ctx, mock, "", nil, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("BuildIncremental() error = %v, want context.Canceled", err)
+	}
+	if entries != nil {
+		t.Errorf("BuildIncremental() returned %d entries after cancellation, want none", len(entries))
+	}
+	// Each worker finishes at most the session it was already extracting
+	if n := int(calls.Load()); n > runtime.NumCPU() {
+		t.Errorf("ExtractMeta called %d times after cancellation, want at most %d", n, runtime.NumCPU())
+	}
+}
+
+func TestBuildIncremental_Progress(t *testing.T) {
+	mock := newMockSessions(t, 10)
+
+	var reports [][2]int
+	progress := func(done, total int) {
+		reports = append(reports, [2]int{done, total})
+	}
+
+	if _, err := BuildIncremental(context.Background(), mock, "", nil, progress); err != nil {
+		t.Fatalf("BuildIncremental() error = %v", err)
+	}
+
+	if len(reports) != 11 {
+		t.Fatalf("progress called %d times, want 11: %v", len(reports), reports)
+	}
+	for i, r := range reports {
+		if r[0] != i || r[1] != 10 {
+			t.Errorf("report %d = %v, want [%d 10]", i, r, i)
+		}
+	}
+}
+
+func TestRemove(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "cache.tsv")
+	Write(cachePath, []Entry{
+		{SessionID: "keep", Date: time.Now(), Project: "p", Summary: "Keep"},
+		{SessionID: "drop", Date: time.Now(), Project: "p", Summary: "Drop"},
+	})
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	os.Chtimes(cachePath, old, old)
+
+	if err := Remove(cachePath, "drop"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+
+	got, _ := Read(cachePath)
+	if len(got) != 1 || got[0].SessionID != "keep" {
+		t.Errorf("Read() after Remove = %+v, want only keep", got)
+	}
+	// Incremental builds rely on the cache mtime, so it must not move
+	if info, _ := os.Stat(cachePath); !info.ModTime().Equal(old) {
+		t.Errorf("cache mtime = %v, want %v", info.ModTime(), old)
+	}
+
+	if err := Remove(filepath.Join(t.TempDir(), "missing.tsv"), "x"); err != nil {
+		t.Errorf("Remove() on a missing cache error = %v", err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "cache.tsv")
+	Write(cachePath, []Entry{
+		{SessionID: "a", Date: time.Now(), Project: "p", Summary: "Old title"},
+		{SessionID: "b", Date: time.Now(), Project: "p", Summary: "Other"},
+	})
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	os.Chtimes(cachePath, old, old)
+
+	err := Update(cachePath, "a", func(e *Entry) { e.Summary = "New title" })
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	got, _ := Read(cachePath)
+	if len(got) != 2 || got[0].Summary != "New title" || got[1].Summary != "Other" {
+		t.Errorf("Read() after Update = %+v", got)
+	}
+	if info, _ := os.Stat(cachePath); !info.ModTime().Equal(old) {
+		t.Errorf("cache mtime = %v, want %v", info.ModTime(), old)
+	}
+}
+
+func TestConcurrentWriters(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "cache.tsv")
+	var entries []Entry
+	for i := 0; i < 20; i++ {
+		entries = append(entries, Entry{SessionID: fmt.Sprintf("s%d", i), Date: time.Now(), Summary: "old"})
+	}
+	if err := Write(cachePath, entries); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	// Readers running alongside see the whole cache every time
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			got, err := Read(cachePath)
+			if err != nil || len(got) != len(entries) {
+				t.Errorf("Read() during writes = %d entries, %v; want %d", len(got), err, len(entries))
+				return
+			}
+		}
+	}()
+
+	// Each edit reads, changes and replaces the file; none may be lost
+	var wg sync.WaitGroup
+	for _, e := range entries {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			if err := Update(cachePath, id, func(e *Entry) { e.Summary = "new" }); err != nil {
+				t.Errorf("Update(%s) error = %v", id, err)
+			}
+		}(e.SessionID)
+	}
+	wg.Wait()
+	close(stop)
+	readers.Wait()
+
+	got, _ := Read(cachePath)
+	for _, e := range got {
+		if e.Summary != "new" {
+			t.Errorf("%s summary = %q, want the update kept", e.SessionID, e.Summary)
+		}
+	}
+}
+
+func TestCommit_KeepsConcurrentEdits(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "cache.tsv")
+	date := time.Unix(1736500000, 0)
+	Write(cachePath, []Entry{
+		{SessionID: "renamed", Date: date, Summary: "Old title"},
+		{SessionID: "deleted", Date: date, Summary: "Gone"},
+		{SessionID: "rebuilt", Date: date, Summary: "Stale"},
+	})
+	existing, _ := Read(cachePath)
+	started := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	// Another process renames and deletes while the build runs
+	Update(cachePath, "renamed", func(e *Entry) { e.Summary = "New title" })
+	Remove(cachePath, "deleted")
+
+	built := []Entry{
+		{SessionID: "renamed", Date: date, Summary: "Old title"},
+		{SessionID: "deleted", Date: date, Summary: "Gone"},
+		{SessionID: "rebuilt", Date: date, Summary: "Fresh"},
+		{SessionID: "added", Date: date, Summary: "New session"},
+	}
+	entries, err := Commit(cachePath, existing, built, started)
+	if err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	got, _ := Read(cachePath)
+	if len(got) != len(entries) {
+		t.Errorf("Commit() returned %d entries but wrote %d", len(entries), len(got))
+	}
+	summaries := make(map[string]string)
+	for _, e := range got {
+		summaries[e.SessionID] = e.Summary
+	}
+	want := map[string]string{"renamed": "New title", "rebuilt": "Fresh", "added": "New session"}
+	if fmt.Sprint(summaries) != fmt.Sprint(want) {
+		t.Errorf("cache after Commit = %v, want %v", summaries, want)
+	}
+	// Sessions written during the build must look newer than the cache
+	if info, _ := os.Stat(cachePath); !info.ModTime().Equal(started) {
+		t.Errorf("cache mtime = %v, want the build's start %v", info.ModTime(), started)
+	}
+}
@@ -0,0 +1,267 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+	"github.com/Julian194/claude-sessions-tui/internal/safefile"
+)
+
+// FileIndexName is the reverse index's file name, next to the session cache
+const FileIndexName = "files-index.tsv"
+
+// FileRef records what one session did to one file. Sessions that touched
+// no files get a single reference with an empty Path, so later updates
+// know they were already read.
+type FileRef struct {
+	Path      string
+	SessionID string
+	Date      time.Time // The session's date, as in its cache entry
+	Ops       map[adapters.FileOp]int
+	FirstTurn int
+	LastTurn  int
+}
+
+// WriteFileIndex writes the reverse index in TSV format, replacing it
+// atomically while holding the cache dir's lock
+func WriteFileIndex(path string, refs []FileRef) error {
+	unlock, err := safefile.Lock(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return writeFileIndex(path, time.Time{}, refs)
+}
+
+func writeFileIndex(path string, mtime time.Time, refs []FileRef) error {
+	return safefile.WriteWithModTime(path, mtime, func(w *bufio.Writer) error {
+		for _, r := range refs {
+			// TSV format: path, sid, mtime, ops, first_turn, last_turn
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%d\t%d\n",
+				r.Path, r.SessionID, r.Date.Unix(), formatOps(r.Ops), r.FirstTurn, r.LastTurn)
+		}
+		return nil
+	})
+}
+
+// ReadFileIndex reads the reverse index
+func ReadFileIndex(path string) ([]FileRef, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var refs []FileRef
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.Split(scanner.Text(), "\t")
+		if len(parts) < 6 {
+			continue // Skip malformed lines
+		}
+		mtime, _ := strconv.ParseInt(parts[2], 10, 64)
+		first, _ := strconv.Atoi(parts[4])
+		last, _ := strconv.Atoi(parts[5])
+		refs = append(refs, FileRef{
+			Path:      parts[0],
+			SessionID: parts[1],
+			Date:      time.Unix(mtime, 0),
+			Ops:       parseOps(parts[3]),
+			FirstTurn: first,
+			LastTurn:  last,
+		})
+	}
+	return refs, scanner.Err()
+}
+
+// UpdateFileIndex brings the reverse index at path in line with the cache
+// entries. Like BuildIncremental, it only asks the adapter for the file
+// activity of sessions whose file changed since the index was written;
+// sessions no longer cached are dropped. The index is written back, dated
+// when the update started so sessions changed meanwhile are read again.
+func UpdateFileIndex(ctx context.Context, adapter adapters.Adapter, path string, entries []Entry) ([]FileRef, error) {
+	started := time.Now()
+	var indexMtime time.Time
+	if info, err := os.Stat(path); err == nil {
+		indexMtime = info.ModTime()
+	}
+	existing, _ := ReadFileIndex(path)
+	bySession := make(map[string][]FileRef)
+	for _, r := range existing {
+		bySession[r.SessionID] = append(bySession[r.SessionID], r)
+	}
+
+	var refs []FileRef
+	var stale []Entry
+	for _, e := range entries {
+		sessionPath := adapter.GetSessionFile(e.SessionID)
+		if sessionPath == "" {
+			continue
+		}
+		info, err := os.Stat(sessionPath)
+		if err != nil {
+			continue
+		}
+		if old, ok := bySession[e.SessionID]; ok && info.ModTime().Before(indexMtime) {
+			refs = append(refs, old...)
+			continue
+		}
+		stale = append(stale, e)
+	}
+
+	// Read the changed sessions in parallel, as BuildIncremental does
+	jobs := make(chan Entry, len(stale))
+	for _, e := range stale {
+		jobs <- e
+	}
+	close(jobs)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				activity, err := adapters.GetFileActivity(ctx, adapter, e.SessionID)
+				if err != nil {
+					continue
+				}
+				mu.Lock()
+				if len(activity) == 0 {
+					refs = append(refs, FileRef{SessionID: e.SessionID, Date: e.Date})
+				}
+				for _, a := range activity {
+					if strings.ContainsAny(a.Path, "\t\n") {
+						continue // Can't be stored in a TSV line
+					}
+					refs = append(refs, FileRef{
+						Path:      a.Path,
+						SessionID: e.SessionID,
+						Date:      e.Date,
+						Ops:       a.Ops,
+						FirstTurn: a.FirstTurn,
+						LastTurn:  a.LastTurn,
+					})
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Path != refs[j].Path {
+			return refs[i].Path < refs[j].Path
+		}
+		return refs[i].Date.After(refs[j].Date)
+	})
+
+	unlock, err := safefile.Lock(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return refs, writeFileIndex(path, started, refs)
+}
+
+// RemoveFromFileIndex drops sessions from the reverse index, keeping its
+// modification time like Remove does for the cache
+func RemoveFromFileIndex(path string, ids ...string) error {
+	unlock, err := safefile.Lock(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	refs, err := ReadFileIndex(path)
+	if err != nil {
+		return err
+	}
+
+	drop := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
+	kept := refs[:0]
+	for _, r := range refs {
+		if !drop[r.SessionID] {
+			kept = append(kept, r)
+		}
+	}
+	if len(kept) == len(refs) {
+		return nil
+	}
+	return writeFileIndex(path, info.ModTime(), kept)
+}
+
+// FindFile returns the references to path, or to files under it when it
+// is a directory, most recent session first
+func FindFile(refs []FileRef, path string) []FileRef {
+	path = filepath.Clean(path)
+	prefix := strings.TrimSuffix(path, string(filepath.Separator)) + string(filepath.Separator)
+
+	var found []FileRef
+	for _, r := range refs {
+		if r.Path != "" && (r.Path == path || strings.HasPrefix(r.Path, prefix)) {
+			found = append(found, r)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].Date.After(found[j].Date)
+	})
+	return found
+}
+
+// formatOps encodes operation counts as "create=1,modify=3"
+func formatOps(ops map[adapters.FileOp]int) string {
+	var parts []string
+	for _, op := range adapters.FileOps {
+		if n := ops[op]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", op, n))
+		}
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ",")
+}
+
+func parseOps(s string) map[adapters.FileOp]int {
+	ops := make(map[adapters.FileOp]int)
+	for _, part := range strings.Split(s, ",") {
+		op, n, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		count, err := strconv.Atoi(n)
+		if err != nil {
+			continue
+		}
+		ops[adapters.FileOp(op)] = count
+	}
+	return ops
+}
@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+// activityAdapter adds file activity to mockAdapter and counts the lookups
+type activityAdapter struct {
+	mockAdapter
+	activity map[string][]adapters.FileActivity
+	calls    atomic.Int32
+	during   func(id string) // Called on each lookup, if set
+}
+
+func (a *activityAdapter) GetFileActivity(ctx context.Context, id string) ([]adapters.FileActivity, error) {
+	a.calls.Add(1)
+	if a.during != nil {
+		a.during(id)
+	}
+	return a.activity[id], nil
+}
+
+func newActivityAdapter(t *testing.T) (*activityAdapter, []Entry) {
+	dir := t.TempDir()
+	files := map[string]string{}
+	for _, id := range []string{"s1", "s2", "s3"} {
+		files[id] = filepath.Join(dir, id+".jsonl")
+		os.WriteFile(files[id], []byte("{}"), 0644)
+	}
+	past := time.Now().Add(-time.Hour)
+	for _, f := range files {
+		os.Chtimes(f, past, past)
+	}
+
+	a := &activityAdapter{
+		mockAdapter: mockAdapter{sessionFile: files},
+		activity: map[string][]adapters.FileActivity{
+			"s1": {
+				{Path: "/w/main.go", Ops: map[adapters.FileOp]int{adapters.FileRead: 1, adapters.FileModify: 2}, FirstTurn: 1, LastTurn: 4},
+				{Path: "/w/pkg/util.go", Ops: map[adapters.FileOp]int{adapters.FileCreate: 1}, FirstTurn: 3, LastTurn: 3},
+			},
+			"s2": {
+				{Path: "/w/main.go", Ops: map[adapters.FileOp]int{adapters.FileRead: 1}, FirstTurn: 0, LastTurn: 0},
+			},
+		},
+	}
+	entries := []Entry{
+		{SessionID: "s1", Date: time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC)},
+		{SessionID: "s2", Date: time.Date(2025, 1, 12, 9, 0, 0, 0, time.UTC)},
+		{SessionID: "s3", Date: time.Date(2025, 1, 14, 9, 0, 0, 0, time.UTC)},
+	}
+	return a, entries
+}
+
+func TestUpdateFileIndex(t *testing.T) {
+	a, entries := newActivityAdapter(t)
+	path := filepath.Join(t.TempDir(), FileIndexName)
+
+	refs, err := UpdateFileIndex(context.Background(), a, path, entries)
+	if err != nil {
+		t.Fatalf("UpdateFileIndex() error = %v", err)
+	}
+	// Three files plus the marker for s3, which touched none
+	if len(refs) != 4 || a.calls.Load() != 3 {
+		t.Fatalf("UpdateFileIndex() = %d refs after %d lookups, want 4 after 3", len(refs), a.calls.Load())
+	}
+
+	read, err := ReadFileIndex(path)
+	if err != nil {
+		t.Fatalf("ReadFileIndex() error = %v", err)
+	}
+	if len(read) != len(refs) {
+		t.Fatalf("ReadFileIndex() returned %d refs, want %d", len(read), len(refs))
+	}
+	for i := range refs {
+		got, want := read[i], refs[i]
+		if got.Path != want.Path || got.SessionID != want.SessionID || !got.Date.Equal(want.Date) ||
+			formatOps(got.Ops) != formatOps(want.Ops) || got.FirstTurn != want.FirstTurn || got.LastTurn != want.LastTurn {
+			t.Errorf("ref %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	// Only the session written since the index is read again
+	time.Sleep(10 * time.Millisecond)
+	os.Chtimes(a.sessionFile["s2"], time.Now(), time.Now())
+	a.activity["s2"] = append(a.activity["s2"], adapters.FileActivity{
+		Path: "/w/README.md", Ops: map[adapters.FileOp]int{adapters.FileModify: 1},
+	})
+	a.calls.Store(0)
+
+	refs, err = UpdateFileIndex(context.Background(), a, path, entries)
+	if err != nil {
+		t.Fatalf("UpdateFileIndex() error = %v", err)
+	}
+	if a.calls.Load() != 1 || len(refs) != 5 {
+		t.Errorf("second update = %d refs after %d lookups, want 5 after 1", len(refs), a.calls.Load())
+	}
+
+	// Sessions no longer cached are dropped
+	refs, _ = UpdateFileIndex(context.Background(), a, path, entries[:1])
+	if len(refs) != 2 {
+		t.Errorf("update with one session = %d refs, want 2", len(refs))
+	}
+}
+
+func TestUpdateFileIndex_SessionChangedDuringUpdate(t *testing.T) {
+	a, entries := newActivityAdapter(t)
+	path := filepath.Join(t.TempDir(), FileIndexName)
+
+	// s1 is written to while the update reads it
+	a.during = func(id string) {
+		if id == "s1" {
+			now := time.Now()
+			os.Chtimes(a.sessionFile["s1"], now, now)
+		}
+	}
+	if _, err := UpdateFileIndex(context.Background(), a, path, entries); err != nil {
+		t.Fatalf("UpdateFileIndex() error = %v", err)
+	}
+
+	a.during = nil
+	a.calls.Store(0)
+	if _, err := UpdateFileIndex(context.Background(), a, path, entries); err != nil {
+		t.Fatalf("UpdateFileIndex() error = %v", err)
+	}
+	if a.calls.Load() != 1 {
+		t.Errorf("second update made %d lookups, want s1 read again", a.calls.Load())
+	}
+}
+
+func TestFindFile(t *testing.T) {
+	a, entries := newActivityAdapter(t)
+	refs, err := UpdateFileIndex(context.Background(), a, filepath.Join(t.TempDir(), FileIndexName), entries)
+	if err != nil {
+		t.Fatalf("UpdateFileIndex() error = %v", err)
+	}
+
+	found := FindFile(refs, "/w/main.go")
+	if len(found) != 2 || found[0].SessionID != "s2" || found[1].SessionID != "s1" {
+		t.Errorf("FindFile(main.go) = %+v, want s2 then s1", found)
+	}
+	if found := FindFile(refs, "/w/pkg/"); len(found) != 1 || found[0].Path != "/w/pkg/util.go" {
+		t.Errorf("FindFile(pkg/) = %+v, want util.go", found)
+	}
+	if found := FindFile(refs, "/w/ma"); len(found) != 0 {
+		t.Errorf("FindFile(/w/ma) = %+v, want no partial name matches", found)
+	}
+}
+
+func TestRemoveFromFileIndex(t *testing.T) {
+	a, entries := newActivityAdapter(t)
+	path := filepath.Join(t.TempDir(), FileIndexName)
+	UpdateFileIndex(context.Background(), a, path, entries)
+	past := time.Now().Add(-time.Minute).Truncate(time.Second)
+	os.Chtimes(path, past, past)
+
+	if err := RemoveFromFileIndex(path, "s1"); err != nil {
+		t.Fatalf("RemoveFromFileIndex() error = %v", err)
+	}
+	refs, _ := ReadFileIndex(path)
+	for _, r := range refs {
+		if r.SessionID == "s1" {
+			t.Errorf("s1 still indexed: %+v", r)
+		}
+	}
+	if info, _ := os.Stat(path); !info.ModTime().Equal(past) {
+		t.Errorf("mtime = %v, want it kept at %v", info.ModTime(), past)
+	}
+
+	if err := RemoveFromFileIndex(filepath.Join(t.TempDir(), "missing.tsv"), "s1"); err != nil {
+		t.Errorf("RemoveFromFileIndex() on a missing index error = %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+// Package diff reconstructs what a session changed in each file by
+// replaying its edit and write tool calls. Sessions rarely see a whole
+// file, so the diffs are built from the text the calls name: old and new
+// strings for edits and full contents for writes. Regions the session
+// never saw are left out, and their line numbers are estimates.
+package diff
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+// contextLines is the number of unchanged lines kept around each change
+const contextLines = 3
+
+// LineKind marks a diff line as unchanged, removed or added
+type LineKind byte
+
+const (
+	Context LineKind = ' '
+	Removed LineKind = '-'
+	Added   LineKind = '+'
+)
+
+// Line is one line of a hunk, without its newline
+type Line struct {
+	Kind LineKind
+	Text string
+}
+
+// Hunk is a run of changes with the unchanged lines around them
+type Hunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Lines              []Line
+}
+
+// File is the net change a session made to one file
+type File struct {
+	Path      string
+	Created   bool // Written before the session read or edited it
+	Rewritten bool // Overwritten without the session knowing what was there
+	Partial   bool // Only the edited regions are known
+	Edits     int  // Edit and write calls replayed
+	FirstTurn int  // 0-based indexes into the exported messages
+	LastTurn  int
+	Hunks     []Hunk
+}
+
+// Stat counts the added and removed lines
+func (f File) Stat() (added, removed int) {
+	for _, h := range f.Hunks {
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case Added:
+				added++
+			case Removed:
+				removed++
+			}
+		}
+	}
+	return added, removed
+}
+
+// fragment is a region of the file before and after the session's edits
+type fragment struct {
+	before, after string
+}
+
+// replay tracks one file while the tool calls are applied
+type replay struct {
+	file  File
+	frags []fragment
+	whole bool // frags[0] is the entire file
+}
+
+// toolInput holds the fields of the edit and write tools. Claude spells
+// them in snake case, OpenCode in camel case.
+type toolInput struct {
+	FilePath  string `json:"file_path"`
+	FilePathC string `json:"filePath"`
+	Content   string `json:"content"`
+	editInput
+	Edits []editInput `json:"edits"`
+}
+
+type editInput struct {
+	OldString   string `json:"old_string"`
+	NewString   string `json:"new_string"`
+	ReplaceAll  bool   `json:"replace_all"`
+	OldStringC  string `json:"oldString"`
+	NewStringC  string `json:"newString"`
+	ReplaceAllC bool   `json:"replaceAll"`
+}
+
+func (in toolInput) path() string {
+	if in.FilePath != "" {
+		return in.FilePath
+	}
+	return in.FilePathC
+}
+
+func (e editInput) fields() (old, new string, all bool) {
+	if e.OldStringC != "" || e.NewStringC != "" {
+		return e.OldStringC, e.NewStringC, e.ReplaceAllC
+	}
+	return e.OldString, e.NewString, e.ReplaceAll
+}
+
+// Replay rebuilds per-file diffs from the edit, multi-edit and write calls
+// in messages. Calls whose result is an error are skipped. Files are
+// returned sorted by path; files the calls left unchanged are dropped.
+func Replay(messages []adapters.Message) []File {
+	results := make(map[string]adapters.ToolResult)
+	for _, m := range messages {
+		for _, tr := range m.ToolResults {
+			results[tr.ToolUseID] = tr
+		}
+	}
+
+	read := make(map[string]bool)
+	files := make(map[string]*replay)
+	for turn, m := range messages {
+		for _, tc := range m.ToolCalls {
+			if res, ok := results[tc.ID]; ok && !res.Success {
+				continue
+			}
+			name := strings.ToLower(tc.Name)
+			if name != "read" && name != "edit" && name != "multiedit" && name != "write" {
+				continue
+			}
+			var in toolInput
+			if json.Unmarshal([]byte(tc.Input), &in) != nil || in.path() == "" {
+				continue
+			}
+			path := in.path()
+			if name == "read" {
+				read[path] = true
+				continue
+			}
+
+			r, ok := files[path]
+			if !ok {
+				r = &replay{file: File{Path: path, FirstTurn: turn}}
+				files[path] = r
+			}
+			switch name {
+			case "edit":
+				r.edit(in.editInput.fields())
+			case "multiedit":
+				for _, e := range in.Edits {
+					r.edit(e.fields())
+				}
+			case "write":
+				r.write(in.Content, !read[path])
+			}
+			r.file.Edits++
+			r.file.LastTurn = turn
+		}
+	}
+
+	var diffs []File
+	for _, r := range files {
+		if f := r.finish(); len(f.Hunks) > 0 {
+			diffs = append(diffs, f)
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+	return diffs
+}
+
+// edit replaces old with new in the text known so far. An edit outside
+// it starts a new fragment, unless the whole file is known, in which
+// case the edit couldn't have applied.
+func (r *replay) edit(old, new string, all bool) {
+	if old == "" {
+		// Claude creates a file by editing with an empty old string
+		if len(r.frags) == 0 {
+			r.write(new, true)
+		}
+		return
+	}
+	for i := range r.frags {
+		f := &r.frags[i]
+		if strings.Contains(f.after, old) {
+			if all {
+				f.after = strings.ReplaceAll(f.after, old, new)
+			} else {
+				f.after = strings.Replace(f.after, old, new, 1)
+			}
+			return
+		}
+	}
+	if r.whole {
+		return
+	}
+	r.frags = append(r.frags, fragment{before: old, after: new})
+	r.file.Partial = true
+}
+
+// write replaces the file's content. created means the session hadn't
+// seen the file before, so it didn't exist.
+func (r *replay) write(content string, created bool) {
+	switch {
+	case r.whole:
+		r.frags[0].after = content
+	case len(r.frags) == 0 && created:
+		r.frags = []fragment{{after: content}}
+		r.file.Created = true
+	default:
+		// What the write replaced is unknown beyond earlier edits
+		r.frags = []fragment{{after: content}}
+		r.file.Rewritten = true
+		r.file.Partial = false
+	}
+	r.whole = true
+}
+
+// finish turns the fragments into hunks. Fragments of a partially known
+// file are numbered as if they followed each other.
+func (r *replay) finish() File {
+	f := r.file
+	oldLine, newLine := 0, 0
+	for _, frag := range r.frags {
+		before, after := splitLines(frag.before), splitLines(frag.after)
+		f.Hunks = append(f.Hunks, hunks(diffLines(before, after), oldLine, newLine)...)
+		oldLine += len(before)
+		newLine += len(after)
+	}
+	return f
+}
+
+// splitLines splits text into lines, ignoring a final newline
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
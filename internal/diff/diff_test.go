@@ -0,0 +1,137 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+// call builds an assistant message with a single tool call
+func call(id, name, input string) adapters.Message {
+	return adapters.Message{Role: "assistant", ToolCalls: []adapters.ToolCall{{ID: id, Name: name, Input: input}}}
+}
+
+func TestReplay_CreatedFile(t *testing.T) {
+	messages := []adapters.Message{
+		{Role: "user", Content: "Add a greeter"},
+		call("t1", "Write", `{"file_path":"/w/hello.go","content":"package main\n\nfunc hello() {}\n"}`),
+		call("t2", "Edit", `{"file_path":"/w/hello.go","old_string":"func hello() {}","new_string":"func hello() string {\n\treturn \"hi\"\n}"}`),
+	}
+
+	files := Replay(messages)
+	if len(files) != 1 {
+		t.Fatalf("Replay() returned %d files, want 1", len(files))
+	}
+	f := files[0]
+	if !f.Created || f.Partial || f.Edits != 2 || f.FirstTurn != 1 || f.LastTurn != 2 {
+		t.Errorf("file = %+v, want created by 2 calls over turns 1-2", f)
+	}
+
+	want := `--- /dev/null
++++ b/hello.go
+@@ -0,0 +1,5 @@
++package main
++
++func hello() string {
++	return "hi"
++}
+`
+	if got := Unified(f, "hello.go"); got != want {
+		t.Errorf("Unified() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestReplay_PartialEdits(t *testing.T) {
+	messages := []adapters.Message{
+		call("t1", "Edit", `{"file_path":"/w/a.go","old_string":"x := 1\ny := 2","new_string":"x := 1\ny := 3"}`),
+		call("t2", "Edit", `{"file_path":"/w/a.go","old_string":"y := 3","new_string":"y := 4"}`),
+		call("t3", "edit", `{"filePath":"/w/a.go","oldString":"return nil","newString":"return err"}`),
+		call("t4", "MultiEdit", `{"file_path":"/w/b.go","edits":[{"old_string":"a","new_string":"b"},{"old_string":"b","new_string":"c"}]}`),
+		call("t5", "Edit", `{"file_path":"/w/a.go","old_string":"missing","new_string":"never"}`),
+		{Role: "user", ToolResults: []adapters.ToolResult{{ToolUseID: "t5", Content: "String not found", Success: false}}},
+	}
+
+	files := Replay(messages)
+	if len(files) != 2 {
+		t.Fatalf("Replay() = %+v, want a.go and b.go", files)
+	}
+
+	a := files[0]
+	if !a.Partial || a.Created || a.Edits != 3 {
+		t.Errorf("a.go = %+v, want a partial file from 3 edits", a)
+	}
+	want := `--- a/a.go
++++ b/a.go
+@@ -1,2 +1,2 @@
+ x := 1
+-y := 2
++y := 4
+@@ -3,1 +3,1 @@
+-return nil
++return err
+`
+	if got := Unified(a, "a.go"); got != want {
+		t.Errorf("Unified(a.go) =\n%s\nwant\n%s", got, want)
+	}
+
+	// The second edit of the multi-edit applies to the first one's result
+	if b := files[1]; len(b.Hunks) != 1 || fmt.Sprint(b.Hunks[0].Lines) != fmt.Sprint([]Line{{Removed, "a"}, {Added, "c"}}) {
+		t.Errorf("b.go hunks = %+v, want a replaced by c", b.Hunks)
+	}
+}
+
+func TestReplay_RewrittenFile(t *testing.T) {
+	messages := []adapters.Message{
+		call("t1", "Read", `{"file_path":"/w/c.go"}`),
+		call("t2", "Write", `{"file_path":"/w/c.go","content":"package c\n"}`),
+	}
+
+	files := Replay(messages)
+	if len(files) != 1 || !files[0].Rewritten || files[0].Created {
+		t.Fatalf("Replay() = %+v, want c.go rewritten", files)
+	}
+	if added, removed := files[0].Stat(); added != 1 || removed != 0 {
+		t.Errorf("Stat() = +%d -%d, want +1 -0", added, removed)
+	}
+}
+
+func TestReplay_UnchangedFilesDropped(t *testing.T) {
+	messages := []adapters.Message{
+		call("t1", "Edit", `{"file_path":"/w/a.go","old_string":"same","new_string":"same"}`),
+		call("t2", "Bash", `{"command":"rm /w/a.go"}`),
+	}
+	if files := Replay(messages); len(files) != 0 {
+		t.Errorf("Replay() = %+v, want no files", files)
+	}
+}
+
+func TestHunks_SplitsDistantChanges(t *testing.T) {
+	var before, after []string
+	for i := 1; i <= 20; i++ {
+		before = append(before, fmt.Sprint(i))
+		after = append(after, fmt.Sprint(i))
+	}
+	after[1] = "two"
+	after[17] = "eighteen"
+
+	got := hunks(diffLines(before, after), 0, 0)
+	if len(got) != 2 {
+		t.Fatalf("hunks() returned %d hunks, want 2", len(got))
+	}
+	if h := HunkHeader(got[0]); h != "@@ -1,5 +1,5 @@" {
+		t.Errorf("first hunk = %s", h)
+	}
+	if h := HunkHeader(got[1]); h != "@@ -15,6 +15,6 @@" {
+		t.Errorf("second hunk = %s", h)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	got := diffLines(strings.Split("a b c d", " "), strings.Split("a c x d", " "))
+	want := []Line{{Context, "a"}, {Removed, "b"}, {Context, "c"}, {Added, "x"}, {Context, "d"}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("diffLines() = %v, want %v", got, want)
+	}
+}
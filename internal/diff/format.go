@@ -0,0 +1,86 @@
+package diff
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Unified formats a file's hunks as a unified diff under the given name,
+// e.g. the path relative to the session's working directory
+func Unified(f File, name string) string {
+	var sb strings.Builder
+	if f.Created {
+		sb.WriteString("--- /dev/null\n")
+	} else {
+		fmt.Fprintf(&sb, "--- a/%s\n", name)
+	}
+	fmt.Fprintf(&sb, "+++ b/%s\n", name)
+	for _, h := range f.Hunks {
+		sb.WriteString(HunkHeader(h))
+		sb.WriteString("\n")
+		for _, l := range h.Lines {
+			sb.WriteByte(byte(l.Kind))
+			sb.WriteString(l.Text)
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// HunkHeader returns the "@@ -a,b +c,d @@" line of a hunk
+func HunkHeader(h Hunk) string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+}
+
+// Name returns path relative to workDir when it lies inside it
+func Name(path, workDir string) string {
+	if workDir != "" {
+		if rel, err := filepath.Rel(workDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
+
+// Summary describes a file's change in one line, e.g.
+// "modified +3 -1, 2 edits in turns 4-9"
+func Summary(f File) string {
+	status := "modified"
+	switch {
+	case f.Created:
+		status = "created"
+	case f.Rewritten:
+		status = "rewritten, previous content unknown"
+	}
+	added, removed := f.Stat()
+	edits := "1 edit"
+	if f.Edits != 1 {
+		edits = fmt.Sprintf("%d edits", f.Edits)
+	}
+	turns := fmt.Sprintf("turn %d", f.FirstTurn)
+	if f.LastTurn != f.FirstTurn {
+		turns = fmt.Sprintf("turns %d-%d", f.FirstTurn, f.LastTurn)
+	}
+	s := fmt.Sprintf("%s +%d -%d, %s in %s", status, added, removed, edits, turns)
+	if f.Partial {
+		s += " (only edited regions known)"
+	}
+	return s
+}
+
+// Filter keeps the files matching any of the given paths, compared as
+// given, relative to workDir, or as a trailing path component
+func Filter(files []File, paths []string, workDir string) []File {
+	var kept []File
+	for _, f := range files {
+		for _, p := range paths {
+			p = filepath.Clean(p)
+			if f.Path == p || Name(f.Path, workDir) == p || strings.HasSuffix(f.Path, string(filepath.Separator)+p) {
+				kept = append(kept, f)
+				break
+			}
+		}
+	}
+	return kept
+}
@@ -0,0 +1,138 @@
+package diff
+
+// maxCells bounds the table diffLines fills; larger inputs are shown as
+// removing every old line and adding every new one
+const maxCells = 1 << 22
+
+// diffLines returns an edit script turning a into b, using the longest
+// common subsequence of the lines the two don't share at either end
+func diffLines(a, b []string) []Line {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var lines []Line
+	for _, l := range a[:prefix] {
+		lines = append(lines, Line{Context, l})
+	}
+	lines = append(lines, lcs(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, l := range a[len(a)-suffix:] {
+		lines = append(lines, Line{Context, l})
+	}
+	return lines
+}
+
+func lcs(a, b []string) []Line {
+	var lines []Line
+	if len(a)*len(b) > maxCells {
+		for _, l := range a {
+			lines = append(lines, Line{Removed, l})
+		}
+		for _, l := range b {
+			lines = append(lines, Line{Added, l})
+		}
+		return lines
+	}
+
+	// n[i][j] is the LCS length of a[i:] and b[j:]
+	n := make([][]int, len(a)+1)
+	for i := range n {
+		n[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				n[i][j] = n[i+1][j+1] + 1
+			} else {
+				n[i][j] = max(n[i+1][j], n[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, Line{Context, a[i]})
+			i++
+			j++
+		case n[i+1][j] >= n[i][j+1]:
+			lines = append(lines, Line{Removed, a[i]})
+			i++
+		default:
+			lines = append(lines, Line{Added, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, Line{Removed, a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, Line{Added, b[j]})
+	}
+	return lines
+}
+
+// hunks groups an edit script into hunks with up to contextLines unchanged
+// lines on each side. oldLine and newLine are the lines preceding the
+// script in the file.
+func hunks(lines []Line, oldLine, newLine int) []Hunk {
+	var result []Hunk
+	for start := 0; start < len(lines); {
+		// Find the next change
+		first := start
+		for first < len(lines) && lines[first].Kind == Context {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+
+		// Extend through changes separated by short unchanged runs
+		end := first
+		for i := first; i < len(lines); i++ {
+			if lines[i].Kind != Context {
+				end = i + 1
+			} else if i-end >= 2*contextLines {
+				break
+			}
+		}
+
+		from := max(first-contextLines, start)
+		to := min(end+contextLines, len(lines))
+		h := Hunk{Lines: lines[from:to]}
+		for _, l := range lines[start:from] {
+			oldLine, newLine = advance(l, oldLine, newLine)
+		}
+		h.OldStart, h.NewStart = oldLine, newLine
+		for _, l := range h.Lines {
+			oldLine, newLine = advance(l, oldLine, newLine)
+		}
+		h.OldLines, h.NewLines = oldLine-h.OldStart, newLine-h.NewStart
+		// Unified diffs number an empty side by the line before it
+		if h.OldLines > 0 {
+			h.OldStart++
+		}
+		if h.NewLines > 0 {
+			h.NewStart++
+		}
+		result = append(result, h)
+		start = to
+	}
+	return result
+}
+
+func advance(l Line, oldLine, newLine int) (int, int) {
+	if l.Kind != Added {
+		oldLine++
+	}
+	if l.Kind != Removed {
+		newLine++
+	}
+	return oldLine, newLine
+}
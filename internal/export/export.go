@@ -10,11 +10,18 @@ import (
 	"time"
 
 	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+	"github.com/Julian194/claude-sessions-tui/internal/diff"
 )
 
 //go:embed template.html
 var htmlTemplate string
 
+// Options controls what exports include
+type Options struct {
+	Thinking bool        // Include the model's reasoning as collapsed sections
+	Diffs    []diff.File // File changes to list above the HTML transcript
+}
+
 // TemplateData holds data for the HTML template
 type TemplateData struct {
 	Title        string
@@ -24,19 +31,48 @@ type TemplateData struct {
 	SessionID    string
 	MsgCount     int
 	ToolCount    int
+	ThinkCount   int
+	Changes      []fileChange
 	MessagesJSON template.JS
 }
 
+// fileChange is one file of the changes section
+type fileChange struct {
+	Name    string
+	Summary string
+	Lines   []diffLine
+}
+
+// diffLine is a line of a rendered diff; Class is hunk, add, del or ctx
+type diffLine struct {
+	Class string
+	Text  string
+}
+
+// maxResultLen caps how much of a tool result the HTML export embeds
+const maxResultLen = 4000
+
 // jsMessage represents a message for JavaScript rendering
 type jsMessage struct {
-	Type  string   `json:"type"`
-	Text  string   `json:"text"`
-	Tools []string `json:"tools,omitempty"`
-	Ts    string   `json:"ts,omitempty"`
+	Type   string    `json:"type"`
+	Blocks []jsBlock `json:"blocks"`
+	Ts     string    `json:"ts,omitempty"`
+}
+
+// jsBlock is one content block of a message: text, thinking, tool (with
+// its result attached), result (one whose call wasn't found) or image
+type jsBlock struct {
+	Kind   string `json:"kind"`
+	Text   string `json:"text,omitempty"`   // For tools, "Name: detail"
+	Result string `json:"result,omitempty"` // Tool output
+	Error  bool   `json:"error,omitempty"`  // Tool or result failed
+	Src    string `json:"src,omitempty"`    // Image URL or data URI
+
+	id string // Tool use ID, to pair calls with results
 }
 
 // ToHTML converts messages to HTML format with full styling
-func ToHTML(messages []adapters.Message, info *adapters.SessionInfo, models []string) string {
+func ToHTML(messages []adapters.Message, info *adapters.SessionInfo, models []string, opts Options) string {
 	// Prepare template data
 	data := TemplateData{
 		Title:     "Session Export",
@@ -62,16 +98,36 @@ func ToHTML(messages []adapters.Message, info *adapters.SessionInfo, models []st
 		data.Models = strings.Join(models, ", ")
 	}
 
+	workDir := ""
+	if info != nil {
+		workDir = info.WorkDir
+	}
+	data.Changes = fileChanges(opts.Diffs, workDir)
+
 	// Convert messages to JS format
 	var jsMessages []jsMessage
 	for _, msg := range messages {
-		jsMsg := convertMessage(msg)
-		if jsMsg != nil {
+		if jsMsg := convertMessage(msg, opts); jsMsg != nil {
 			jsMessages = append(jsMessages, *jsMsg)
-			if msg.Role == "user" {
-				data.MsgCount++
+		}
+	}
+	jsMessages = attachResults(jsMessages)
+
+	for _, m := range jsMessages {
+		if m.Type == "user" {
+			data.MsgCount++
+		}
+		thinking := false
+		for _, b := range m.Blocks {
+			switch b.Kind {
+			case "tool":
+				data.ToolCount++
+			case "thinking":
+				thinking = true
 			}
-			data.ToolCount += len(msg.ToolCalls)
+		}
+		if thinking {
+			data.ThinkCount++
 		}
 	}
 
@@ -93,41 +149,136 @@ func ToHTML(messages []adapters.Message, info *adapters.SessionInfo, models []st
 	return buf.String()
 }
 
-func convertMessage(msg adapters.Message) *jsMessage {
+// fileChanges renders diffs line by line so the template can color them
+func fileChanges(files []diff.File, workDir string) []fileChange {
+	var changes []fileChange
+	for _, f := range files {
+		c := fileChange{Name: diff.Name(f.Path, workDir), Summary: diff.Summary(f)}
+		for _, h := range f.Hunks {
+			c.Lines = append(c.Lines, diffLine{Class: "hunk", Text: diff.HunkHeader(h)})
+			for _, l := range h.Lines {
+				class := "ctx"
+				switch l.Kind {
+				case diff.Added:
+					class = "add"
+				case diff.Removed:
+					class = "del"
+				}
+				c.Lines = append(c.Lines, diffLine{Class: class, Text: string(l.Kind) + l.Text})
+			}
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+func convertMessage(msg adapters.Message, opts Options) *jsMessage {
 	ts := ""
 	if msg.Timestamp > 0 {
 		ts = time.Unix(msg.Timestamp, 0).Format(time.RFC3339)
 	}
+	if msg.Role != "user" && msg.Role != "assistant" {
+		return nil
+	}
+	// Skip system messages injected as user turns
+	if msg.Role == "user" && (strings.HasPrefix(msg.Content, "<") || strings.HasPrefix(msg.Content, "Caveat:")) {
+		return nil
+	}
 
-	if msg.Role == "user" {
-		// Skip empty or system messages
-		if msg.Content == "" || strings.HasPrefix(msg.Content, "<") || strings.HasPrefix(msg.Content, "Caveat:") {
-			return nil
+	var blocks []jsBlock
+	for _, b := range adapters.MessageBlocks(msg) {
+		switch b.Kind {
+		case adapters.BlockText:
+			if b.Text != "" {
+				blocks = append(blocks, jsBlock{Kind: "text", Text: b.Text})
+			}
+		case adapters.BlockThinking:
+			if opts.Thinking && b.Text != "" {
+				blocks = append(blocks, jsBlock{Kind: "thinking", Text: b.Text})
+			}
+		case adapters.BlockToolUse:
+			tc := adapters.ToolCall{ID: b.ToolUseID, Name: b.Name, Input: b.Input}
+			blocks = append(blocks, jsBlock{Kind: "tool", Text: formatToolCall(tc), id: b.ToolUseID})
+		case adapters.BlockToolResult:
+			blocks = append(blocks, jsBlock{Kind: "result", Result: truncateResult(b.Text), Error: b.IsError, id: b.ToolUseID})
+		case adapters.BlockImage:
+			if src := imageSrc(b); src != "" {
+				blocks = append(blocks, jsBlock{Kind: "image", Src: src})
+			}
 		}
-		return &jsMessage{
-			Type: "user",
-			Text: msg.Content,
-			Ts:   ts,
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	return &jsMessage{
+		Type:   msg.Role,
+		Blocks: blocks,
+		Ts:     ts,
+	}
+}
+
+// attachResults moves each tool result onto the call it answers, so the
+// export shows them together, and drops messages left without content
+// (Claude records results as separate user turns)
+func attachResults(messages []jsMessage) []jsMessage {
+	calls := make(map[string]*jsBlock)
+	for i := range messages {
+		for j := range messages[i].Blocks {
+			if b := &messages[i].Blocks[j]; b.Kind == "tool" && b.id != "" {
+				calls[b.id] = b
+			}
 		}
 	}
 
-	if msg.Role == "assistant" {
-		var tools []string
-		for _, tc := range msg.ToolCalls {
-			tools = append(tools, formatToolCall(tc))
+	attached := make(map[*jsBlock]bool)
+	for i := range messages {
+		for j := range messages[i].Blocks {
+			b := &messages[i].Blocks[j]
+			if call, ok := calls[b.id]; ok && b.Kind == "result" {
+				call.Result, call.Error = b.Result, b.Error
+				attached[b] = true
+			}
 		}
+	}
 
-		if msg.Content != "" || len(tools) > 0 {
-			return &jsMessage{
-				Type:  "assistant",
-				Text:  msg.Content,
-				Tools: tools,
-				Ts:    ts,
+	var kept []jsMessage
+	for i := range messages {
+		var blocks []jsBlock
+		for j := range messages[i].Blocks {
+			if !attached[&messages[i].Blocks[j]] {
+				blocks = append(blocks, messages[i].Blocks[j])
 			}
 		}
+		if len(blocks) > 0 {
+			messages[i].Blocks = blocks
+			kept = append(kept, messages[i])
+		}
+	}
+	return kept
+}
+
+// truncateResult shortens long tool output, keeping its start
+func truncateResult(s string) string {
+	if len(s) <= maxResultLen {
+		return s
 	}
+	return fmt.Sprintf("%s\n… (%d more characters)", strings.ToValidUTF8(s[:maxResultLen], ""), len(s)-maxResultLen)
+}
 
-	return nil
+// imageSrc returns an image block as something an <img> can load
+func imageSrc(b adapters.ContentBlock) string {
+	if b.URL != "" {
+		return b.URL
+	}
+	if b.Data == "" {
+		return ""
+	}
+	mediaType := b.MediaType
+	if mediaType == "" {
+		mediaType = "image/png"
+	}
+	return "data:" + mediaType + ";base64," + b.Data
 }
 
 func formatToolCall(tc adapters.ToolCall) string {
@@ -165,7 +316,7 @@ func formatToolCall(tc adapters.ToolCall) string {
 }
 
 // ToMarkdown converts messages to Markdown format
-func ToMarkdown(messages []adapters.Message, info *adapters.SessionInfo, models []string) string {
+func ToMarkdown(messages []adapters.Message, info *adapters.SessionInfo, models []string, opts Options) string {
 	var sb strings.Builder
 
 	if info != nil {
@@ -184,14 +335,14 @@ func ToMarkdown(messages []adapters.Message, info *adapters.SessionInfo, models
 	}
 
 	for _, msg := range messages {
-		sb.WriteString(messageToMarkdown(msg))
+		sb.WriteString(messageToMarkdown(msg, opts))
 		sb.WriteString("\n")
 	}
 
 	return sb.String()
 }
 
-func messageToMarkdown(msg adapters.Message) string {
+func messageToMarkdown(msg adapters.Message, opts Options) string {
 	var sb strings.Builder
 
 	if msg.Role == "user" {
@@ -200,26 +351,45 @@ func messageToMarkdown(msg adapters.Message) string {
 		sb.WriteString("## 🤖 Assistant\n\n")
 	}
 
-	if msg.Content != "" {
-		sb.WriteString(msg.Content)
-		sb.WriteString("\n\n")
-	}
-
-	for _, tc := range msg.ToolCalls {
-		sb.WriteString(fmt.Sprintf("**Tool: %s**\n", tc.Name))
-		if tc.Input != "" {
-			sb.WriteString("```json\n")
-			sb.WriteString(tc.Input)
+	for _, b := range adapters.MessageBlocks(msg) {
+		switch b.Kind {
+		case adapters.BlockText:
+			if b.Text != "" {
+				sb.WriteString(b.Text)
+				sb.WriteString("\n\n")
+			}
+		case adapters.BlockThinking:
+			// <details> renders collapsed on GitHub and in most Markdown viewers
+			if opts.Thinking && b.Text != "" {
+				sb.WriteString("<details>\n<summary>💭 Thinking</summary>\n\n")
+				sb.WriteString(b.Text)
+				sb.WriteString("\n\n</details>\n\n")
+			}
+		case adapters.BlockToolUse:
+			sb.WriteString(fmt.Sprintf("**Tool: %s**\n", b.Name))
+			if b.Input != "" {
+				sb.WriteString("```json\n")
+				sb.WriteString(b.Input)
+				sb.WriteString("\n```\n\n")
+			}
+		case adapters.BlockToolResult:
+			if b.IsError {
+				sb.WriteString("**Error:**\n```\n")
+			} else {
+				sb.WriteString("**Result:**\n```\n")
+			}
+			sb.WriteString(b.Text)
 			sb.WriteString("\n```\n\n")
+		case adapters.BlockImage:
+			// Inline data would swamp the document; link only real URLs
+			if b.URL != "" {
+				sb.WriteString(fmt.Sprintf("![image](%s)\n\n", b.URL))
+			} else {
+				sb.WriteString(fmt.Sprintf("_[image: %s]_\n\n", b.MediaType))
+			}
 		}
 	}
 
-	for _, tr := range msg.ToolResults {
-		sb.WriteString("**Result:**\n```\n")
-		sb.WriteString(tr.Content)
-		sb.WriteString("\n```\n\n")
-	}
-
 	return sb.String()
 }
 
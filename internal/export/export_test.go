@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+	"github.com/Julian194/claude-sessions-tui/internal/diff"
 )
 
 func sampleMessages() []adapters.Message {
@@ -44,7 +45,7 @@ func TestToHTML_Basic(t *testing.T) {
 	messages := sampleMessages()
 	info := sampleInfo()
 
-	html := ToHTML(messages, info, nil)
+	html := ToHTML(messages, info, nil, Options{})
 
 	if !strings.Contains(html, "<!DOCTYPE html>") {
 		t.Error("HTML should start with DOCTYPE")
@@ -61,7 +62,7 @@ func TestToHTML_ContainsSessionInfo(t *testing.T) {
 	messages := sampleMessages()
 	info := sampleInfo()
 
-	html := ToHTML(messages, info, nil)
+	html := ToHTML(messages, info, nil, Options{})
 
 	if !strings.Contains(html, "my-project") {
 		t.Error("HTML should contain project name")
@@ -78,7 +79,7 @@ func TestToHTML_ContainsMessages(t *testing.T) {
 	messages := sampleMessages()
 	info := sampleInfo()
 
-	html := ToHTML(messages, info, nil)
+	html := ToHTML(messages, info, nil, Options{})
 
 	if !strings.Contains(html, "Help me with authentication") {
 		t.Error("HTML should contain user message")
@@ -91,7 +92,7 @@ func TestToHTML_ContainsMessages(t *testing.T) {
 func TestToHTML_ContainsToolCalls(t *testing.T) {
 	messages := sampleMessages()
 
-	html := ToHTML(messages, nil, nil)
+	html := ToHTML(messages, nil, nil, Options{})
 
 	if !strings.Contains(html, "Read") {
 		t.Error("HTML should contain tool name")
@@ -104,7 +105,7 @@ func TestToHTML_ContainsToolCalls(t *testing.T) {
 func TestToHTML_NilInfo(t *testing.T) {
 	messages := sampleMessages()
 
-	html := ToHTML(messages, nil, nil)
+	html := ToHTML(messages, nil, nil, Options{})
 
 	if html == "" {
 		t.Error("ToHTML should return non-empty string even with nil info")
@@ -118,7 +119,7 @@ func TestToMarkdown_Basic(t *testing.T) {
 	messages := sampleMessages()
 	info := sampleInfo()
 
-	md := ToMarkdown(messages, info, nil)
+	md := ToMarkdown(messages, info, nil, Options{})
 
 	if !strings.Contains(md, "# my-project") {
 		t.Error("Markdown should contain project header")
@@ -131,7 +132,7 @@ func TestToMarkdown_Basic(t *testing.T) {
 func TestToMarkdown_ContainsMessages(t *testing.T) {
 	messages := sampleMessages()
 
-	md := ToMarkdown(messages, nil, nil)
+	md := ToMarkdown(messages, nil, nil, Options{})
 
 	if !strings.Contains(md, "## 👤 User") {
 		t.Error("Markdown should contain user header")
@@ -147,7 +148,7 @@ func TestToMarkdown_ContainsMessages(t *testing.T) {
 func TestToMarkdown_ContainsToolCalls(t *testing.T) {
 	messages := sampleMessages()
 
-	md := ToMarkdown(messages, nil, nil)
+	md := ToMarkdown(messages, nil, nil, Options{})
 
 	if !strings.Contains(md, "**Tool: Read**") {
 		t.Error("Markdown should contain tool name")
@@ -160,7 +161,7 @@ func TestToMarkdown_ContainsToolCalls(t *testing.T) {
 func TestToMarkdown_NilInfo(t *testing.T) {
 	messages := sampleMessages()
 
-	md := ToMarkdown(messages, nil, nil)
+	md := ToMarkdown(messages, nil, nil, Options{})
 
 	if md == "" {
 		t.Error("ToMarkdown should return non-empty string even with nil info")
@@ -208,7 +209,7 @@ func TestConvertMessage_SkipsSystemMessages(t *testing.T) {
 		Content: "<system>internal message</system>",
 	}
 
-	result := convertMessage(msg)
+	result := convertMessage(msg, Options{})
 	if result != nil {
 		t.Error("convertMessage should skip messages starting with <")
 	}
@@ -220,7 +221,7 @@ func TestConvertMessage_SkipsCaveat(t *testing.T) {
 		Content: "Caveat: This is a caveat message",
 	}
 
-	result := convertMessage(msg)
+	result := convertMessage(msg, Options{})
 	if result != nil {
 		t.Error("convertMessage should skip caveat messages")
 	}
@@ -263,3 +264,123 @@ func TestFormatToolCall(t *testing.T) {
 		})
 	}
 }
+
+func TestThinking_OnlyWhenEnabled(t *testing.T) {
+	messages := []adapters.Message{
+		{Role: "user", Content: "Why?"},
+		{Role: "assistant", Thinking: "Because of the cache."},
+		{Role: "assistant", Content: "It's the cache.", Thinking: "Double-check the TTL."},
+	}
+
+	html := ToHTML(messages, nil, nil, Options{})
+	if strings.Contains(html, "Because of the cache") || strings.Contains(html, "1 thinking") {
+		t.Error("ToHTML should leave thinking out by default")
+	}
+	html = ToHTML(messages, nil, nil, Options{Thinking: true})
+	if !strings.Contains(html, "Because of the cache") || !strings.Contains(html, "2 thinking") {
+		t.Error("ToHTML should include thinking when enabled")
+	}
+
+	md := ToMarkdown(messages, nil, nil, Options{})
+	if strings.Contains(md, "Double-check the TTL") {
+		t.Error("ToMarkdown should leave thinking out by default")
+	}
+	md = ToMarkdown(messages, nil, nil, Options{Thinking: true})
+	if !strings.Contains(md, "<details>\n<summary>💭 Thinking</summary>\n\nDouble-check the TTL.\n\n</details>") {
+		t.Errorf("ToMarkdown should include thinking as a collapsed section, got:\n%s", md)
+	}
+}
+
+func TestConvertMessage_ThinkingOnly(t *testing.T) {
+	msg := adapters.Message{Role: "assistant", Thinking: "Planning"}
+
+	if convertMessage(msg, Options{}) != nil {
+		t.Error("convertMessage should skip a thinking-only message when thinking is off")
+	}
+	if got := convertMessage(msg, Options{Thinking: true}); got == nil || len(got.Blocks) != 1 || got.Blocks[0].Text != "Planning" {
+		t.Errorf("convertMessage() = %+v, want the thinking", got)
+	}
+}
+
+func blockMessages() []adapters.Message {
+	return []adapters.Message{
+		{Role: "user", Content: "Check the logs", Blocks: []adapters.ContentBlock{
+			{Kind: adapters.BlockText, Text: "Check the logs"},
+			{Kind: adapters.BlockImage, MediaType: "image/png", Data: "iVBORw0KGgo="},
+		}},
+		{Role: "assistant", Blocks: []adapters.ContentBlock{
+			{Kind: adapters.BlockText, Text: "First I'll look."},
+			{Kind: adapters.BlockToolUse, ToolUseID: "t1", Name: "Bash", Input: `{"command":"tail app.log"}`},
+			{Kind: adapters.BlockText, Text: "Then I'll fix it."},
+		}},
+		{Role: "user", Blocks: []adapters.ContentBlock{
+			{Kind: adapters.BlockToolResult, ToolUseID: "t1", Text: "permission denied", IsError: true},
+		}},
+	}
+}
+
+func TestToHTML_BlocksInOrder(t *testing.T) {
+	html := ToHTML(blockMessages(), nil, nil, Options{})
+
+	look := strings.Index(html, "First I'll look.")
+	tool := strings.Index(html, "tail app.log")
+	fix := strings.Index(html, "Then I'll fix it.")
+	if look < 0 || tool < look || fix < tool {
+		t.Error("HTML should keep text and tool use in their original order")
+	}
+	if !strings.Contains(html, "data:image/png;base64,iVBORw0KGgo=") {
+		t.Error("HTML should embed images")
+	}
+}
+
+func TestToHTML_Diffs(t *testing.T) {
+	messages := append(sampleMessages(), adapters.Message{
+		Role:      "assistant",
+		ToolCalls: []adapters.ToolCall{{ID: "tool-2", Name: "Edit", Input: `{"file_path":"/home/user/project/src/auth.ts","old_string":"if (a < b)","new_string":"if (a <= b)"}`}},
+	})
+
+	if html := ToHTML(messages, sampleInfo(), nil, Options{}); strings.Contains(html, "changed</span>") {
+		t.Error("HTML should leave out the changes section without diffs")
+	}
+
+	html := ToHTML(messages, sampleInfo(), nil, Options{Diffs: diff.Replay(messages)})
+	if !strings.Contains(html, "±</span> 1 changed") || !strings.Contains(html, `<span class="diff-name">src/auth.ts</span>`) {
+		t.Error("HTML should list changed files relative to the working directory")
+	}
+	if !strings.Contains(html, `<span class="del">-if (a &lt; b)</span>`) || !strings.Contains(html, `<span class="add">&#43;if (a &lt;= b)</span>`) {
+		t.Error("HTML should render escaped diff lines")
+	}
+}
+
+func TestAttachResults(t *testing.T) {
+	var messages []jsMessage
+	for _, m := range blockMessages() {
+		if js := convertMessage(m, Options{}); js != nil {
+			messages = append(messages, *js)
+		}
+	}
+	messages = attachResults(messages)
+
+	// The result-only user turn is folded into the call it answers
+	if len(messages) != 2 {
+		t.Fatalf("attachResults() left %d messages, want 2", len(messages))
+	}
+	call := messages[1].Blocks[1]
+	if call.Kind != "tool" || call.Result != "permission denied" || !call.Error {
+		t.Errorf("tool block = %+v, want the failed result attached", call)
+	}
+}
+
+func TestToMarkdown_Blocks(t *testing.T) {
+	md := ToMarkdown(blockMessages(), nil, nil, Options{})
+
+	if !strings.Contains(md, "_[image: image/png]_") {
+		t.Error("Markdown should mention inline images without embedding them")
+	}
+	if !strings.Contains(md, "**Error:**\n```\npermission denied") {
+		t.Error("Markdown should mark failed tool results")
+	}
+	if strings.Index(md, "**Tool: Bash**") > strings.Index(md, "Then I'll fix it.") {
+		t.Error("Markdown should keep text and tool use in their original order")
+	}
+}
@@ -1,6 +1,7 @@
 package preview
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -10,7 +11,7 @@ import (
 )
 
 // Format generates the preview pane content for a session
-func Format(adapter adapters.Adapter, id string) (string, error) {
+func Format(ctx context.Context, adapter adapters.Adapter, id string) (string, error) {
 	info, err := adapter.GetSessionInfo(id)
 	if err != nil {
 		return "", err
@@ -28,7 +29,7 @@ func Format(adapter adapters.Adapter, id string) (string, error) {
 	sb.WriteString("\n")
 
 	// Summaries (topics)
-	summaries, _ := adapter.GetSummaries(id)
+	summaries, _ := adapters.GetSummaries(adapter, id)
 	if len(summaries) > 0 {
 		sb.WriteString("━━━ Topics ━━━\n")
 		for _, s := range summaries {
@@ -38,7 +39,7 @@ func Format(adapter adapters.Adapter, id string) (string, error) {
 	}
 
 	// Slash commands
-	cmds, _ := adapter.GetSlashCommands(id)
+	cmds, _ := adapters.GetSlashCommands(adapter, id)
 	if len(cmds) > 0 {
 		sb.WriteString("━━━ Slash Commands ━━━\n")
 		for _, cmd := range cmds {
@@ -47,33 +48,26 @@ func Format(adapter adapters.Adapter, id string) (string, error) {
 		sb.WriteString("\n")
 	}
 
-	// Files touched (relative to cwd)
-	files, _ := adapter.GetFilesTouched(id)
-	if len(files) > 0 {
-		sb.WriteString("━━━ Files ━━━\n")
-		// Limit to 10 files
-		shown := files
-		if len(shown) > 10 {
-			shown = shown[:10]
-		}
-		for _, f := range shown {
-			// Make relative to workdir if possible
-			rel := f
-			if info.WorkDir != "" {
-				if r, err := filepath.Rel(info.WorkDir, f); err == nil && !strings.HasPrefix(r, "..") {
-					rel = r
-				}
+	// Files grouped by what the session did to them
+	activity, _ := adapters.GetFileActivity(ctx, adapter, id)
+	sb.WriteString(Files(activity, info.WorkDir))
+
+	// Forks inside the file, e.g. after rewinding to an earlier message
+	forks, _ := adapters.GetForks(adapter, id)
+	if len(forks) > 0 {
+		sb.WriteString(fmt.Sprintf("━━━ Forks (%d) ━━━\n", len(forks)))
+		for _, f := range forks {
+			if f.Active {
+				sb.WriteString(fmt.Sprintf("▶ %d messages (shown)\n", f.Messages))
+			} else {
+				sb.WriteString(fmt.Sprintf("• %d messages, diverges at #%d: %s\n", f.Messages, f.Shared, f.First))
 			}
-			sb.WriteString(fmt.Sprintf("• %s\n", rel))
-		}
-		if len(files) > 10 {
-			sb.WriteString(fmt.Sprintf("  ... and %d more\n", len(files)-10))
 		}
 		sb.WriteString("\n")
 	}
 
 	// Stats
-	s, err := adapter.GetStats(id)
+	s, err := adapters.GetStats(ctx, adapter, id)
 	if err == nil {
 		sb.WriteString(stats.Format(s))
 		sb.WriteString("\n")
@@ -81,7 +75,7 @@ func Format(adapter adapters.Adapter, id string) (string, error) {
 
 	// First message (fallback if no summaries)
 	if len(summaries) == 0 {
-		msg, _ := adapter.GetFirstMessage(id)
+		msg, _ := adapters.GetFirstMessage(ctx, adapter, id)
 		if msg != "" {
 			sb.WriteString("━━━ First Message ━━━\n")
 			sb.WriteString(msg)
@@ -91,3 +85,68 @@ func Format(adapter adapters.Adapter, id string) (string, error) {
 
 	return sb.String(), nil
 }
+
+// maxFilesPerGroup limits how many files each operation lists
+const maxFilesPerGroup = 10
+
+var opHeadings = map[adapters.FileOp]string{
+	adapters.FileCreate: "Created",
+	adapters.FileModify: "Modified",
+	adapters.FileDelete: "Deleted",
+	adapters.FileRead:   "Read",
+}
+
+// Files formats file activity grouped by operation, with paths relative to
+// workDir where possible. Files appear once, under the first operation of
+// adapters.FileOps the session performed on them.
+func Files(activity []adapters.FileActivity, workDir string) string {
+	if len(activity) == 0 {
+		return ""
+	}
+	groups := make(map[adapters.FileOp][]adapters.FileActivity)
+	for _, f := range activity {
+		groups[f.Op()] = append(groups[f.Op()], f)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("━━━ Files (%d) ━━━\n", len(activity)))
+	for _, op := range adapters.FileOps {
+		files := groups[op]
+		if len(files) == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("%s (%d)\n", opHeadings[op], len(files)))
+		shown := files
+		if len(shown) > maxFilesPerGroup {
+			shown = shown[:maxFilesPerGroup]
+		}
+		for _, f := range shown {
+			// Make relative to workdir if possible
+			rel := f.Path
+			if workDir != "" {
+				if r, err := filepath.Rel(workDir, f.Path); err == nil && !strings.HasPrefix(r, "..") {
+					rel = r
+				}
+			}
+			sb.WriteString(fmt.Sprintf("• %s%s\n", rel, opCounts(f)))
+		}
+		if len(files) > maxFilesPerGroup {
+			sb.WriteString(fmt.Sprintf("  ... and %d more\n", len(files)-maxFilesPerGroup))
+		}
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// opCounts lists how often each operation happened when a file saw more
+// than one tool call, e.g. " (modify ×3, read)"
+func opCounts(f adapters.FileActivity) string {
+	total := 0
+	for _, n := range f.Ops {
+		total += n
+	}
+	if total < 2 {
+		return ""
+	}
+	return " (" + adapters.FormatOps(f.Ops) + ")"
+}
@@ -0,0 +1,50 @@
+package preview
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+func TestFiles(t *testing.T) {
+	var r adapters.FileRecorder
+	r.Record("/w/new.go", adapters.FileCreate, 1)
+	r.Record("/w/new.go", adapters.FileModify, 3)
+	r.Record("/w/main.go", adapters.FileRead, 0)
+	r.Record("/w/main.go", adapters.FileModify, 2)
+	r.Record("/w/main.go", adapters.FileModify, 4)
+	r.Record("/etc/hosts", adapters.FileRead, 0)
+
+	got := Files(r.Activity(), "/w")
+	want := `━━━ Files (3) ━━━
+Created (1)
+• new.go (create, modify)
+Modified (1)
+• main.go (modify ×2, read)
+Read (1)
+• /etc/hosts
+
+`
+	if got != want {
+		t.Errorf("Files() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFiles_LimitsEachGroup(t *testing.T) {
+	var r adapters.FileRecorder
+	for i := 0; i < maxFilesPerGroup+3; i++ {
+		r.Record("/w/f"+strings.Repeat("x", i), adapters.FileRead, i)
+	}
+
+	got := Files(r.Activity(), "/w")
+	if !strings.Contains(got, "Read (13)\n") || !strings.Contains(got, "... and 3 more\n") {
+		t.Errorf("Files() = %q, want 10 reads shown and 3 more", got)
+	}
+}
+
+func TestFiles_Empty(t *testing.T) {
+	if got := Files(nil, "/w"); got != "" {
+		t.Errorf("Files(nil) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,207 @@
+// Package pricing estimates what a session cost from its token usage.
+// Prices are per million tokens and keyed by model ID; each assistant turn
+// is priced with the model that produced it. Users can override or add
+// models in a JSON file:
+//
+//	{
+//	  "claude-opus-4-5": {"input": 5, "output": 25, "cache_read": 0.5, "cache_write": 6.25},
+//	  "default": {"input": 3, "output": 15}
+//	}
+//
+// "default" prices models the table doesn't know.
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Price is what a model charges per million tokens, in USD
+type Price struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheRead  float64 `json:"cache_read"`
+	CacheWrite float64 `json:"cache_write"`
+}
+
+// Cost returns the cost of the given token counts
+func (p Price) Cost(input, output, cacheRead, cacheWrite int) float64 {
+	cost := float64(input) * p.Input
+	cost += float64(output) * p.Output
+	cost += float64(cacheRead) * p.CacheRead
+	cost += float64(cacheWrite) * p.CacheWrite
+	return cost / 1_000_000
+}
+
+// DefaultKey prices models that match no other entry
+const DefaultKey = "default"
+
+// defaults are list prices. Keys match any model ID that contains them
+// as whole words, so dated and provider-prefixed IDs
+// (claude-sonnet-4-20250514, anthropic/claude-sonnet-4) resolve too; the
+// longest match wins.
+var defaults = map[string]Price{
+	DefaultKey: {Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+
+	"claude-opus-4-5":   {Input: 5, Output: 25, CacheRead: 0.50, CacheWrite: 6.25},
+	"claude-opus-4":     {Input: 15, Output: 75, CacheRead: 1.50, CacheWrite: 18.75},
+	"claude-3-opus":     {Input: 15, Output: 75, CacheRead: 1.50, CacheWrite: 18.75},
+	"claude-sonnet-4":   {Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+	"claude-3-7-sonnet": {Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+	"claude-3-5-sonnet": {Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+	"claude-haiku-4-5":  {Input: 1, Output: 5, CacheRead: 0.10, CacheWrite: 1.25},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4, CacheRead: 0.08, CacheWrite: 1},
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25, CacheRead: 0.03, CacheWrite: 0.30},
+
+	"gpt-5":       {Input: 1.25, Output: 10, CacheRead: 0.125},
+	"gpt-5-mini":  {Input: 0.25, Output: 2, CacheRead: 0.025},
+	"gpt-5-nano":  {Input: 0.05, Output: 0.40, CacheRead: 0.005},
+	"gpt-4.1":     {Input: 2, Output: 8, CacheRead: 0.50},
+	"gpt-4o":      {Input: 2.50, Output: 10, CacheRead: 1.25},
+	"gpt-4o-mini": {Input: 0.15, Output: 0.60, CacheRead: 0.075},
+	"o3":          {Input: 2, Output: 8, CacheRead: 0.50},
+	"o3-mini":     {Input: 1.10, Output: 4.40, CacheRead: 0.55},
+	"o4-mini":     {Input: 1.10, Output: 4.40, CacheRead: 0.275},
+
+	"gemini-2.5-pro":        {Input: 1.25, Output: 10, CacheRead: 0.31},
+	"gemini-2.5-flash":      {Input: 0.30, Output: 2.50, CacheRead: 0.075},
+	"gemini-2.5-flash-lite": {Input: 0.10, Output: 0.40, CacheRead: 0.025},
+}
+
+// Table maps model IDs to prices
+type Table struct {
+	prices map[string]Price
+}
+
+// Default returns the built-in price table
+func Default() *Table {
+	t := &Table{prices: make(map[string]Price, len(defaults))}
+	for k, p := range defaults {
+		t.prices[k] = p
+	}
+	return t
+}
+
+// Load returns the built-in table with the entries of the file at path
+// laid over it. A missing file is not an error.
+func Load(path string) (*Table, error) {
+	t := Default()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return t, nil
+	}
+	if err != nil {
+		return t, err
+	}
+
+	var overrides map[string]Price
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return t, fmt.Errorf("pricing file %s: %w", path, err)
+	}
+	for k, p := range overrides {
+		t.prices[strings.ToLower(k)] = p
+	}
+	return t, nil
+}
+
+// Lookup returns the price for a model and whether the table knows it.
+// A key matches where the model ID contains it between word boundaries,
+// so "o3" matches openai/o3 and o3-pro but not gpt-4o3, while o3-mini
+// matches its own, longer key. The longest match wins, then the one found
+// first in the ID. Unknown models get the default price.
+func (t *Table) Lookup(model string) (Price, bool) {
+	model = strings.ToLower(model)
+	best, bestAt := "", 0
+	for k := range t.prices {
+		if k == DefaultKey {
+			continue
+		}
+		at := matchAt(model, k)
+		if at < 0 {
+			continue
+		}
+		if best == "" || len(k) > len(best) || (len(k) == len(best) && at < bestAt) {
+			best, bestAt = k, at
+		}
+	}
+	if best == "" {
+		return t.prices[DefaultKey], false
+	}
+	return t.prices[best], true
+}
+
+// matchAt returns where key first occurs in model as whole words, or -1
+func matchAt(model, key string) int {
+	for from := 0; from < len(model); {
+		i := strings.Index(model[from:], key)
+		if i < 0 {
+			return -1
+		}
+		start, end := from+i, from+i+len(key)
+		if (start == 0 || !isWordByte(model[start-1])) && (end == len(model) || !isWordByte(model[end])) {
+			return start
+		}
+		from = start + 1
+	}
+	return -1
+}
+
+// isWordByte reports whether c continues a word of a lowercased model ID
+func isWordByte(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')
+}
+
+// Cost prices token counts with the model's rates
+func (t *Table) Cost(model string, input, output, cacheRead, cacheWrite int) float64 {
+	p, _ := t.Lookup(model)
+	return p.Cost(input, output, cacheRead, cacheWrite)
+}
+
+// Models returns the table's keys, sorted
+func (t *Table) Models() []string {
+	keys := make([]string, 0, len(t.prices))
+	for k := range t.prices {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Path returns the user's pricing file: $SESSIONS_PRICING_FILE, or
+// pricing.json in the claude-sessions config directory
+func Path() string {
+	if path := os.Getenv("SESSIONS_PRICING_FILE"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "claude-sessions", "pricing.json")
+}
+
+var current atomic.Pointer[Table]
+
+// Use makes t the table adapters price sessions with
+func Use(t *Table) {
+	current.Store(t)
+}
+
+// Current returns the table set with Use, or the built-in one
+func Current() *Table {
+	if t := current.Load(); t != nil {
+		return t
+	}
+	return Default()
+}
+
+// Cost prices token counts with the current table
+func Cost(model string, input, output, cacheRead, cacheWrite int) float64 {
+	return Current().Cost(model, input, output, cacheRead, cacheWrite)
+}
@@ -0,0 +1,152 @@
+package pricing
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestCost(t *testing.T) {
+	// Sonnet: $3 in, $15 out, $0.30 cache read, $3.75 cache write per 1M
+	cost := Default().Cost("claude-sonnet-4-20250514", 1_000_000, 1_000_000, 1_000_000, 1_000_000)
+	expected := 3.0 + 15.0 + 0.30 + 3.75
+
+	if !approx(cost, expected) {
+		t.Errorf("Cost() = %f, want %f", cost, expected)
+	}
+}
+
+func TestCost_Small(t *testing.T) {
+	// Input: 10000 tokens * $3/1M = $0.03
+	// Output: 5000 tokens * $15/1M = $0.075
+	// Cache read: 2000 * $0.30/1M = $0.0006
+	// Cache write: 1000 * $3.75/1M = $0.00375
+	cost := Default().Cost("claude-3-5-sonnet-20241022", 10000, 5000, 2000, 1000)
+	expected := 0.03 + 0.075 + 0.0006 + 0.00375
+
+	if !approx(cost, expected) {
+		t.Errorf("Cost() = %f, want approximately %f", cost, expected)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	table := Default()
+	tests := []struct {
+		model string
+		input float64
+		known bool
+	}{
+		{"claude-opus-4-5-20251101", 5, true},
+		{"claude-opus-4-1-20250805", 15, true},
+		{"claude-haiku-4-5-20251001", 1, true},
+		{"anthropic/claude-3-5-haiku-20241022", 0.80, true},
+		{"gpt-5-codex", 1.25, true},
+		{"gpt-5-mini", 0.25, true},
+		{"gemini-2.5-flash-lite", 0.10, true},
+		{"Claude-Sonnet-4-5", 3, true},
+		{"openai/o3", 2, true},
+		{"o3-pro", 2, true},
+		{"openai/o3-mini-2025-01-31", 1.10, true},
+		{"gpt-4o3", 3, false}, // Neither gpt-4o nor o3
+		{"claude-opus-45", 3, false},
+		{"some-local-model", 3, false},
+		{"", 3, false},
+	}
+
+	for _, tt := range tests {
+		p, known := table.Lookup(tt.model)
+		if p.Input != tt.input || known != tt.known {
+			t.Errorf("Lookup(%q) = %+v, %v, want input %v, %v", tt.model, p, known, tt.input, tt.known)
+		}
+	}
+}
+
+func TestLookup_TiesAreStable(t *testing.T) {
+	table := &Table{prices: map[string]Price{
+		DefaultKey: {},
+		"foo":      {Input: 1},
+		"bar":      {Input: 2},
+		"baz":      {Input: 3},
+	}}
+	// Equal lengths: the key found first in the ID wins, whatever the
+	// map's order
+	for i := 0; i < 50; i++ {
+		if p, _ := table.Lookup("baz/foo-bar"); p.Input != 3 {
+			t.Fatalf("Lookup() = %+v, want baz", p)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pricing.json")
+	os.WriteFile(path, []byte(`{
+		"claude-opus-4-5": {"input": 4, "output": 20},
+		"My-Local-Model": {"input": 0.5, "output": 1},
+		"default": {"input": 0, "output": 0}
+	}`), 0644)
+
+	table, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if p, _ := table.Lookup("claude-opus-4-5-20251101"); p.Input != 4 || p.CacheRead != 0 {
+		t.Errorf("overridden price = %+v, want the file's entry as is", p)
+	}
+	if p, known := table.Lookup("my-local-model-q4"); !known || p.Output != 1 {
+		t.Errorf("added price = %+v, %v", p, known)
+	}
+	if p, _ := table.Lookup("claude-haiku-4-5"); p.Input != 1 {
+		t.Errorf("untouched price = %+v, want the default", p)
+	}
+	if cost := table.Cost("unknown", 1000, 1000, 0, 0); cost != 0 {
+		t.Errorf("Cost() of an unknown model = %f, want the overridden default of 0", cost)
+	}
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	table, err := Load(filepath.Join(t.TempDir(), "none.json"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(table.Models()) != len(Default().Models()) {
+		t.Error("Load() of a missing file should return the defaults")
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pricing.json")
+	os.WriteFile(path, []byte(`{"gpt-5": 3}`), 0644)
+
+	table, err := Load(path)
+	if err == nil {
+		t.Error("Load() should fail on a malformed file")
+	}
+	if table == nil || table.Cost("gpt-5", 1_000_000, 0, 0, 0) != 1.25 {
+		t.Error("Load() should still return the defaults on error")
+	}
+}
+
+func TestUse(t *testing.T) {
+	t.Cleanup(func() { Use(nil) })
+
+	path := filepath.Join(t.TempDir(), "pricing.json")
+	os.WriteFile(path, []byte(`{"default": {"input": 1}}`), 0644)
+	table, _ := Load(path)
+	Use(table)
+
+	if cost := Cost("unknown", 1_000_000, 0, 0, 0); cost != 1 {
+		t.Errorf("Cost() = %f, want the table set with Use", cost)
+	}
+}
+
+func TestPath(t *testing.T) {
+	t.Setenv("SESSIONS_PRICING_FILE", "/tmp/prices.json")
+	if got := Path(); got != "/tmp/prices.json" {
+		t.Errorf("Path() = %q, want the env override", got)
+	}
+}
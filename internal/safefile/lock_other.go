@@ -0,0 +1,13 @@
+//go:build !unix
+
+package safefile
+
+import "os"
+
+// tryLock always succeeds where flock isn't available; writes are still
+// atomic, only concurrent edits can be lost
+func tryLock(f *os.File) (bool, error) {
+	return true, nil
+}
+
+func unlockFile(f *os.File) {}
@@ -0,0 +1,104 @@
+// Package safefile replaces files so readers never see them half written,
+// and serializes writers across processes with an advisory lock file.
+package safefile
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LockName is the lock file Lock creates in a directory
+const LockName = "sessions.lock"
+
+// lockTimeout bounds how long Lock waits for another process
+const lockTimeout = 10 * time.Second
+
+// Write replaces path with what fn writes. The data goes to a temporary
+// file in the same directory, which is synced and then renamed over path,
+// so readers see either the old content or the new, never a mix. If fn
+// or any step fails, path is left as it was.
+func Write(path string, fn func(w *bufio.Writer) error) error {
+	return WriteWithModTime(path, time.Time{}, fn)
+}
+
+// WriteWithModTime is Write for files whose modification time means
+// something to their readers: the new file gets mtime before it replaces
+// the old one. A zero mtime leaves the current time.
+func WriteWithModTime(path string, mtime time.Time, fn func(w *bufio.Writer) error) (err error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer func() {
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+
+	w := bufio.NewWriter(f)
+	if err = fn(w); err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	// CreateTemp makes the file private to the user
+	if err := os.Chmod(tmp, 0644); err != nil {
+		return err
+	}
+	if !mtime.IsZero() {
+		if err := os.Chtimes(tmp, mtime, mtime); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp, path)
+}
+
+// Lock takes the advisory lock guarding the files in dir, waiting up to
+// lockTimeout for other processes to release it. Processes that write
+// those files hold it while they read, change and replace one. The lock
+// is released by calling unlock, or when the process exits.
+func Lock(dir string) (unlock func(), err error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, LockName), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		ok, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if ok {
+			return func() {
+				unlockFile(f)
+				f.Close()
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%s is locked by another process", dir)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
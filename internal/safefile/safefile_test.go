@@ -0,0 +1,92 @@
+package safefile
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.tsv")
+	os.WriteFile(path, []byte("old\n"), 0644)
+
+	err := Write(path, func(w *bufio.Writer) error {
+		w.WriteString("new\n")
+		return errors.New("disk full")
+	})
+	if err == nil {
+		t.Fatal("Write() should return fn's error")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old\n" {
+		t.Errorf("failed Write() left %q, want the old content", data)
+	}
+
+	if err := Write(path, func(w *bufio.Writer) error {
+		_, err := w.WriteString("new\n")
+		return err
+	}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new\n" {
+		t.Errorf("file = %q, want the new content", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0644 {
+		t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+	}
+
+	// No temporary files are left behind either way
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("dir holds %d files, want only data.tsv", len(entries))
+	}
+}
+
+func TestWriteWithModTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "data.tsv")
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	if err := WriteWithModTime(path, mtime, func(w *bufio.Writer) error { return nil }); err != nil {
+		t.Fatalf("WriteWithModTime() error = %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || !info.ModTime().Equal(mtime) {
+		t.Errorf("mtime = %v, want %v", info.ModTime(), mtime)
+	}
+}
+
+func TestLock(t *testing.T) {
+	dir := t.TempDir()
+	unlock, err := Lock(dir)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		second, err := Lock(dir)
+		if err != nil {
+			t.Errorf("second Lock() error = %v", err)
+			close(acquired)
+			return
+		}
+		acquired <- second
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second Lock() succeeded while the first was held")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case second := <-acquired:
+		if second != nil {
+			second()
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("second Lock() didn't succeed after unlock")
+	}
+}
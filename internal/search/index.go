@@ -0,0 +1,248 @@
+// Package search keeps an inverted index over session transcripts: the
+// text of each message, tool inputs and tool outputs. Queries are ranked
+// with BM25 and snippets are cut from the matching messages.
+package search
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+	"github.com/Julian194/claude-sessions-tui/internal/safefile"
+)
+
+// IndexName is the index's file name, next to the session cache
+const IndexName = "search-index.tsv"
+
+// Index maps terms to the sessions containing them
+type Index struct {
+	docs     map[string]int            // Session ID to its number of terms
+	postings map[string]map[string]int // Term to session ID to occurrences
+}
+
+// NewIndex returns an empty index
+func NewIndex() *Index {
+	return &Index{
+		docs:     make(map[string]int),
+		postings: make(map[string]map[string]int),
+	}
+}
+
+// Len returns the number of indexed sessions
+func (ix *Index) Len() int {
+	return len(ix.docs)
+}
+
+// Has reports whether a session is indexed
+func (ix *Index) Has(id string) bool {
+	_, ok := ix.docs[id]
+	return ok
+}
+
+// Add indexes a session's messages, replacing what was indexed for it
+func (ix *Index) Add(id string, messages []adapters.Message) {
+	ix.Remove(id)
+	counts := make(map[string]int)
+	total := 0
+	for _, m := range messages {
+		for _, text := range segments(m) {
+			for _, t := range tokenize(text) {
+				counts[t.term]++
+				total++
+			}
+		}
+	}
+	ix.docs[id] = total
+	for term, n := range counts {
+		if ix.postings[term] == nil {
+			ix.postings[term] = make(map[string]int)
+		}
+		ix.postings[term][id] = n
+	}
+}
+
+// Remove drops sessions from the index
+func (ix *Index) Remove(ids ...string) {
+	drop := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if _, ok := ix.docs[id]; ok {
+			drop[id] = true
+			delete(ix.docs, id)
+		}
+	}
+	if len(drop) == 0 {
+		return
+	}
+	for term, docs := range ix.postings {
+		for id := range drop {
+			delete(docs, id)
+		}
+		if len(docs) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+}
+
+// Save writes the index in TSV format. A line with an empty term records
+// an indexed session and its length; the other lines list a term's
+// sessions and occurrence counts. The file is replaced atomically while
+// holding the lock of its directory.
+func (ix *Index) Save(path string) error {
+	return ix.SaveAt(path, time.Time{})
+}
+
+// SaveAt is Save for an index built from sessions as they were at mtime:
+// the file is dated mtime, so sessions changed since are indexed again.
+// A zero mtime leaves the current time.
+func (ix *Index) SaveAt(path string, mtime time.Time) error {
+	unlock, err := safefile.Lock(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return safefile.WriteWithModTime(path, mtime, func(w *bufio.Writer) error {
+		for _, id := range sortedKeys(ix.docs) {
+			fmt.Fprintf(w, "\t%s\t%d\n", id, ix.docs[id])
+		}
+		for _, term := range sortedKeys(ix.postings) {
+			docs := ix.postings[term]
+			w.WriteString(term)
+			for _, id := range sortedKeys(docs) {
+				fmt.Fprintf(w, "\t%s\t%d", id, docs[id])
+			}
+			w.WriteByte('\n')
+		}
+		return nil
+	})
+}
+
+// Load reads an index written by Save
+func Load(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ix := NewIndex()
+	scanner := bufio.NewScanner(f)
+	// Common terms list many sessions
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		parts := strings.Split(scanner.Text(), "\t")
+		if len(parts) < 3 || len(parts)%2 == 0 {
+			continue // Skip malformed lines
+		}
+		if parts[0] == "" {
+			n, _ := strconv.Atoi(parts[2])
+			ix.docs[parts[1]] = n
+			continue
+		}
+		docs := make(map[string]int, len(parts)/2)
+		for i := 1; i+1 < len(parts); i += 2 {
+			n, _ := strconv.Atoi(parts[i+1])
+			docs[parts[i]] = n
+		}
+		ix.postings[parts[0]] = docs
+	}
+	return ix, scanner.Err()
+}
+
+// Update brings the index at path in line with the given sessions and
+// writes it back. Sessions whose file is older than the index are kept
+// as they are, the others are read again through the adapter and those
+// not listed are dropped.
+func Update(ctx context.Context, adapter adapters.Adapter, path string, ids []string) (*Index, error) {
+	started := time.Now()
+	var indexMtime time.Time
+	if info, err := os.Stat(path); err == nil {
+		indexMtime = info.ModTime()
+	}
+	ix, err := Load(path)
+	if err != nil {
+		ix = NewIndex()
+	}
+
+	listed := make(map[string]bool, len(ids))
+	var stale []string
+	for _, id := range ids {
+		listed[id] = true
+		sessionPath := adapter.GetSessionFile(id)
+		if sessionPath == "" {
+			continue
+		}
+		info, err := os.Stat(sessionPath)
+		if err != nil {
+			continue
+		}
+		if ix.Has(id) && info.ModTime().Before(indexMtime) {
+			continue
+		}
+		stale = append(stale, id)
+	}
+
+	var gone []string
+	for id := range ix.docs {
+		if !listed[id] {
+			gone = append(gone, id)
+		}
+	}
+	ix.Remove(gone...)
+	if len(stale) == 0 && len(gone) == 0 && !indexMtime.IsZero() {
+		return ix, nil
+	}
+
+	// Read the changed sessions in parallel; adding to the index is cheap
+	// next to parsing, so it happens under the lock
+	jobs := make(chan string, len(stale))
+	for _, id := range stale {
+		jobs <- id
+	}
+	close(jobs)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				messages, err := adapter.ExportMessages(ctx, id)
+				if err != nil {
+					continue
+				}
+				mu.Lock()
+				ix.Add(id, messages)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return ix, ix.SaveAt(path, started)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,205 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+// BM25 parameters
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// snippetWidth is roughly how many characters a snippet shows
+const snippetWidth = 120
+
+// Result is a session matching a query
+type Result struct {
+	SessionID string
+	Score     float64
+}
+
+// Query is a parsed search query. Every term must match; a term ending in
+// * matches any term it prefixes.
+type Query struct {
+	terms []queryTerm
+}
+
+type queryTerm struct {
+	text   string
+	prefix bool
+}
+
+// ParseQuery splits a query into terms the way the index does
+func ParseQuery(s string) Query {
+	var q Query
+	for _, word := range strings.Fields(s) {
+		prefix := strings.HasSuffix(word, "*")
+		terms := Terms(word)
+		for i, t := range terms {
+			// Only the word's last part can be a prefix
+			q.terms = append(q.terms, queryTerm{t, prefix && i == len(terms)-1})
+		}
+	}
+	return q
+}
+
+// Empty reports whether the query has no searchable terms
+func (q Query) Empty() bool {
+	return len(q.terms) == 0
+}
+
+func (qt queryTerm) matches(term string) bool {
+	if qt.prefix {
+		return strings.HasPrefix(term, qt.text)
+	}
+	return term == qt.text
+}
+
+// Search returns the sessions matching every term of q, best first
+func (ix *Index) Search(q Query) []Result {
+	if q.Empty() || len(ix.docs) == 0 {
+		return nil
+	}
+
+	total := 0
+	for _, n := range ix.docs {
+		total += n
+	}
+	avgLen := float64(total) / float64(len(ix.docs))
+	if avgLen == 0 {
+		avgLen = 1
+	}
+
+	scores := make(map[string]float64)
+	matched := make(map[string]int)
+	for _, qt := range q.terms {
+		seen := make(map[string]bool)
+		for _, term := range ix.expand(qt) {
+			docs := ix.postings[term]
+			df := float64(len(docs))
+			idf := math.Log(1 + (float64(len(ix.docs))-df+0.5)/(df+0.5))
+			for id, tf := range docs {
+				norm := k1 * (1 - b + b*float64(ix.docs[id])/avgLen)
+				scores[id] += idf * float64(tf) * (k1 + 1) / (float64(tf) + norm)
+				seen[id] = true
+			}
+		}
+		for id := range seen {
+			matched[id]++
+		}
+	}
+
+	var results []Result
+	for id, n := range matched {
+		if n == len(q.terms) {
+			results = append(results, Result{SessionID: id, Score: scores[id]})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].SessionID < results[j].SessionID
+	})
+	return results
+}
+
+// expand returns the indexed terms a query term matches
+func (ix *Index) expand(qt queryTerm) []string {
+	if !qt.prefix {
+		return []string{qt.text}
+	}
+	var terms []string
+	for term := range ix.postings {
+		if qt.matches(term) {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// Snippet is the part of a session that best matches a query
+type Snippet struct {
+	Turn int    // 0-based index into the exported messages
+	Role string // Role of the message it comes from
+	Text string // On one line, with … where it was cut
+}
+
+// FindSnippet returns the message text matching the most query terms, cut
+// around the first match. ok is false when no message matches.
+func FindSnippet(messages []adapters.Message, q Query) (s Snippet, ok bool) {
+	best := 0
+	for turn, m := range messages {
+		for _, text := range segments(m) {
+			first, n := -1, 0
+			found := make([]bool, len(q.terms))
+			for _, t := range tokenize(text) {
+				for i, qt := range q.terms {
+					if !found[i] && qt.matches(t.term) {
+						found[i] = true
+						n++
+						if first < 0 {
+							first = t.start
+						}
+					}
+				}
+			}
+			if n > best {
+				best = n
+				s = Snippet{Turn: turn, Role: m.Role, Text: cut(text, first)}
+			}
+		}
+	}
+	return s, best > 0
+}
+
+// cut returns about snippetWidth characters of text around offset, on a
+// single line
+func cut(text string, offset int) string {
+	start := offset
+	for n := 0; start > 0 && n < snippetWidth/3; n++ {
+		_, size := utf8.DecodeLastRuneInString(text[:start])
+		start -= size
+	}
+	end := offset
+	for n := 0; end < len(text) && n < snippetWidth*2/3; n++ {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+
+	s := strings.Join(strings.Fields(text[start:end]), " ")
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(text) {
+		s += "…"
+	}
+	return s
+}
+
+// Highlight wraps the words of s that match q in on and off, e.g. ANSI
+// escapes
+func Highlight(s string, q Query, on, off string) string {
+	var sb strings.Builder
+	last := 0
+	for _, t := range tokenize(s) {
+		for _, qt := range q.terms {
+			if qt.matches(t.term) {
+				sb.WriteString(s[last:t.start])
+				sb.WriteString(on)
+				sb.WriteString(s[t.start:t.end])
+				sb.WriteString(off)
+				last = t.end
+				break
+			}
+		}
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
@@ -0,0 +1,176 @@
+package search
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+func testIndex() *Index {
+	ix := NewIndex()
+	ix.Add("s1", []adapters.Message{
+		{Role: "user", Content: "Fix the login redirect"},
+		{Role: "assistant", ToolCalls: []adapters.ToolCall{{ID: "t1", Name: "Bash", Input: `{"command":"go test ./internal/auth/..."}`}}},
+		{Role: "user", ToolResults: []adapters.ToolResult{{ToolUseID: "t1", Content: "FAIL: TestRedirectLoop", Success: false}}},
+	})
+	ix.Add("s2", []adapters.Message{
+		{Role: "user", Content: "Why does the redirect loop? The redirect should stop after login."},
+	})
+	ix.Add("s3", []adapters.Message{
+		{Role: "user", Content: "Write a changelog"},
+	})
+	return ix
+}
+
+func ids(results []Result) string {
+	var s []string
+	for _, r := range results {
+		s = append(s, r.SessionID)
+	}
+	return strings.Join(s, ",")
+}
+
+func TestSearch(t *testing.T) {
+	ix := testIndex()
+
+	// s2 mentions redirect twice in a shorter session
+	if got := ids(ix.Search(ParseQuery("redirect"))); got != "s2,s1" {
+		t.Errorf("Search(redirect) = %s, want s2,s1", got)
+	}
+	// Every term has to match; tool inputs and outputs are searched
+	if got := ids(ix.Search(ParseQuery("redirect auth"))); got != "s1" {
+		t.Errorf("Search(redirect auth) = %s, want s1", got)
+	}
+	if got := ids(ix.Search(ParseQuery("testredirectloop"))); got != "s1" {
+		t.Errorf("Search(testredirectloop) = %s, want s1", got)
+	}
+	if got := ids(ix.Search(ParseQuery("change*"))); got != "s3" {
+		t.Errorf("Search(change*) = %s, want s3", got)
+	}
+	if got := ix.Search(ParseQuery("change")); len(got) != 0 {
+		t.Errorf("Search(change) = %v, want whole words only", got)
+	}
+	if got := ix.Search(ParseQuery("!")); got != nil {
+		t.Errorf("Search(!) = %v, want nil", got)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	ix := testIndex()
+	ix.Remove("s3")
+	path := filepath.Join(t.TempDir(), IndexName)
+	if err := ix.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.Len() != 2 || loaded.Has("s3") {
+		t.Errorf("Load() has %d sessions, want s1 and s2", loaded.Len())
+	}
+	if _, ok := loaded.postings["changelog"]; ok {
+		t.Error("terms of removed sessions should be dropped")
+	}
+	for _, q := range []string{"redirect", "login", "redirect auth"} {
+		if got, want := ids(loaded.Search(ParseQuery(q))), ids(ix.Search(ParseQuery(q))); got != want {
+			t.Errorf("loaded Search(%s) = %s, want %s", q, got, want)
+		}
+	}
+}
+
+// fileAdapter serves messages for sessions stored in files
+type fileAdapter struct {
+	files    map[string]string
+	messages map[string][]adapters.Message
+	calls    int
+	during   func(id string) // Called on each export, if set
+}
+
+func (a *fileAdapter) Name() string                                       { return "files" }
+func (a *fileAdapter) DataDir() string                                    { return "" }
+func (a *fileAdapter) CacheDir() string                                   { return "" }
+func (a *fileAdapter) ResumeCmd(id string) string                         { return "" }
+func (a *fileAdapter) ListSessions(ctx context.Context) ([]string, error) { return nil, nil }
+func (a *fileAdapter) GetSessionFile(id string) string                    { return a.files[id] }
+func (a *fileAdapter) ExtractMeta(ctx context.Context, id string) (*adapters.SessionMeta, error) {
+	return nil, nil
+}
+func (a *fileAdapter) GetSessionInfo(id string) (*adapters.SessionInfo, error) { return nil, nil }
+func (a *fileAdapter) ExportMessages(ctx context.Context, id string) ([]adapters.Message, error) {
+	a.calls++
+	if a.during != nil {
+		a.during(id)
+	}
+	return a.messages[id], nil
+}
+
+func TestUpdate_SessionChangedDuringUpdate(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "s1.jsonl")
+	os.WriteFile(file, []byte("{}"), 0644)
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes(file, past, past)
+	a := &fileAdapter{
+		files:    map[string]string{"s1": file},
+		messages: map[string][]adapters.Message{"s1": {{Role: "user", Content: "Fix the redirect"}}},
+	}
+	path := filepath.Join(dir, IndexName)
+
+	// The session is written to while it is being indexed
+	a.during = func(id string) {
+		now := time.Now()
+		os.Chtimes(file, now, now)
+	}
+	if _, err := Update(context.Background(), a, path, []string{"s1"}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	a.during = nil
+	a.calls = 0
+	a.messages["s1"] = append(a.messages["s1"], adapters.Message{Role: "user", Content: "Then the changelog"})
+	ix, err := Update(context.Background(), a, path, []string{"s1"})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if a.calls != 1 || len(ix.Search(ParseQuery("changelog"))) != 1 {
+		t.Errorf("second Update() made %d exports, want the changed session indexed again", a.calls)
+	}
+}
+
+func TestFindSnippet(t *testing.T) {
+	long := strings.Repeat("padding ", 40) + "the redirect loop starts here " + strings.Repeat("more ", 40)
+	messages := []adapters.Message{
+		{Role: "user", Content: "redirect only"},
+		{Role: "assistant", Content: long},
+	}
+
+	q := ParseQuery("redirect loop")
+	s, ok := FindSnippet(messages, q)
+	if !ok || s.Turn != 1 || s.Role != "assistant" {
+		t.Fatalf("FindSnippet() = %+v, %v, want turn 1 matching both terms", s, ok)
+	}
+	if !strings.HasPrefix(s.Text, "…") || !strings.HasSuffix(s.Text, "…") || !strings.Contains(s.Text, "the redirect loop starts") {
+		t.Errorf("snippet = %q, want a cut around the match", s.Text)
+	}
+
+	if got := Highlight("A Redirect loops", q, "[", "]"); got != "A [Redirect] loops" {
+		t.Errorf("Highlight() = %q", got)
+	}
+	if _, ok := FindSnippet(messages, ParseQuery("missing")); ok {
+		t.Error("FindSnippet() found a snippet for a missing term")
+	}
+}
+
+func TestTerms(t *testing.T) {
+	got := strings.Join(Terms(`Run "go test ./...", then BuildIndex(ctx) — naïve é 42`), " ")
+	if got != "run go test then buildindex ctx naïve 42" {
+		t.Errorf("Terms() = %q", got)
+	}
+}
@@ -0,0 +1,128 @@
+package search
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+)
+
+// maxToolBytes bounds how much of each tool input and output is indexed;
+// file contents and command output past it rarely make useful matches
+const maxToolBytes = 8 << 10
+
+// Terms shorter or longer than these are not indexed
+const (
+	minTermLen = 2
+	maxTermLen = 64
+)
+
+// token is a term and where it appears in the text it came from
+type token struct {
+	term       string
+	start, end int // Byte offsets
+}
+
+// tokenize splits text into lowercased runs of letters, digits and
+// underscores, so identifiers like build_index or BuildIndex stay whole
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+		if word && start < 0 {
+			start = i
+		}
+		if !word && start >= 0 {
+			tokens = appendToken(tokens, text, start, i)
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = appendToken(tokens, text, start, len(text))
+	}
+	return tokens
+}
+
+func appendToken(tokens []token, text string, start, end int) []token {
+	n := utf8.RuneCountInString(text[start:end])
+	if n < minTermLen || n > maxTermLen {
+		return tokens
+	}
+	return append(tokens, token{strings.ToLower(text[start:end]), start, end})
+}
+
+// Terms returns the indexed terms of text, in order and with repeats
+func Terms(text string) []string {
+	tokens := tokenize(text)
+	terms := make([]string, len(tokens))
+	for i, t := range tokens {
+		terms[i] = t.term
+	}
+	return terms
+}
+
+// segments returns the searchable text of a message: its text, the
+// string values of its tool inputs and its tool outputs. Thinking and
+// images are left out.
+func segments(m adapters.Message) []string {
+	var texts []string
+	for _, b := range adapters.MessageBlocks(m) {
+		switch b.Kind {
+		case adapters.BlockText:
+			texts = append(texts, b.Text)
+		case adapters.BlockToolUse:
+			texts = append(texts, truncate(b.Name+" "+inputText(b.Input)))
+		case adapters.BlockToolResult:
+			texts = append(texts, truncate(b.Text))
+		}
+	}
+	return texts
+}
+
+// inputText joins the string values of a JSON tool input, so escapes
+// like \n don't end up inside terms
+func inputText(input string) string {
+	var v any
+	if json.Unmarshal([]byte(input), &v) != nil {
+		return input
+	}
+	var parts []string
+	var walk func(any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case string:
+			parts = append(parts, v)
+		case []any:
+			for _, e := range v {
+				walk(e)
+			}
+		case map[string]any:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(v[k])
+			}
+		}
+	}
+	walk(v)
+	return strings.Join(parts, "\n")
+}
+
+func truncate(s string) string {
+	if len(s) <= maxToolBytes {
+		return s
+	}
+	// Back up to a rune boundary
+	end := maxToolBytes
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end]
+}
@@ -25,6 +25,9 @@ func Format(s *adapters.Stats) string {
 	sb.WriteString("🔤 Tokens\n")
 	sb.WriteString(fmt.Sprintf("   Input:       %s\n", formatNumber(s.InputTokens)))
 	sb.WriteString(fmt.Sprintf("   Output:      %s\n", formatNumber(s.OutputTokens)))
+	if s.ReasoningTokens > 0 {
+		sb.WriteString(fmt.Sprintf("   Reasoning:   %s (of output)\n", formatNumber(s.ReasoningTokens)))
+	}
 	sb.WriteString(fmt.Sprintf("   Cache Read:  %s\n", formatNumber(s.CacheRead)))
 	sb.WriteString(fmt.Sprintf("   Cache Write: %s\n", formatNumber(s.CacheWrite)))
 	sb.WriteString(fmt.Sprintf("   Total:       %s\n\n", formatNumber(s.InputTokens+s.OutputTokens+s.CacheRead+s.CacheWrite)))
@@ -33,6 +36,23 @@ func Format(s *adapters.Stats) string {
 	sb.WriteString("💰 Cost\n")
 	sb.WriteString(fmt.Sprintf("   Estimated: $%.4f\n\n", s.Cost))
 
+	// Subagents, and the totals including them
+	if len(s.Subagents) > 0 {
+		sb.WriteString(fmt.Sprintf("🤖 Subagents (%d)\n", len(s.Subagents)))
+		for _, sub := range s.Subagents {
+			name := sub.Description
+			if name == "" {
+				name = sub.ID
+			}
+			sb.WriteString(fmt.Sprintf("   $%-9.4f %9s tokens  %s\n", sub.Cost, formatNumber(tokens(sub.Totals)), name))
+		}
+		sb.WriteString("\n")
+
+		sb.WriteString("Σ Including Subagents\n")
+		sb.WriteString(fmt.Sprintf("   Tokens:    %s\n", formatNumber(tokens(s.Inclusive))))
+		sb.WriteString(fmt.Sprintf("   Estimated: $%.4f\n\n", s.Inclusive.Cost))
+	}
+
 	// Tool calls
 	if len(s.ToolCalls) > 0 {
 		sb.WriteString("🔧 Tool Calls\n")
@@ -67,6 +87,11 @@ func FormatCompact(s *adapters.Stats) string {
 	)
 }
 
+// tokens returns the total token count of t
+func tokens(t adapters.Totals) int {
+	return t.InputTokens + t.OutputTokens + t.CacheRead + t.CacheWrite
+}
+
 // FormatTokens formats token counts for display
 func FormatTokens(input, output, cacheRead, cacheWrite int) string {
 	total := input + output + cacheRead + cacheWrite
@@ -78,22 +103,6 @@ func FormatTokens(input, output, cacheRead, cacheWrite int) string {
 	)
 }
 
-// CalculateCost calculates the estimated cost based on token counts
-func CalculateCost(input, output, cacheRead, cacheWrite int) float64 {
-	// Sonnet 3.5 pricing (per 1M tokens)
-	inputPrice := 3.0
-	outputPrice := 15.0
-	cacheReadPrice := 0.30
-	cacheWritePrice := 3.75
-
-	cost := float64(input) * inputPrice / 1_000_000
-	cost += float64(output) * outputPrice / 1_000_000
-	cost += float64(cacheRead) * cacheReadPrice / 1_000_000
-	cost += float64(cacheWrite) * cacheWritePrice / 1_000_000
-
-	return cost
-}
-
 // formatNumber formats a number with thousands separators
 func formatNumber(n int) string {
 	if n < 1000 {
@@ -74,6 +74,18 @@ func TestFormat_TokenCounts(t *testing.T) {
 	}
 }
 
+func TestFormat_ReasoningTokens(t *testing.T) {
+	s := sampleStats()
+	if strings.Contains(Format(s), "Reasoning") {
+		t.Error("Format should hide reasoning when there is none")
+	}
+
+	s.ReasoningTokens = 1200
+	if !strings.Contains(Format(s), "Reasoning:   1,200 (of output)") {
+		t.Error("Format should show reasoning tokens")
+	}
+}
+
 func TestFormat_Cost(t *testing.T) {
 	s := sampleStats()
 	output := Format(s)
@@ -104,6 +116,35 @@ func TestFormat_ToolCalls(t *testing.T) {
 	}
 }
 
+func TestFormat_Subagents(t *testing.T) {
+	s := sampleStats()
+	if strings.Contains(Format(s), "Subagents") {
+		t.Error("Format should not show subagents for a session without them")
+	}
+
+	s.Rollup([]adapters.SubagentStats{
+		{ID: "p/agent-1", Description: "Find the tests", Totals: adapters.Totals{InputTokens: 1000, Cost: 0.01}},
+		{ID: "p/agent-2", Totals: adapters.Totals{OutputTokens: 500, Cost: 0.5}},
+	})
+	output := Format(s)
+
+	if !strings.Contains(output, "Subagents (2)") {
+		t.Error("Format should count the subagents")
+	}
+	if !strings.Contains(output, "Find the tests") || !strings.Contains(output, "p/agent-2") {
+		t.Error("Format should name each subagent, by ID when it has no description")
+	}
+	if !strings.Contains(output, "Estimated: $0.0825") {
+		t.Error("Format should keep the session's own cost")
+	}
+	if !strings.Contains(output, "Estimated: $0.5925") {
+		t.Error("Format should show the cost including subagents")
+	}
+	if strings.Index(output, "p/agent-2") > strings.Index(output, "Find the tests") {
+		t.Error("Format should list the most expensive subagent first")
+	}
+}
+
 func TestFormat_NoToolCalls(t *testing.T) {
 	s := &adapters.Stats{
 		UserMessages:      1,
@@ -149,39 +190,6 @@ func TestFormatTokens(t *testing.T) {
 	}
 }
 
-func TestCalculateCost(t *testing.T) {
-	// Test with known values
-	// Input: 1M tokens * $3/1M = $3
-	// Output: 1M tokens * $15/1M = $15
-	// Cache read: 1M tokens * $0.30/1M = $0.30
-	// Cache write: 1M tokens * $3.75/1M = $3.75
-	// Total = $22.05
-
-	cost := CalculateCost(1_000_000, 1_000_000, 1_000_000, 1_000_000)
-	expected := 3.0 + 15.0 + 0.30 + 3.75
-
-	if cost != expected {
-		t.Errorf("CalculateCost() = %f, want %f", cost, expected)
-	}
-}
-
-func TestCalculateCost_Small(t *testing.T) {
-	// Test with smaller values
-	// Input: 10000 tokens * $3/1M = $0.03
-	// Output: 5000 tokens * $15/1M = $0.075
-	// Cache read: 2000 * $0.30/1M = $0.0006
-	// Cache write: 1000 * $3.75/1M = $0.00375
-
-	cost := CalculateCost(10000, 5000, 2000, 1000)
-	expected := 0.03 + 0.075 + 0.0006 + 0.00375
-
-	// Use approximate comparison due to floating point
-	diff := cost - expected
-	if diff < -0.0001 || diff > 0.0001 {
-		t.Errorf("CalculateCost() = %f, want approximately %f", cost, expected)
-	}
-}
-
 func TestFormatNumber(t *testing.T) {
 	tests := []struct {
 		input int
@@ -0,0 +1,256 @@
+// Package trash moves session files out of a provider's data directory so
+// they can be restored later. Trashed and archived sessions live in
+// separate directories under the cache dir; each removal gets its own
+// folder with a manifest recording where every file came from.
+package trash
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kind is where removed sessions are kept
+type Kind string
+
+const (
+	KindTrash   Kind = "trash"
+	KindArchive Kind = "archive"
+)
+
+const manifestFile = "manifest.json"
+
+// ErrNotFound is returned by Restore when no removed copy of a session exists
+var ErrNotFound = errors.New("session not found in trash or archive")
+
+// Entry describes one removed session
+type Entry struct {
+	SessionID string    `json:"session_id"`
+	Provider  string    `json:"provider"`
+	Kind      Kind      `json:"kind"`
+	RemovedAt time.Time `json:"removed_at"`
+	Files     []File    `json:"files"`
+
+	dir string // Folder holding the manifest and the stored files
+}
+
+// File maps a stored file back to its original location
+type File struct {
+	Original string `json:"original"`
+	Stored   string `json:"stored"` // Name inside the entry folder
+}
+
+// Dir returns the directory holding sessions of the given kind
+func Dir(root string, kind Kind) string {
+	return filepath.Join(root, string(kind))
+}
+
+// Move moves the session's files into the trash or archive under root.
+// If any file can't be moved, the ones already moved are put back.
+func Move(root string, kind Kind, provider, sessionID string, files []string) (*Entry, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("session %s has no files", sessionID)
+	}
+
+	now := time.Now()
+	e := &Entry{
+		SessionID: sessionID,
+		Provider:  provider,
+		Kind:      kind,
+		RemovedAt: now.UTC(),
+		dir:       filepath.Join(Dir(root, kind), now.Format("20060102-150405.000")+"-"+safeName(sessionID)),
+	}
+	if err := os.MkdirAll(e.dir, 0755); err != nil {
+		return nil, err
+	}
+
+	for i, f := range files {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			return nil, err
+		}
+		e.Files = append(e.Files, File{
+			Original: abs,
+			Stored:   fmt.Sprintf("%d-%s", i, filepath.Base(abs)),
+		})
+	}
+
+	// Record the manifest first so an interrupted move can still be restored
+	if err := e.writeManifest(); err != nil {
+		os.RemoveAll(e.dir)
+		return nil, err
+	}
+
+	for i, f := range e.Files {
+		if err := move(f.Original, filepath.Join(e.dir, f.Stored)); err != nil {
+			for _, done := range e.Files[:i] {
+				move(filepath.Join(e.dir, done.Stored), done.Original)
+			}
+			os.RemoveAll(e.dir)
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// List returns every trashed and archived session, most recent first
+func List(root string) ([]Entry, error) {
+	var entries []Entry
+	for _, kind := range []Kind{KindTrash, KindArchive} {
+		dirs, err := os.ReadDir(Dir(root, kind))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, d := range dirs {
+			if !d.IsDir() {
+				continue
+			}
+			dir := filepath.Join(Dir(root, kind), d.Name())
+			e, err := readManifest(dir)
+			if err != nil {
+				continue // Skip folders without a readable manifest
+			}
+			entries = append(entries, *e)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].RemovedAt.After(entries[j].RemovedAt)
+	})
+	return entries, nil
+}
+
+// Restore moves the most recently removed copy of a session back to its
+// original location. It refuses to overwrite files that exist again. The
+// manifest is rewritten as files go back, so a restore that fails partway
+// can be retried.
+func Restore(root, sessionID string) (*Entry, error) {
+	entries, err := List(root)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		e := &entries[i]
+		if e.SessionID != sessionID {
+			continue
+		}
+
+		// Files whose stored copy is gone were never moved, e.g. after an
+		// interrupted Move, or are back already
+		var pending []File
+		for _, f := range e.Files {
+			if _, err := os.Lstat(filepath.Join(e.dir, f.Stored)); os.IsNotExist(err) {
+				continue
+			}
+			if _, err := os.Lstat(f.Original); err == nil {
+				return nil, fmt.Errorf("cannot restore %s: %s already exists", sessionID, f.Original)
+			}
+			pending = append(pending, f)
+		}
+
+		restored := *e
+		restored.Files = slices.Clone(e.Files)
+		for _, f := range pending {
+			if err := os.MkdirAll(filepath.Dir(f.Original), 0755); err != nil {
+				return nil, err
+			}
+			if err := move(filepath.Join(e.dir, f.Stored), f.Original); err != nil {
+				return nil, err
+			}
+			// Forget the file, so retrying after a later failure doesn't
+			// find it in the way
+			e.Files = slices.DeleteFunc(e.Files, func(g File) bool { return g == f })
+			if err := e.writeManifest(); err != nil {
+				return nil, err
+			}
+		}
+		return &restored, os.RemoveAll(e.dir)
+	}
+	return nil, ErrNotFound
+}
+
+func (e *Entry) writeManifest() error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(e.dir, manifestFile), data, 0644)
+}
+
+func readManifest(dir string) (*Entry, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	e.dir = dir
+	return &e, nil
+}
+
+// safeName makes a session ID usable as a folder name
+func safeName(id string) string {
+	return strings.NewReplacer("/", "_", ":", "_", string(filepath.Separator), "_").Replace(id)
+}
+
+// move renames src to dst, copying when they are on different filesystems
+func move(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := copyTree(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyTree copies a file or directory, keeping modification times so the
+// cache treats restored sessions as unchanged
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		if err := out.Close(); err != nil {
+			return err
+		}
+		return os.Chtimes(target, info.ModTime(), info.ModTime())
+	})
+}
@@ -0,0 +1,166 @@
+package trash
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupSession creates a session file plus a directory of subagent files
+func setupSession(t *testing.T) (string, []string) {
+	t.Helper()
+	data := t.TempDir()
+
+	file := filepath.Join(data, "proj", "abc.jsonl")
+	sub := filepath.Join(data, "proj", "abc", "subagents", "agent-1.jsonl")
+	os.MkdirAll(filepath.Dir(sub), 0755)
+	os.WriteFile(file, []byte(`{"type":"user"}`), 0644)
+	os.WriteFile(sub, []byte(`{"type":"assistant"}`), 0644)
+
+	return data, []string{file, filepath.Join(data, "proj", "abc")}
+}
+
+func TestMoveAndRestore(t *testing.T) {
+	root := t.TempDir()
+	data, files := setupSession(t)
+
+	old := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	os.Chtimes(files[0], old, old)
+
+	e, err := Move(root, KindTrash, "claude", "abc", files)
+	if err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	if e.Kind != KindTrash || len(e.Files) != 2 {
+		t.Errorf("Move() = %+v", e)
+	}
+	for _, f := range files {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Errorf("%s should have been moved out", f)
+		}
+	}
+
+	entries, err := List(root)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].SessionID != "abc" || entries[0].Provider != "claude" {
+		t.Fatalf("List() = %+v, want the trashed session", entries)
+	}
+
+	if _, err := Restore(root, "abc"); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	info, err := os.Stat(files[0])
+	if err != nil {
+		t.Fatalf("session file not restored: %v", err)
+	}
+	if !info.ModTime().Equal(old) {
+		t.Errorf("restored mtime = %v, want %v", info.ModTime(), old)
+	}
+	if _, err := os.Stat(filepath.Join(data, "proj", "abc", "subagents", "agent-1.jsonl")); err != nil {
+		t.Errorf("subagent file not restored: %v", err)
+	}
+
+	entries, _ = List(root)
+	if len(entries) != 0 {
+		t.Errorf("List() after restore = %+v, want empty", entries)
+	}
+}
+
+func TestMove_RollsBackOnFailure(t *testing.T) {
+	root := t.TempDir()
+	_, files := setupSession(t)
+	files = append(files, filepath.Join(t.TempDir(), "missing.json"))
+
+	if _, err := Move(root, KindArchive, "claude", "abc", files); err == nil {
+		t.Fatal("Move() should fail when a file is missing")
+	}
+	for _, f := range files[:2] {
+		if _, err := os.Stat(f); err != nil {
+			t.Errorf("%s should have been put back: %v", f, err)
+		}
+	}
+	if entries, _ := List(root); len(entries) != 0 {
+		t.Errorf("List() = %+v, want no leftover entry", entries)
+	}
+}
+
+func TestRestore_RefusesOverwrite(t *testing.T) {
+	root := t.TempDir()
+	_, files := setupSession(t)
+
+	if _, err := Move(root, KindArchive, "claude", "abc", files[:1]); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	os.WriteFile(files[0], []byte("new"), 0644)
+
+	_, err := Restore(root, "abc")
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Restore() error = %v, want refusal to overwrite", err)
+	}
+}
+
+func TestRestore_RetriesAfterPartialFailure(t *testing.T) {
+	root := t.TempDir()
+	data := t.TempDir()
+	first := filepath.Join(data, "a", "abc.jsonl")
+	second := filepath.Join(data, "b", "abc.json")
+	for _, f := range []string{first, second} {
+		os.MkdirAll(filepath.Dir(f), 0755)
+		os.WriteFile(f, []byte("{}"), 0644)
+	}
+	if _, err := Move(root, KindTrash, "claude", "abc", []string{first, second}); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+
+	// A file where the second one's directory was stops the restore after
+	// the first file is back
+	os.RemoveAll(filepath.Join(data, "b"))
+	os.WriteFile(filepath.Join(data, "b"), nil, 0644)
+	if _, err := Restore(root, "abc"); err == nil {
+		t.Fatal("Restore() should fail when a directory can't be created")
+	}
+	if _, err := os.Stat(first); err != nil {
+		t.Fatalf("first file not restored: %v", err)
+	}
+
+	os.Remove(filepath.Join(data, "b"))
+	e, err := Restore(root, "abc")
+	if err != nil {
+		t.Fatalf("Restore() retry error = %v", err)
+	}
+	if len(e.Files) != 1 || e.Files[0].Original != second {
+		t.Errorf("Restore() = %+v, want only the file left to restore", e)
+	}
+	for _, f := range []string{first, second} {
+		if _, err := os.Stat(f); err != nil {
+			t.Errorf("%s not restored: %v", f, err)
+		}
+	}
+	if entries, _ := List(root); len(entries) != 0 {
+		t.Errorf("List() after restore = %+v, want empty", entries)
+	}
+}
+
+func TestRestore_NotFound(t *testing.T) {
+	if _, err := Restore(t.TempDir(), "nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Restore() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCopyTree(t *testing.T) {
+	_, files := setupSession(t)
+	dst := filepath.Join(t.TempDir(), "copy")
+
+	if err := copyTree(files[1], dst); err != nil {
+		t.Fatalf("copyTree() error = %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dst, "subagents", "agent-1.jsonl"))
+	if err != nil || string(got) != `{"type":"assistant"}` {
+		t.Errorf("copied file = %q, %v", got, err)
+	}
+}
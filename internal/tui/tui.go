@@ -2,6 +2,7 @@ package tui
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"math/rand"
 	"net/http"
@@ -12,7 +13,10 @@ import (
 	"time"
 
 	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+	"github.com/Julian194/claude-sessions-tui/internal/adapters/multi"
 	"github.com/Julian194/claude-sessions-tui/internal/cache"
+	"github.com/Julian194/claude-sessions-tui/internal/preview"
+	"github.com/Julian194/claude-sessions-tui/internal/search"
 )
 
 // Action represents the user's selected action
@@ -21,6 +25,7 @@ type Action int
 const (
 	ActionResume Action = iota
 	ActionBranch
+	ActionBranchAt
 	ActionExport
 	ActionCopyMD
 	ActionCancel
@@ -40,12 +45,16 @@ type Config struct {
 	BinPath  string
 }
 
-// Run launches the fzf TUI and returns the user's selection
-func Run(cfg Config) (*Result, error) {
+// Run launches the fzf TUI and returns the user's selection. The
+// background cache rebuild is cancelled as soon as fzf exits.
+func Run(ctx context.Context, cfg Config) (*Result, error) {
 	cacheFile := filepath.Join(cfg.CacheDir, "sessions-cache.tsv")
 
 	// Ensure cache file exists
 	if _, err := os.Stat(cacheFile); os.IsNotExist(err) {
+		if err := os.MkdirAll(cfg.CacheDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create cache: %w", err)
+		}
 		f, err := os.Create(cacheFile)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create cache: %w", err)
@@ -60,7 +69,7 @@ func Run(cfg Config) (*Result, error) {
 	rand.Seed(time.Now().UnixNano())
 	port := 10000 + rand.Intn(50000)
 
-	keybinds := "enter=resume  ctrl-o=export  ctrl-y=copy-md  ctrl-b=branch  ctrl-r=refresh  ctrl-a=activity"
+	keybinds, expect := keybindings(cfg.Adapter)
 	sessionCount := len(entries)
 	header := fmt.Sprintf("[%d sessions] %s", sessionCount, keybinds)
 	loadingHeader := fmt.Sprintf("[Loading...] %s", keybinds)
@@ -85,9 +94,15 @@ func Run(cfg Config) (*Result, error) {
 	exportCmd := fmt.Sprintf("%s export {1} && %s &", cfg.BinPath, resetCmd)
 	copyMDCmd := fmt.Sprintf("%s copy-md {1} && %s", cfg.BinPath, resetCmd)
 
+	// Multi-provider mode shows which tool each session belongs to
+	withNth := "--with-nth=2,3,4"
+	if _, ok := cfg.Adapter.(*multi.Adapter); ok {
+		withNth = "--with-nth=2,8,3,4"
+	}
+
 	args := []string{
 		"--delimiter=\t",
-		"--with-nth=2,3,4",
+		withNth,
 		"--ansi",
 		"--no-sort",
 		"--no-separator",
@@ -103,23 +118,60 @@ func Run(cfg Config) (*Result, error) {
 		fmt.Sprintf("--bind=ctrl-o:execute-silent(%s)+change-header(%s)", exportCmd, exportedHeader),
 		fmt.Sprintf("--bind=ctrl-y:execute-silent(%s)+change-header(%s)", copyMDCmd, copiedHeader),
 		fmt.Sprintf("--bind=ctrl-a:transform:%s", activityToggle),
-		"--expect=enter,ctrl-b",
+		"--expect=" + strings.Join(expect, ","),
+	}
+	if adapters.Supports(cfg.Adapter, "", adapters.CanDelete) {
+		// execute hands the terminal to the command so it can ask first
+		args = append(args,
+			fmt.Sprintf("--bind=ctrl-x:execute(%s delete --confirm {1})+reload(%s)", cfg.BinPath, rebuildWithCount),
+			fmt.Sprintf("--bind=alt-x:execute(%s archive --confirm {1})+reload(%s)", cfg.BinPath, rebuildWithCount),
+		)
 	}
 
+	if adapters.Supports(cfg.Adapter, "", adapters.CanRename) {
+		args = append(args, fmt.Sprintf("--bind=alt-r:execute(%s rename {1})+reload(%s)", cfg.BinPath, rebuildWithCount))
+	}
+
+	// The file picker and search prompt reload the list themselves
+	// through the listen port
+	args = append(args, fmt.Sprintf("--bind=alt-f:execute(%s pick-file %d {1})", cfg.BinPath, port))
+	args = append(args, fmt.Sprintf("--bind=alt-s:execute(%s search-prompt %d)", cfg.BinPath, port))
+
 	cmd := exec.Command("fzf", args...)
 	cmd.Stderr = os.Stderr
 
 	formatted := formatForDisplay(entries)
 	cmd.Stdin = strings.NewReader(strings.Join(formatted, "\n"))
 
+	buildCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	// Background: incremental rebuild and reload fzf
 	go func() {
-		time.Sleep(100 * time.Millisecond) // Brief delay for fzf to start listening
+		select {
+		case <-time.After(100 * time.Millisecond): // Brief delay for fzf to start listening
+		case <-buildCtx.Done():
+			return
+		}
 
 		reloadURL := fmt.Sprintf("http://localhost:%d", port)
 
+		// Show extraction progress, throttled so fzf isn't flooded
+		var lastReport time.Time
+		progress := func(done, total int) {
+			if total == 0 || time.Since(lastReport) < progressInterval {
+				return
+			}
+			lastReport = time.Now()
+			post(buildCtx, reloadURL, fmt.Sprintf("change-header([Indexing %d/%d] %s)", done, total, keybinds))
+		}
+
 		// Always do incremental rebuild (fast - only processes new/modified files)
-		newEntries, err := cache.BuildIncremental(cfg.Adapter, cacheFile, entries)
+		started := time.Now()
+		newEntries, err := cache.BuildIncremental(buildCtx, cfg.Adapter, cacheFile, entries, progress)
+		if buildCtx.Err() != nil {
+			return // fzf already exited
+		}
 		if err == nil {
 			newHeader := fmt.Sprintf("[%d sessions] %s", len(newEntries), keybinds)
 
@@ -136,19 +188,25 @@ func Run(cfg Config) (*Result, error) {
 			}
 
 			if changed {
-				cache.Write(cacheFile, newEntries)
-				body := fmt.Sprintf("reload(%s)+change-header(%s)", rebuildCmd, newHeader)
-				http.Post(reloadURL, "text/plain", strings.NewReader(body))
+				// Renames and deletes made while building take precedence
+				if merged, err := cache.Commit(cacheFile, entries, newEntries, started); err == nil {
+					newEntries = merged
+				}
+				post(buildCtx, reloadURL, fmt.Sprintf("reload(%s)+change-header(%s)", rebuildCmd, newHeader))
 			} else {
-				http.Post(reloadURL, "text/plain", strings.NewReader(fmt.Sprintf("change-header(%s)", newHeader)))
+				post(buildCtx, reloadURL, fmt.Sprintf("change-header(%s)", newHeader))
 			}
+
+			// Keep the reverse file index in step with the cache
+			cache.UpdateFileIndex(buildCtx, cfg.Adapter, filepath.Join(cfg.CacheDir, cache.FileIndexName), newEntries)
 		} else {
-			http.Post(reloadURL, "text/plain", strings.NewReader(fmt.Sprintf("change-header(%s)", header)))
+			post(buildCtx, reloadURL, fmt.Sprintf("change-header(%s)", header))
 		}
 	}()
 
 	// Run fzf
 	output, err := cmd.Output()
+	cancel()
 	if err != nil {
 		// fzf exits with 130 on Ctrl-C, 1 on no match
 		if exitErr, ok := err.(*exec.ExitError); ok {
@@ -162,6 +220,46 @@ func Run(cfg Config) (*Result, error) {
 	return parseResult(output, cfg.Adapter)
 }
 
+// progressInterval limits how often indexing progress is sent to fzf
+const progressInterval = 250 * time.Millisecond
+
+// post sends an action to fzf's listen port
+func post(ctx context.Context, url, action string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(action))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	if resp, err := http.DefaultClient.Do(req); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// keybindings returns the header help text and the keys fzf should report
+// back, leaving out actions the adapter doesn't support
+func keybindings(adapter adapters.Adapter) (string, []string) {
+	help := []string{"enter=resume", "ctrl-o=export", "ctrl-y=copy-md"}
+	expect := []string{"enter"}
+
+	if adapters.Supports(adapter, "", adapters.CanBranch) {
+		help = append(help, "ctrl-b=branch")
+		expect = append(expect, "ctrl-b")
+	}
+	if adapters.Supports(adapter, "", adapters.CanBranchAt) {
+		help = append(help, "alt-b=branch-at")
+		expect = append(expect, "alt-b")
+	}
+	if adapters.Supports(adapter, "", adapters.CanDelete) {
+		help = append(help, "ctrl-x=delete", "alt-x=archive")
+	}
+	if adapters.Supports(adapter, "", adapters.CanRename) {
+		help = append(help, "alt-r=rename")
+	}
+
+	help = append(help, "alt-f=by-file", "alt-s=search", "ctrl-r=refresh", "ctrl-a=activity")
+	return strings.Join(help, "  "), expect
+}
+
 // parseResult extracts the action and session from fzf output
 func parseResult(output []byte, adapter adapters.Adapter) (*Result, error) {
 	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
@@ -200,6 +298,8 @@ func parseResult(output []byte, adapter adapters.Adapter) (*Result, error) {
 	switch key {
 	case "ctrl-b":
 		result.Action = ActionBranch
+	case "alt-b":
+		result.Action = ActionBranchAt
 	case "ctrl-o":
 		result.Action = ActionExport
 	case "ctrl-y":
@@ -211,22 +311,97 @@ func parseResult(output []byte, adapter adapters.Adapter) (*Result, error) {
 	return result, nil
 }
 
+// PickTurn lets the user choose a message to branch at and returns its
+// index, or "" if the picker was cancelled
+func PickTurn(ctx context.Context, adapter adapters.Adapter, sid string) (string, error) {
+	messages, err := adapter.ExportMessages(ctx, sid)
+	if err != nil {
+		return "", err
+	}
+	turns := FormatTurns(messages)
+	if len(turns) == 0 {
+		return "", adapters.ErrMessageNotFound
+	}
+
+	// Newest first, so the latest turn sits next to the prompt
+	lines := make([]string, len(turns))
+	for i, t := range turns {
+		lines[len(turns)-1-i] = t
+	}
+
+	cmd := exec.CommandContext(ctx, "fzf",
+		"--delimiter=\t",
+		"--with-nth=2",
+		"--no-sort",
+		"--border=rounded",
+		"--prompt=branch at> ",
+		"--header=Keeps the chosen message and everything before it",
+	)
+	cmd.Stdin = strings.NewReader(strings.Join(lines, "\n"))
+	cmd.Stderr = os.Stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if exitErr.ExitCode() == 130 || exitErr.ExitCode() == 1 {
+				return "", nil
+			}
+		}
+		return "", fmt.Errorf("fzf failed: %w", err)
+	}
+	return strings.SplitN(strings.TrimSpace(string(output)), "\t", 2)[0], nil
+}
+
+// FormatTurns returns one line per message: its index, a tab, and a
+// one-line description with the role, time and start of the text
+func FormatTurns(messages []adapters.Message) []string {
+	lines := make([]string, len(messages))
+	for i, m := range messages {
+		text := strings.Join(strings.Fields(m.Content), " ")
+		if text == "" {
+			var tools []string
+			for _, tc := range m.ToolCalls {
+				tools = append(tools, tc.Name)
+			}
+			switch {
+			case len(tools) > 0:
+				text = "[tools: " + strings.Join(tools, ", ") + "]"
+			case len(m.ToolResults) > 0:
+				text = "[tool results]"
+			}
+		}
+		if r := []rune(text); len(r) > 100 {
+			text = string(r[:97]) + "..."
+		}
+
+		when := ""
+		if m.Timestamp > 0 {
+			when = time.Unix(m.Timestamp, 0).Format("01-02 15:04")
+		}
+		lines[i] = fmt.Sprintf("%d\t#%-4d %-9s %-11s %s", i, i, m.Role, when, text)
+	}
+	return lines
+}
+
 // Rebuild rebuilds the cache and outputs formatted data for fzf reload
-func Rebuild(cfg Config, mainOnly bool) error {
+func Rebuild(ctx context.Context, cfg Config, mainOnly bool) error {
 	cacheFile := filepath.Join(cfg.CacheDir, "sessions-cache.tsv")
 
 	// Read existing cache for incremental build
 	existing, _ := cache.Read(cacheFile)
 
 	// Use incremental build instead of full rebuild
-	entries, err := cache.BuildIncremental(cfg.Adapter, cacheFile, existing)
+	started := time.Now()
+	built, err := cache.BuildIncremental(ctx, cfg.Adapter, cacheFile, existing, nil)
 	if err != nil {
 		return err
 	}
 
-	if err := cache.Write(cacheFile, entries); err != nil {
+	entries, err := cache.Commit(cacheFile, existing, built, started)
+	if err != nil {
 		return err
 	}
+	all := entries
 
 	if mainOnly {
 		var filtered []cache.Entry
@@ -244,9 +419,220 @@ func Rebuild(cfg Config, mainOnly bool) error {
 		fmt.Println(line)
 	}
 
+	// fzf already shows the list while the file index catches up
+	_, err = cache.UpdateFileIndex(ctx, cfg.Adapter, filepath.Join(cfg.CacheDir, cache.FileIndexName), all)
+	return err
+}
+
+// FileSessions outputs the cached sessions that touched path, formatted
+// for fzf reload
+func FileSessions(cfg Config, path string) error {
+	entries, err := cache.Read(filepath.Join(cfg.CacheDir, "sessions-cache.tsv"))
+	if err != nil {
+		return err
+	}
+	refs, err := cache.ReadFileIndex(filepath.Join(cfg.CacheDir, cache.FileIndexName))
+	if err != nil {
+		return err
+	}
+
+	touched := make(map[string]bool)
+	for _, r := range cache.FindFile(refs, path) {
+		touched[r.SessionID] = true
+	}
+	var matching []cache.Entry
+	for _, e := range entries {
+		if touched[e.SessionID] {
+			matching = append(matching, e)
+		}
+	}
+
+	for _, line := range formatForDisplay(matching) {
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// PickFile lets the user choose one of the indexed files under the
+// session's working directory, then tells the fzf instance listening on
+// port to show only the sessions that touched it
+func PickFile(ctx context.Context, cfg Config, port, sid string) error {
+	if sid == "---HEADER---" {
+		return nil
+	}
+	workDir := ""
+	if info, err := cfg.Adapter.GetSessionInfo(sid); err == nil {
+		workDir = info.WorkDir
+	}
+	refs, err := cache.ReadFileIndex(filepath.Join(cfg.CacheDir, cache.FileIndexName))
+	if err != nil {
+		return err
+	}
+
+	files := FileChoices(refs, sid, workDir)
+	if len(files) == 0 {
+		return nil
+	}
+	lines := make([]string, len(files))
+	for i, f := range files {
+		lines[i] = f + "\t" + RelPath(f, workDir)
+	}
+
+	cmd := exec.CommandContext(ctx, "fzf",
+		"--delimiter=\t",
+		"--with-nth=2",
+		"--no-sort",
+		"--border=rounded",
+		"--prompt=file> ",
+		"--header=Show the sessions that touched this file",
+	)
+	cmd.Stdin = strings.NewReader(strings.Join(lines, "\n"))
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if exitErr.ExitCode() == 130 || exitErr.ExitCode() == 1 {
+				return nil
+			}
+		}
+		return fmt.Errorf("fzf failed: %w", err)
+	}
+	path := strings.SplitN(strings.TrimSpace(string(output)), "\t", 2)[0]
+
+	// A closing parenthesis would end change-header early
+	name := strings.ReplaceAll(RelPath(path, workDir), ")", "]")
+	header := fmt.Sprintf("[file: %s] ctrl-r=all sessions", name)
+	reload := fmt.Sprintf("%s file --fzf %s", cfg.BinPath, shellQuote(path))
+	// The colon form takes the rest of the action, so the path needs no escaping
+	post(ctx, "http://localhost:"+port, fmt.Sprintf("change-header(%s)+reload:%s", header, reload))
 	return nil
 }
 
+// Match is a cached session found by a full-text search
+type Match struct {
+	Entry   cache.Entry
+	Score   float64
+	Snippet search.Snippet
+	Found   bool // Snippet is set
+}
+
+// Search runs query against the full-text index, updating it first, and
+// returns up to limit cached sessions with snippets, best first
+func Search(ctx context.Context, cfg Config, query search.Query, limit int) ([]Match, error) {
+	cacheFile := filepath.Join(cfg.CacheDir, "sessions-cache.tsv")
+	entries, err := cache.Read(cacheFile)
+	if err != nil {
+		entries, err = cache.BuildFrom(ctx, cfg.Adapter)
+		if err != nil {
+			return nil, err
+		}
+	}
+	byID := make(map[string]cache.Entry, len(entries))
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		byID[e.SessionID] = e
+		ids[i] = e.SessionID
+	}
+
+	ix, err := search.Update(ctx, cfg.Adapter, filepath.Join(cfg.CacheDir, search.IndexName), ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Match
+	for _, r := range ix.Search(query) {
+		if len(matches) == limit {
+			break
+		}
+		m := Match{Entry: byID[r.SessionID], Score: r.Score}
+		if messages, err := cfg.Adapter.ExportMessages(ctx, r.SessionID); err == nil {
+			m.Snippet, m.Found = search.FindSnippet(messages, query)
+		}
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
+
+// SearchSessions outputs the sessions matching query for fzf reload, best
+// first and with the matching text after their summary
+func SearchSessions(ctx context.Context, cfg Config, query string, limit int) error {
+	q := search.ParseQuery(query)
+	matches, err := Search(ctx, cfg, q, limit)
+	if err != nil {
+		return err
+	}
+
+	dim := "\033[2m"
+	bold := "\033[1;33m"
+	nc := "\033[0m"
+	for _, m := range matches {
+		e := m.Entry
+		if m.Found {
+			e.Summary += "  " + dim + search.Highlight(m.Snippet.Text, q, bold, nc+dim) + nc
+		}
+		fmt.Println(formatEntry(e))
+	}
+	return nil
+}
+
+// SearchPrompt asks for a query, then tells the fzf instance listening on
+// port to show the sessions matching it
+func SearchPrompt(ctx context.Context, cfg Config, port string) error {
+	fmt.Print("Search: ")
+	query, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	query = strings.Join(strings.Fields(query), " ")
+	if search.ParseQuery(query).Empty() {
+		return nil
+	}
+
+	// A closing parenthesis would end change-header early
+	header := fmt.Sprintf("[search: %s] ctrl-r=all sessions", strings.ReplaceAll(query, ")", "]"))
+	reload := fmt.Sprintf("%s search --fzf %s", cfg.BinPath, shellQuote(query))
+	post(ctx, "http://localhost:"+port, fmt.Sprintf("change-header(%s)+reload:%s", header, reload))
+	return nil
+}
+
+// FileChoices returns the indexed files under workDir, most recently
+// touched first. Without a working directory it offers the session's own
+// files.
+func FileChoices(refs []cache.FileRef, sid, workDir string) []string {
+	var candidates []cache.FileRef
+	if workDir != "" {
+		candidates = cache.FindFile(refs, workDir)
+	} else {
+		for _, r := range refs {
+			if r.SessionID == sid && r.Path != "" {
+				candidates = append(candidates, r)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, r := range candidates {
+		if !seen[r.Path] {
+			seen[r.Path] = true
+			files = append(files, r.Path)
+		}
+	}
+	return files
+}
+
+// RelPath shortens path to be relative to workDir when it lies inside it
+func RelPath(path, workDir string) string {
+	if workDir != "" {
+		if r, err := filepath.Rel(workDir, path); err == nil && !strings.HasPrefix(r, "..") {
+			return r
+		}
+	}
+	return path
+}
+
+// shellQuote quotes s for sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 // formatForDisplay formats cache entries with date headers and child indicators
 func formatForDisplay(entries []cache.Entry) []string {
 	if len(entries) == 0 {
@@ -254,7 +640,6 @@ func formatForDisplay(entries []cache.Entry) []string {
 	}
 
 	cyan := "\033[0;36m"
-	dim := "\033[2m"
 	nc := "\033[0m"
 
 	var result []string
@@ -266,43 +651,19 @@ func formatForDisplay(entries []cache.Entry) []string {
 		if entryDate != currentDate {
 			if currentDate != "" {
 				formatted := formatDateHeader(currentDate)
-				header := fmt.Sprintf("---HEADER---\t%s%s ─────────────────────────%s\t\t\t0\t-\t-",
+				header := fmt.Sprintf("---HEADER---\t%s%s ─────────────────────────%s\t\t\t0\t-\t-\t",
 					cyan, formatted, nc)
 				result = append(result, header)
 			}
 			currentDate = entryDate
 		}
 
-		isChild := e.ParentSID != "" && e.ParentSID != "-"
-
-		var line string
-		if isChild {
-			line = fmt.Sprintf("%s\t%s↳ %s%s\t%s\t%s\t%d\t%s\t%s",
-				e.SessionID,
-				dim, e.Date.Format("15:04"), nc,
-				e.Project,
-				e.Summary,
-				e.Date.Unix(),
-				e.ParentSID,
-				entryDate,
-			)
-		} else {
-			line = fmt.Sprintf("%s\t%s\t%s\t%s\t%d\t%s\t%s",
-				e.SessionID,
-				e.Date.Format("15:04"),
-				e.Project,
-				e.Summary,
-				e.Date.Unix(),
-				e.ParentSID,
-				entryDate,
-			)
-		}
-		result = append(result, line)
+		result = append(result, formatEntry(e))
 	}
 
 	if currentDate != "" {
 		formatted := formatDateHeader(currentDate)
-		header := fmt.Sprintf("---HEADER---\t%s%s ─────────────────────────%s\t\t\t0\t-\t-",
+		header := fmt.Sprintf("---HEADER---\t%s%s ─────────────────────────%s\t\t\t0\t-\t-\t",
 			cyan, formatted, nc)
 		result = append(result, header)
 	}
@@ -310,6 +671,49 @@ func formatForDisplay(entries []cache.Entry) []string {
 	return result
 }
 
+// formatEntry formats one session as an fzf list line
+func formatEntry(e cache.Entry) string {
+	dim := "\033[2m"
+	nc := "\033[0m"
+	entryDate := e.Date.Format("2006-01-02")
+	isChild := e.ParentSID != "" && e.ParentSID != "-"
+	// Titles can be whole prompts; a newline or tab would break the line
+	// into rows or shift its columns
+	summary := strings.Join(strings.Fields(e.Summary), " ")
+
+	// Column 8: provider of namespaced (multi-provider) session IDs
+	providerCol := ""
+	if provider, _, ok := multi.SplitID(e.SessionID); ok {
+		providerCol = dim + provider + nc
+	}
+
+	var line string
+	if isChild {
+		line = fmt.Sprintf("%s\t%s↳ %s%s\t%s\t%s\t%d\t%s\t%s\t%s",
+			e.SessionID,
+			dim, e.Date.Format("15:04"), nc,
+			e.Project,
+			summary,
+			e.Date.Unix(),
+			e.ParentSID,
+			entryDate,
+			providerCol,
+		)
+	} else {
+		line = fmt.Sprintf("%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s",
+			e.SessionID,
+			e.Date.Format("15:04"),
+			e.Project,
+			summary,
+			e.Date.Unix(),
+			e.ParentSID,
+			entryDate,
+			providerCol,
+		)
+	}
+	return line
+}
+
 func formatDateHeader(dateStr string) string {
 	t, err := time.Parse("2006-01-02", dateStr)
 	if err != nil {
@@ -319,7 +723,7 @@ func formatDateHeader(dateStr string) string {
 }
 
 // Preview outputs the preview pane content for a session
-func Preview(adapter adapters.Adapter, sid string) error {
+func Preview(ctx context.Context, adapter adapters.Adapter, sid string) error {
 	info, err := adapter.GetSessionInfo(sid)
 	if err != nil {
 		return err
@@ -332,14 +736,14 @@ func Preview(adapter adapters.Adapter, sid string) error {
 	if info.Branch != "" {
 		fmt.Printf("🌿 %s\n", info.Branch)
 	}
-	models, _ := adapter.GetModels(sid)
+	models, _ := adapters.GetModels(adapter, sid)
 	if len(models) > 0 {
 		fmt.Printf("🤖 %s\n", strings.Join(models, ", "))
 	}
 	fmt.Println()
 
 	// Summaries
-	summaries, _ := adapter.GetSummaries(sid)
+	summaries, _ := adapters.GetSummaries(adapter, sid)
 	if len(summaries) > 0 {
 		fmt.Println("━━━ Topics ━━━")
 		for _, s := range summaries {
@@ -349,7 +753,7 @@ func Preview(adapter adapters.Adapter, sid string) error {
 	}
 
 	// Slash commands
-	cmds, _ := adapter.GetSlashCommands(sid)
+	cmds, _ := adapters.GetSlashCommands(adapter, sid)
 	if len(cmds) > 0 {
 		fmt.Println("━━━ Slash Commands ━━━")
 		for _, cmd := range cmds {
@@ -358,46 +762,58 @@ func Preview(adapter adapters.Adapter, sid string) error {
 		fmt.Println()
 	}
 
-	// Files
-	files, _ := adapter.GetFilesTouched(sid)
-	if len(files) > 0 {
-		fmt.Println("━━━ Files ━━━")
-		shown := files
-		if len(shown) > 10 {
-			shown = shown[:10]
-		}
-		for _, f := range shown {
-			rel := f
-			if info.WorkDir != "" {
-				if r, err := filepath.Rel(info.WorkDir, f); err == nil && !strings.HasPrefix(r, "..") {
-					rel = r
-				}
+	// Files grouped by what the session did to them
+	activity, _ := adapters.GetFileActivity(ctx, adapter, sid)
+	fmt.Print(preview.Files(activity, info.WorkDir))
+
+	// Forks inside the file, e.g. after rewinding to an earlier message
+	forks, _ := adapters.GetForks(adapter, sid)
+	if len(forks) > 0 {
+		fmt.Printf("━━━ Forks (%d) ━━━\n", len(forks))
+		for _, f := range forks {
+			if f.Active {
+				fmt.Printf("▶ %d messages (shown)\n", f.Messages)
+			} else {
+				fmt.Printf("• %d messages, diverges at #%d: %s\n", f.Messages, f.Shared, f.First)
 			}
-			fmt.Printf("• %s\n", rel)
-		}
-		if len(files) > 10 {
-			fmt.Printf("  ... and %d more\n", len(files)-10)
 		}
 		fmt.Println()
 	}
 
 	// Stats (use claude-sessions-stats style output)
-	stats, err := adapter.GetStats(sid)
+	stats, err := adapters.GetStats(ctx, adapter, sid)
 	if err == nil {
 		fmt.Println("━━━ Stats ━━━")
 		fmt.Printf("Messages: %d user, %d assistant\n", stats.UserMessages, stats.AssistantMessages)
 		fmt.Printf("Tokens: %d in, %d out", stats.InputTokens, stats.OutputTokens)
+		if stats.ReasoningTokens > 0 {
+			fmt.Printf(" (%d reasoning)", stats.ReasoningTokens)
+		}
 		if stats.CacheRead > 0 || stats.CacheWrite > 0 {
 			fmt.Printf(", %d cache", stats.CacheRead+stats.CacheWrite)
 		}
 		fmt.Println()
 		fmt.Printf("Cost: $%.4f\n", stats.Cost)
+		if len(stats.Subagents) > 0 {
+			fmt.Printf("Subagents: %d, $%.4f including them\n", len(stats.Subagents), stats.Inclusive.Cost)
+			for i, sub := range stats.Subagents {
+				if i == 5 {
+					fmt.Printf("  ... and %d more\n", len(stats.Subagents)-5)
+					break
+				}
+				name := sub.Description
+				if name == "" {
+					name = sub.ID
+				}
+				fmt.Printf("  $%.4f  %s\n", sub.Cost, name)
+			}
+		}
 		fmt.Println()
 	}
 
 	// First message (if no summaries)
 	if len(summaries) == 0 {
-		msg, _ := adapter.GetFirstMessage(sid)
+		msg, _ := adapters.GetFirstMessage(ctx, adapter, sid)
 		if msg != "" {
 			fmt.Println("━━━ First Message ━━━")
 			fmt.Println(msg)
@@ -1,10 +1,15 @@
 package tui
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/Julian194/claude-sessions-tui/internal/adapters"
+	"github.com/Julian194/claude-sessions-tui/internal/adapters/claude"
+	"github.com/Julian194/claude-sessions-tui/internal/adapters/codex"
+	"github.com/Julian194/claude-sessions-tui/internal/adapters/multi"
 	"github.com/Julian194/claude-sessions-tui/internal/cache"
 )
 
@@ -161,3 +166,131 @@ func TestFormatForDisplay_PreservesOrder(t *testing.T) {
 		t.Errorf("sessions not in expected order: %v", indices)
 	}
 }
+
+func TestFormatEntry_MultiLineSummary(t *testing.T) {
+	for _, parent := range []string{"", "parent"} {
+		line := formatEntry(cache.Entry{
+			SessionID: "s1",
+			Date:      time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC),
+			Project:   "p",
+			Summary:   "Fix the build\n\tthen run\r\n the tests",
+			ParentSID: parent,
+		})
+		if strings.ContainsAny(line, "\n\r") {
+			t.Errorf("formatEntry() = %q, want a single line", line)
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 8 || fields[3] != "Fix the build then run the tests" {
+			t.Errorf("formatEntry() fields = %q, want the summary collapsed in column 4", fields)
+		}
+	}
+}
+
+func TestFormatForDisplay_ProviderColumn(t *testing.T) {
+	entries := []cache.Entry{
+		{SessionID: "claude:abc", Date: time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC), Project: "p", Summary: "Claude"},
+		{SessionID: "plain", Date: time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC), Project: "p", Summary: "Plain"},
+	}
+
+	result := formatForDisplay(entries)
+
+	for _, line := range result {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 8 {
+			t.Fatalf("expected 8 columns, got %d: %q", len(fields), line)
+		}
+		switch fields[0] {
+		case "claude:abc":
+			if !strings.Contains(fields[7], "claude") {
+				t.Errorf("provider column = %q, want claude", fields[7])
+			}
+		case "plain":
+			if fields[7] != "" {
+				t.Errorf("provider column = %q, want empty", fields[7])
+			}
+		}
+	}
+}
+
+func TestKeybindings_HidesUnsupportedActions(t *testing.T) {
+	help, expect := keybindings(claude.New("/nonexistent"))
+	if !strings.Contains(help, "ctrl-b=branch") {
+		t.Errorf("claude help %q should offer branching", help)
+	}
+	if strings.Join(expect, ",") != "enter,ctrl-b,alt-b" {
+		t.Errorf("claude expect = %v, want [enter ctrl-b alt-b]", expect)
+	}
+	if !strings.Contains(help, "ctrl-x=delete") {
+		t.Errorf("claude help %q should offer deleting", help)
+	}
+	if !strings.Contains(help, "alt-r=rename") {
+		t.Errorf("claude help %q should offer renaming", help)
+	}
+
+	help, expect = keybindings(codex.New("/nonexistent"))
+	if strings.Contains(help, "ctrl-b") {
+		t.Errorf("codex help %q should not offer branching", help)
+	}
+	if strings.Contains(help, "ctrl-x") {
+		t.Errorf("codex help %q should not offer deleting", help)
+	}
+	if strings.Contains(help, "alt-r") {
+		t.Errorf("codex help %q should not offer renaming", help)
+	}
+	if strings.Join(expect, ",") != "enter" {
+		t.Errorf("codex expect = %v, want [enter]", expect)
+	}
+
+	// The combined provider offers what one of its members can do
+	help, _ = keybindings(multi.New(codex.New("/nonexistent")))
+	if strings.Contains(help, "ctrl-b") || strings.Contains(help, "ctrl-x") || strings.Contains(help, "alt-r") {
+		t.Errorf("multi over codex help %q should offer no branching, deleting or renaming", help)
+	}
+	help, _ = keybindings(multi.New(codex.New("/nonexistent"), claude.New("/nonexistent")))
+	if !strings.Contains(help, "ctrl-b=branch") || !strings.Contains(help, "alt-r=rename") {
+		t.Errorf("multi over codex and claude help %q should offer branching and renaming", help)
+	}
+}
+
+func TestFormatTurns(t *testing.T) {
+	messages := []adapters.Message{
+		{Role: "user", Content: "Fix the\nlogin   bug", Timestamp: 1700000000},
+		{Role: "assistant", ToolCalls: []adapters.ToolCall{{Name: "Read"}, {Name: "Edit"}}},
+		{Role: "user", ToolResults: []adapters.ToolResult{{ToolUseID: "t1"}}},
+	}
+	lines := FormatTurns(messages)
+	if len(lines) != 3 {
+		t.Fatalf("FormatTurns() returned %d lines, want 3", len(lines))
+	}
+	for i, want := range []string{"Fix the login bug", "[tools: Read, Edit]", "[tool results]"} {
+		fields := strings.SplitN(lines[i], "\t", 2)
+		if fields[0] != fmt.Sprint(i) {
+			t.Errorf("line %d index = %q, want %d", i, fields[0], i)
+		}
+		if !strings.HasSuffix(fields[1], want) {
+			t.Errorf("line %d = %q, want it to end with %q", i, fields[1], want)
+		}
+	}
+}
+
+func TestFileChoices(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 9, 0, 0, 0, time.UTC) }
+	refs := []cache.FileRef{
+		{Path: "/w/a.go", SessionID: "s1", Date: day(1)},
+		{Path: "/w/a.go", SessionID: "s2", Date: day(3)},
+		{Path: "/w/b.go", SessionID: "s1", Date: day(1)},
+		{Path: "/other/c.go", SessionID: "s2", Date: day(3)},
+		{SessionID: "s3", Date: day(4)},
+	}
+
+	got := FileChoices(refs, "s1", "/w")
+	if strings.Join(got, ",") != "/w/a.go,/w/b.go" {
+		t.Errorf("FileChoices(/w) = %v, want a.go then b.go, once each", got)
+	}
+	if got := FileChoices(refs, "s2", ""); strings.Join(got, ",") != "/w/a.go,/other/c.go" {
+		t.Errorf("FileChoices without workdir = %v, want the session's own files", got)
+	}
+	if got := RelPath("/w/pkg/a.go", "/w"); got != "pkg/a.go" {
+		t.Errorf("RelPath() = %q, want pkg/a.go", got)
+	}
+}